@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package batchInserter
+
+import (
+	"context"
+	"sync"
+)
+
+// Ack is the result of inserting a record given to InsertWithAck.  It is
+// resolved once the batch holding the record has been inserted, or has
+// failed to be.
+type Ack struct {
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+func newAck() *Ack {
+	return &Ack{done: make(chan struct{})}
+}
+
+// Done returns a channel that is closed once the Ack is resolved.
+func (a *Ack) Done() <-chan struct{} {
+	return a.done
+}
+
+// Err returns nil if the record was inserted, or why it wasn't.  It is only
+// valid once Done is closed.
+func (a *Ack) Err() error {
+	select {
+	case <-a.done:
+		return a.err
+	default:
+		return nil
+	}
+}
+
+// Wait blocks until the Ack is resolved, returning Err, or until ctx is
+// done, returning ctx's error.
+func (a *Ack) Wait(ctx context.Context) error {
+	select {
+	case <-a.done:
+		return a.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (a *Ack) resolve(err error) {
+	if a == nil {
+		return
+	}
+	a.once.Do(func() {
+		a.err = err
+		close(a.done)
+	})
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package batchInserter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func TestAck(t *testing.T) {
+	assert := assert.New(t)
+	a := newAck()
+	assert.NoError(a.Err())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, a.Wait(ctx))
+
+	testErr := errors.New("test error")
+	a.resolve(testErr)
+	a.resolve(nil)
+	<-a.Done()
+	assert.Equal(testErr, a.Err())
+	assert.Equal(testErr, a.Wait(context.Background()))
+
+	// records inserted without an Ack have nothing to resolve.
+	var none *Ack
+	none.resolve(nil)
+}
+
+func TestInsertWithAck(t *testing.T) {
+	tests := []struct {
+		description string
+		insertErr   error
+	}{
+		{
+			description: "Success",
+		},
+		{
+			description: "Insert Error",
+			insertErr:   errors.New("test insert error"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			record := db.Record{DeviceID: "a", Data: []byte("a")}
+			inserter := new(mockInserter)
+			inserter.On("InsertRecords", []db.Record{record}).Return(tc.insertErr).Once()
+			tracker := new(mockTracker)
+			tracker.On("TrackTime", mock.Anything).Once()
+			b, err := NewBatchInserter(Config{MaxBatchSize: 1}, nil, xmetricstest.NewProvider(nil, Metrics), inserter, tracker)
+			require.NoError(t, err)
+			b.Start()
+
+			_, err = b.InsertWithAck(context.Background(), RecordWithTime{Record: record})
+			assert.Equal(ErrBadBeginning, err)
+			ack, err := b.InsertWithAck(context.Background(), RecordWithTime{Record: record, Beginning: time.Now()})
+			require.NoError(t, err)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			assert.Equal(tc.insertErr, ack.Wait(ctx))
+			b.Stop()
+			inserter.AssertExpectations(t)
+			tracker.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAckOverflow(t *testing.T) {
+	assert := assert.New(t)
+	record := RecordWithTime{Record: db.Record{DeviceID: "a", Data: []byte("a")}}
+	b := BatchInserter{
+		config:      Config{OverflowPolicy: DropOldestPolicy},
+		insertQueue: make(chan queuedRecord, 1),
+		logger:      log.NewNopLogger(),
+	}
+	dropped, err := b.InsertWithAck(context.Background(), record)
+	require.NoError(t, err)
+	_, err = b.InsertWithAck(context.Background(), record)
+	require.NoError(t, err)
+	assert.Equal(ErrDropped, dropped.Wait(context.Background()))
+
+	b.config.OverflowPolicy = SpillPolicy
+	b.spill = newSpillSink(t)
+	spilled, err := b.InsertWithAck(context.Background(), record)
+	require.NoError(t, err)
+	assert.Equal(ErrSpilled, spilled.Wait(context.Background()))
+	b.Stop()
+}
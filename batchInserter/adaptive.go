@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package batchInserter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+const (
+	defaultTargetLatency  = time.Second
+	defaultDecreaseFactor = 0.5
+)
+
+// AdaptiveConfig holds the configuration values for tuning the batch size and
+// number of insert workers to how the database is doing.  Each batch that
+// fails or takes longer than TargetLatency to insert multiplies both by
+// DecreaseFactor, and each batch inserted in time adds one to both, keeping
+// them within their bounds.
+type AdaptiveConfig struct {
+	// Enabled turns tuning on.  Without it, MaxBatchSize and
+	// MaxInsertWorkers are always used.
+	Enabled bool
+
+	// MinBatchSize is the smallest the batch size is tuned to.  The largest
+	// is MaxBatchSize.  If MaxBatchSize is zero, the batch size isn't tuned.
+	MinBatchSize int
+
+	// MinInsertWorkers is the fewest insert workers tuned to.  The most is
+	// MaxInsertWorkers.
+	MinInsertWorkers int
+
+	// TargetLatency is how long inserting a batch can take before the
+	// database is considered to be struggling.
+	TargetLatency time.Duration
+
+	// DecreaseFactor is what the batch size and insert workers are
+	// multiplied by when the database is struggling, between 0 and 1.
+	DecreaseFactor float64
+}
+
+// controller tunes the batch size and insert workers with additive increase
+// and multiplicative decrease, and limits how many batches are inserted at
+// once to the tuned number of workers.
+type controller struct {
+	config     AdaptiveConfig
+	maxBatch   int
+	maxWorkers int
+	batchGauge metrics.Gauge
+	workGauge  metrics.Gauge
+
+	lock sync.Mutex
+	// changed is closed and replaced whenever a worker may have become
+	// free.
+	changed   chan struct{}
+	batchSize int
+	workers   int
+	inFlight  int
+}
+
+// newController starts with the largest batch size and most workers,
+// ensuring the configuration values are valid.  If configuration values
+// aren't valid, a default value is used.
+func newController(config AdaptiveConfig, maxBatchSize int, maxWorkers int, measures *Measures) *controller {
+	if config.MinBatchSize < 1 {
+		config.MinBatchSize = 1
+	}
+	if config.MinBatchSize > maxBatchSize {
+		config.MinBatchSize = maxBatchSize
+	}
+	if config.MinInsertWorkers < 1 {
+		config.MinInsertWorkers = 1
+	}
+	if config.MinInsertWorkers > maxWorkers {
+		config.MinInsertWorkers = maxWorkers
+	}
+	if config.TargetLatency <= 0 {
+		config.TargetLatency = defaultTargetLatency
+	}
+	if config.DecreaseFactor <= 0 || config.DecreaseFactor >= 1 {
+		config.DecreaseFactor = defaultDecreaseFactor
+	}
+	c := &controller{
+		config:     config,
+		maxBatch:   maxBatchSize,
+		maxWorkers: maxWorkers,
+		batchSize:  maxBatchSize,
+		workers:    maxWorkers,
+		changed:    make(chan struct{}),
+	}
+	if measures != nil {
+		c.batchGauge = measures.EffectiveBatchSize
+		c.workGauge = measures.EffectiveInsertWorkers
+	}
+	c.setGauges()
+	return c
+}
+
+// size returns the batch size to use.
+func (c *controller) size() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.batchSize
+}
+
+// acquire waits until fewer batches than the tuned number of workers are
+// being inserted, or until ctx is done, returning its error.
+func (c *controller) acquire(ctx context.Context) error {
+	for {
+		c.lock.Lock()
+		if c.inFlight < c.workers {
+			c.inFlight++
+			c.lock.Unlock()
+			return nil
+		}
+		changed := c.changed
+		c.lock.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *controller) release() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.inFlight--
+	c.notify()
+}
+
+// notify wakes everything waiting in acquire.  The lock must be held.
+func (c *controller) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// observe tunes the batch size and workers to how inserting a batch went.
+func (c *controller) observe(latency time.Duration, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err != nil || latency > c.config.TargetLatency {
+		c.batchSize = decrease(c.batchSize, c.config.DecreaseFactor, c.config.MinBatchSize)
+		c.workers = decrease(c.workers, c.config.DecreaseFactor, c.config.MinInsertWorkers)
+	} else {
+		if c.batchSize < c.maxBatch {
+			c.batchSize++
+		}
+		if c.workers < c.maxWorkers {
+			c.workers++
+			c.notify()
+		}
+	}
+	c.setGauges()
+}
+
+func decrease(value int, factor float64, min int) int {
+	value = int(float64(value) * factor)
+	if value < min {
+		return min
+	}
+	return value
+}
+
+// setGauges sets the effective batch size and workers gauges.  The lock must
+// be held, if the controller is in use.
+func (c *controller) setGauges() {
+	if c.batchGauge != nil {
+		c.batchGauge.Set(float64(c.batchSize))
+	}
+	if c.workGauge != nil {
+		c.workGauge.Set(float64(c.workers))
+	}
+}
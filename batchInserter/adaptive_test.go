@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package batchInserter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func TestNewController(t *testing.T) {
+	assert := assert.New(t)
+	c := newController(AdaptiveConfig{MinBatchSize: 50, MinInsertWorkers: -1, DecreaseFactor: 2}, 10, 4, nil)
+	assert.Equal(AdaptiveConfig{
+		MinBatchSize:     10,
+		MinInsertWorkers: 1,
+		TargetLatency:    defaultTargetLatency,
+		DecreaseFactor:   defaultDecreaseFactor,
+	}, c.config)
+	assert.Equal(10, c.size())
+	assert.Equal(4, c.workers)
+
+	inserter := new(mockInserter)
+	b, err := NewBatchInserter(Config{
+		MaxBatchSize:     10,
+		MaxInsertWorkers: 4,
+		Adaptive:         AdaptiveConfig{Enabled: true, MinBatchSize: 2},
+	}, nil, xmetricstest.NewProvider(nil, Metrics), inserter, nil)
+	require.NoError(t, err)
+	require.NotNil(t, b.adaptive)
+	assert.Equal(AdaptiveConfig{
+		Enabled:          true,
+		MinBatchSize:     2,
+		MinInsertWorkers: 1,
+		TargetLatency:    defaultTargetLatency,
+		DecreaseFactor:   defaultDecreaseFactor,
+	}, b.config.Adaptive)
+}
+
+func TestControllerObserve(t *testing.T) {
+	assert := assert.New(t)
+	p := xmetricstest.NewProvider(nil, Metrics)
+	c := newController(AdaptiveConfig{MinBatchSize: 3, TargetLatency: time.Second}, 20, 8, NewMeasures(p))
+	p.Assert(t, EffectiveBatchSizeGauge)(xmetricstest.Value(20))
+	p.Assert(t, EffectiveInsertWorkersGauge)(xmetricstest.Value(8))
+
+	steps := []struct {
+		latency         time.Duration
+		err             error
+		expectedSize    int
+		expectedWorkers int
+	}{
+		{latency: time.Millisecond, expectedSize: 20, expectedWorkers: 8},
+		{latency: 2 * time.Second, expectedSize: 10, expectedWorkers: 4},
+		{latency: time.Millisecond, err: errors.New("test error"), expectedSize: 5, expectedWorkers: 2},
+		{latency: 2 * time.Second, expectedSize: 3, expectedWorkers: 1},
+		{latency: 2 * time.Second, expectedSize: 3, expectedWorkers: 1},
+		{latency: time.Millisecond, expectedSize: 4, expectedWorkers: 2},
+		{latency: time.Millisecond, expectedSize: 5, expectedWorkers: 3},
+	}
+	for _, step := range steps {
+		c.observe(step.latency, step.err)
+		assert.Equal(step.expectedSize, c.size())
+		assert.Equal(step.expectedWorkers, c.workers)
+	}
+	p.Assert(t, EffectiveBatchSizeGauge)(xmetricstest.Value(5))
+	p.Assert(t, EffectiveInsertWorkersGauge)(xmetricstest.Value(3))
+}
+
+func TestControllerAcquire(t *testing.T) {
+	assert := assert.New(t)
+	c := newController(AdaptiveConfig{}, 10, 2, nil)
+	c.observe(0, errors.New("test error"))
+	c.acquire(context.Background())
+
+	acquired := make(chan struct{})
+	go func() {
+		c.acquire(context.Background())
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquired past the tuned number of workers")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// a healthy batch allows another worker.
+	c.observe(0, nil)
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("never acquired")
+	}
+	c.release()
+	c.release()
+	assert.Equal(0, c.inFlight)
+
+	c.observe(0, errors.New("test error"))
+	c.acquire(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, c.acquire(ctx))
+}
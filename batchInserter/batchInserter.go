@@ -7,14 +7,20 @@
 package batchInserter
 
 import (
+	"context"
 	"errors"
+	"hash/fnv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/metrics/provider"
 	"github.com/goph/emperror"
 	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/xmidt-org/codex-db/deadLetter"
+	"github.com/xmidt-org/codex-db/wal"
 	"github.com/xmidt-org/webpa-common/v2/logging"
 	"github.com/xmidt-org/webpa-common/v2/semaphore"
 )
@@ -33,9 +39,31 @@ var (
 	defaultLogger = log.NewNopLogger()
 )
 
+// The policies for what to do with a record when the queue is full.
+const (
+	// BlockPolicy waits for room in the queue.
+	BlockPolicy = "block"
+	// DropNewestPolicy drops the record being inserted.
+	DropNewestPolicy = "dropNewest"
+	// DropOldestPolicy drops the record that has waited longest in the queue
+	// to make room.
+	DropOldestPolicy = "dropOldest"
+	// SpillPolicy sends the record to the spill sink given with WithSpill.
+	SpillPolicy = "spill"
+)
+
 var (
 	ErrBadBeginning = errors.New("invalid value for the beginning time of the record")
 	ErrBadData      = errors.New("data nil or empty")
+	ErrQueueFull    = errors.New("insert queue is full")
+	ErrStopped      = errors.New("batch inserter has been stopped")
+	ErrDropped      = errors.New("record was dropped to make room in the queue")
+	ErrSpilled      = errors.New("record was sent to the spill sink instead of inserted")
+	ErrAbandoned    = errors.New("record wasn't inserted before the shutdown deadline")
+	ErrDuplicate    = errors.New("record was dropped as a duplicate")
+	ErrBlacklisted  = errors.New("record's device is on the blacklist")
+
+	errNoSpillSink = errors.New("no spill sink given for the spill overflow policy")
 )
 
 // defaultTicker is the production code that produces a ticker.  Note that we don't
@@ -56,7 +84,8 @@ type TimeTracker interface {
 // period of time and that each batch doesn't pass a specified size.
 type BatchInserter struct {
 	numBatchers   int
-	insertQueue   chan RecordWithTime
+	insertQueue   chan queuedRecord
+	partitions    []chan queuedRecord
 	inserter      db.Inserter
 	timeTracker   TimeTracker
 	insertWorkers semaphore.Interface
@@ -65,6 +94,35 @@ type BatchInserter struct {
 	logger        log.Logger
 	config        Config
 	ticker        func(time.Duration) (<-chan time.Time, func())
+	spill         deadLetter.Sink
+	deadLetter    deadLetter.Sink
+	wal           *wal.Log
+	adaptive      *controller
+	dedup         *dedupCache
+	dedupKey      DedupKey
+	blacklist     blacklist.List
+	blacklistHook BlacklistHook
+	undrainedFunc func([]RecordWithTime)
+
+	// stopCtx is canceled before the queues are closed, so that nothing
+	// blocks on a full queue while holding stopLock.
+	stopOnce   sync.Once
+	stopCtx    context.Context
+	cancelStop context.CancelFunc
+	stopLock   sync.RWMutex
+	stopped    bool
+
+	// drainCtx is canceled when Shutdown's deadline passes, so that the
+	// batchers give up on the records they have.
+	drainOnce     sync.Once
+	drainCtx      context.Context
+	cancelDrain   context.CancelFunc
+	undrainedLock sync.Mutex
+	undrained     []queuedRecord
+
+	inserted atomic.Int64
+	failed   atomic.Int64
+	inFlight atomic.Int64
 }
 
 // Config holds the configuration values for a batch inserter.
@@ -74,6 +132,67 @@ type Config struct {
 	MaxBatchSize     int
 	MaxBatchWaitTime time.Duration
 	QueueSize        int
+
+	// MaxBatchBytes is the most bytes of Data, Nonce, and string fields a
+	// batch can hold.  A batch is inserted as soon as it reaches
+	// MaxBatchSize records or MaxBatchBytes bytes, or has waited
+	// MaxBatchWaitTime.  A record larger than MaxBatchBytes is inserted in a
+	// batch of its own.  Zero is no limit.
+	MaxBatchBytes int
+
+	// Adaptive tunes the batch size and insert workers to how long the
+	// database takes to insert batches and whether it fails.
+	Adaptive AdaptiveConfig
+
+	// Dedup drops records with the same key as one inserted shortly before,
+	// such as events delivered more than once.
+	Dedup DedupConfig
+
+	// Partitioned routes each record to one of ParseWorkers batchers by its
+	// DeviceID, and each batcher inserts one batch at a time.  This commits
+	// the records for a device in the order they were inserted, while devices
+	// in different partitions are inserted in parallel.  Each partition's
+	// queue holds QueueSize / ParseWorkers records.
+	Partitioned bool
+
+	// OverflowPolicy is what Insert does when the queue is full: BlockPolicy,
+	// DropNewestPolicy, DropOldestPolicy, or SpillPolicy.  It defaults to
+	// BlockPolicy.  SpillPolicy needs a sink given with WithSpill.
+	OverflowPolicy string
+}
+
+// Option is the function used to configure optional parts of a
+// BatchInserter.
+type Option func(*BatchInserter)
+
+// WithDeadLetter sets a sink to send batches to when inserting them fails,
+// instead of dropping them.
+func WithDeadLetter(sink deadLetter.Sink) Option {
+	return func(b *BatchInserter) {
+		b.deadLetter = sink
+	}
+}
+
+// WithSpill sets the sink records are sent to when the queue is full and the
+// policy is SpillPolicy, such as a deadLetter.FileSink that a
+// deadLetter.Replayer inserts from once the database catches up.  The
+// caller closes the sink after calling Stop.
+func WithSpill(sink deadLetter.Sink) Option {
+	return func(b *BatchInserter) {
+		b.spill = sink
+	}
+}
+
+// WithWriteAheadLog sets a write-ahead log to append records to before they
+// are queued.  Records are acknowledged once they are inserted or taken by
+// the dead letter sink, or when they are turned away because the queue is
+// full.  Start replays the records the log has that were never acknowledged,
+// such as those queued when the process last stopped.  The caller closes the
+// log after calling Stop.
+func WithWriteAheadLog(l *wal.Log) Option {
+	return func(b *BatchInserter) {
+		b.wal = l
+	}
 }
 
 // RecordWithTime provides the db record and the time this event was received by a service
@@ -82,10 +201,20 @@ type RecordWithTime struct {
 	Beginning time.Time
 }
 
+// queuedRecord is a record in the queue, along with its sequence number in
+// the write-ahead log, or zero if there isn't one, its Ack, if it has one,
+// and its key for dropping duplicates, if that is enabled.
+type queuedRecord struct {
+	RecordWithTime
+	seq      uint64
+	ack      *Ack
+	dedupKey string
+}
+
 // NewBatchInserter creates a BatchInserter with the given values, ensuring
 // that the configuration and other values given are valid.  If configuration
 // values aren't valid, a default value is used.
-func NewBatchInserter(config Config, logger log.Logger, metricsRegistry provider.Provider, inserter db.Inserter, timeTracker TimeTracker) (*BatchInserter, error) {
+func NewBatchInserter(config Config, logger log.Logger, metricsRegistry provider.Provider, inserter db.Inserter, timeTracker TimeTracker, options ...Option) (*BatchInserter, error) {
 	if inserter == nil {
 		return nil, errors.New("no inserter")
 	}
@@ -104,13 +233,42 @@ func NewBatchInserter(config Config, logger log.Logger, metricsRegistry provider
 	if config.QueueSize < defaultMinQueueSize {
 		config.QueueSize = defaultMinQueueSize
 	}
+	if config.MaxBatchBytes < 0 {
+		config.MaxBatchBytes = 0
+	}
+	switch config.OverflowPolicy {
+	case BlockPolicy, DropNewestPolicy, DropOldestPolicy, SpillPolicy:
+	default:
+		config.OverflowPolicy = BlockPolicy
+	}
 	if logger == nil {
 		logger = defaultLogger
 	}
 
 	measures := NewMeasures(metricsRegistry)
 	workers := semaphore.New(config.MaxInsertWorkers)
-	queue := make(chan RecordWithTime, config.QueueSize)
+	queue := make(chan queuedRecord, config.QueueSize)
+	var partitions []chan queuedRecord
+	if config.Partitioned {
+		size := config.QueueSize / config.ParseWorkers
+		if size < 1 {
+			size = 1
+		}
+		partitions = make([]chan queuedRecord, config.ParseWorkers)
+		for i := range partitions {
+			partitions[i] = make(chan queuedRecord, size)
+		}
+	}
+	var adaptive *controller
+	if config.Adaptive.Enabled {
+		adaptive = newController(config.Adaptive, config.MaxBatchSize, config.MaxInsertWorkers, measures)
+		config.Adaptive = adaptive.config
+	}
+	var dedup *dedupCache
+	if config.Dedup.Enabled {
+		dedup = newDedupCache(config.Dedup, measures)
+		config.Dedup = dedup.config
+	}
 	b := BatchInserter{
 		config:        config,
 		logger:        logger,
@@ -119,44 +277,287 @@ func NewBatchInserter(config Config, logger log.Logger, metricsRegistry provider
 		insertWorkers: workers,
 		inserter:      inserter,
 		insertQueue:   queue,
+		partitions:    partitions,
 		ticker:        defaultTicker,
+		adaptive:      adaptive,
+		dedup:         dedup,
+		dedupKey:      DefaultDedupKey,
 		timeTracker:   timeTracker,
 	}
+	for _, o := range options {
+		o(&b)
+	}
+	if config.OverflowPolicy == SpillPolicy && b.spill == nil {
+		return nil, errNoSpillSink
+	}
 	return &b, nil
 }
 
-// Start starts the batcher, which pulls from the queue inside of the BatchInserter.
+// Start starts the batcher, which pulls from the queue inside of the
+// BatchInserter.  With a write-ahead log, Start then blocks until the records
+// to replay from it are queued.
 func (b *BatchInserter) Start() {
-	for i := 0; i < b.numBatchers; i++ {
+	for _, partition := range b.partitions {
+		b.wg.Add(1)
+		go b.batch(partition, true)
+	}
+	for i := 0; len(b.partitions) == 0 && i < b.numBatchers; i++ {
 		b.wg.Add(1)
 		go b.batchRecords()
 	}
+	if b.wal != nil {
+		b.replay()
+	}
+}
+
+// replay queues the records in the write-ahead log that were never
+// acknowledged.
+func (b *BatchInserter) replay() {
+	b.stopLock.RLock()
+	defer b.stopLock.RUnlock()
+	if b.stopped {
+		return
+	}
+	replayed := 0
+	err := b.wal.Replay(func(e wal.Entry) error {
+		q := queuedRecord{
+			RecordWithTime: RecordWithTime{Record: e.Record, Beginning: e.Beginning},
+			seq:            e.Seq,
+		}
+		select {
+		case b.queueFor(e.Record) <- q:
+		case <-b.stopContext().Done():
+			// the rest stay in the log for the next Start.
+			return ErrStopped
+		}
+		b.queued(1.0)
+		replayed++
+		return nil
+	})
+	if err != nil && err != ErrStopped {
+		logging.Error(b.logger, emperror.Context(err)...).Log(logging.MessageKey(),
+			"Failed to replay the write-ahead log", logging.ErrorKey(), err.Error())
+	}
+	if replayed > 0 {
+		logging.Info(b.logger).Log(logging.MessageKey(), "Replayed the write-ahead log", "records", replayed)
+	}
 }
 
 // Insert adds the event to the queue inside of BatchInserter, preparing for it
-// to be inserted.  This can block, if the queue is full.  If the record has
-// certain fields empty, an error is returned.
+// to be inserted.  If the queue is full, the overflow policy decides what
+// happens, and the default policy blocks until there is room.  If the record
+// has certain fields empty, or the BatchInserter has been stopped, an error is
+// returned.
 func (b *BatchInserter) Insert(rwt RecordWithTime) error {
+	return b.enqueue(context.Background(), rwt, true, nil)
+}
+
+// InsertWithAck is Insert, also returning an Ack that is resolved once the
+// batch holding the record has been inserted or has failed to be.  If an
+// error is returned, the record wasn't queued and there is no Ack.  A record
+// later dropped for a newer one resolves with ErrDropped, a record sent to
+// the spill sink resolves with ErrSpilled, a duplicate resolves with ErrDuplicate, and a
+// record for a blacklisted device resolves with ErrBlacklisted.
+func (b *BatchInserter) InsertWithAck(ctx context.Context, rwt RecordWithTime) (*Ack, error) {
+	ack := newAck()
+	if err := b.enqueue(ctx, rwt, true, ack); err != nil {
+		return nil, err
+	}
+	return ack, nil
+}
+
+// InsertContext is Insert, except that when the policy blocks it only waits
+// until ctx is done, returning ctx's error.
+func (b *BatchInserter) InsertContext(ctx context.Context, rwt RecordWithTime) error {
+	return b.enqueue(ctx, rwt, true, nil)
+}
+
+// TryInsert is Insert, except that it never blocks.  When the queue is full
+// and the policy blocks, ErrQueueFull is returned instead.
+func (b *BatchInserter) TryInsert(rwt RecordWithTime) error {
+	return b.enqueue(context.Background(), rwt, false, nil)
+}
+
+func (b *BatchInserter) enqueue(ctx context.Context, rwt RecordWithTime, wait bool, ack *Ack) (err error) {
 	if b.timeTracker != nil && rwt.Beginning.IsZero() {
 		return ErrBadBeginning
 	}
 	if rwt.Record.Data == nil || len(rwt.Record.Data) == 0 {
 		return ErrBadData
 	}
-	b.insertQueue <- rwt
+
+	// Stop can't close the queue while a record is being added.
+	b.stopLock.RLock()
+	defer b.stopLock.RUnlock()
+	if b.stopped {
+		return ErrStopped
+	}
+	if b.blacklisted(rwt.Record) {
+		ack.resolve(ErrBlacklisted)
+		return nil
+	}
+	q := queuedRecord{RecordWithTime: rwt, ack: ack}
+	if b.dedup != nil {
+		q.dedupKey = b.dedupKey(rwt.Record)
+		if !b.dedup.add(q.dedupKey) {
+			if b.measures != nil {
+				b.measures.DuplicatesDropped.Add(1.0)
+			}
+			ack.resolve(ErrDuplicate)
+			return nil
+		}
+		// a record that isn't queued can be inserted again.
+		defer func() {
+			if err != nil {
+				b.forget(q)
+			}
+		}()
+	}
+	if b.wal != nil {
+		seq, err := b.wal.Append(rwt.Record, rwt.Beginning)
+		if err != nil {
+			return emperror.Wrap(err, "Writing record to the write-ahead log failed")
+		}
+		q.seq = seq
+	}
+	queued, err := b.push(ctx, q, wait)
+	if !queued {
+		b.ackLog(q)
+		b.forget(q)
+		if err == nil {
+			ack.resolve(ErrSpilled)
+		}
+	}
+	return err
+}
+
+// push adds the record to the queue, following the overflow policy if it is
+// full, and returns whether the record was queued.
+func (b *BatchInserter) push(ctx context.Context, q queuedRecord, wait bool) (bool, error) {
+	queue := b.queueFor(q.Record)
+	select {
+	case queue <- q:
+		b.queued(1.0)
+		return true, nil
+	default:
+	}
+
+	switch b.config.OverflowPolicy {
+	case DropNewestPolicy:
+		b.overflowed(DroppedNewestOutcome)
+		return false, ErrQueueFull
+	case DropOldestPolicy:
+		b.replaceOldest(queue, q)
+		return true, nil
+	case SpillPolicy:
+		if err := b.spill.Send([]db.Record{q.Record}, ErrQueueFull); err != nil {
+			b.overflowed(SpillFailedOutcome)
+			return false, emperror.Wrap(err, "Spilling record failed")
+		}
+		b.overflowed(SpilledOutcome)
+		return false, nil
+	}
+
+	if !wait {
+		b.overflowed(RejectedOutcome)
+		return false, ErrQueueFull
+	}
+	b.overflowed(BlockedOutcome)
+	select {
+	case queue <- q:
+		b.queued(1.0)
+		return true, nil
+	case <-ctx.Done():
+		b.overflowed(RejectedOutcome)
+		return false, ctx.Err()
+	case <-b.stopContext().Done():
+		b.overflowed(RejectedOutcome)
+		return false, ErrStopped
+	}
+}
+
+// queueFor returns the queue the record goes in: its partition's, or the
+// one queue if the BatchInserter isn't partitioned.
+func (b *BatchInserter) queueFor(record db.Record) chan queuedRecord {
+	if len(b.partitions) == 0 {
+		return b.insertQueue
+	}
+	h := fnv.New32a()
+	h.Write([]byte(record.DeviceID))
+	return b.partitions[h.Sum32()%uint32(len(b.partitions))]
+}
+
+// replaceOldest drops records from the front of the queue until q fits.
+func (b *BatchInserter) replaceOldest(queue chan queuedRecord, q queuedRecord) {
+	for {
+		select {
+		case old := <-queue:
+			b.queued(-1.0)
+			b.overflowed(DroppedOldestOutcome)
+			b.ackLog(old)
+			b.forget(old)
+			old.ack.resolve(ErrDropped)
+		default:
+		}
+		select {
+		case queue <- q:
+			b.queued(1.0)
+			return
+		default:
+			// another insert took the room first.
+		}
+	}
+}
+
+// ackLog acknowledges the records in the write-ahead log, once this
+// BatchInserter is done with them.
+func (b *BatchInserter) ackLog(batch ...queuedRecord) {
+	if b.wal == nil {
+		return
+	}
+	seqs := make([]uint64, 0, len(batch))
+	for _, q := range batch {
+		if q.seq != 0 {
+			seqs = append(seqs, q.seq)
+		}
+	}
+	if err := b.wal.Ack(seqs...); err != nil {
+		logging.Error(b.logger, emperror.Context(err)...).Log(logging.MessageKey(),
+			"Failed to acknowledge records in the write-ahead log", "records", len(seqs),
+			logging.ErrorKey(), err.Error())
+	}
+}
+
+// forget removes the records from the duplicate window, so that records
+// which weren't inserted can be inserted again.
+func (b *BatchInserter) forget(batch ...queuedRecord) {
+	if b.dedup == nil {
+		return
+	}
+	for _, q := range batch {
+		b.dedup.remove(q.dedupKey)
+	}
+}
+
+func (b *BatchInserter) queued(delta float64) {
 	if b.measures != nil {
-		b.measures.InsertingQueue.Add(1.0)
+		b.measures.InsertingQueue.Add(delta)
+	}
+}
+
+func (b *BatchInserter) overflowed(outcome string) {
+	if b.measures != nil {
+		b.measures.Overflow.With(OutcomeLabel, outcome).Add(1.0)
 	}
-	return nil
 }
 
 // Stop closes the internal queue and waits for the workers to finish
 // processing what has already been added.  This can block as it waits for
-// everything to stop.  After Stop() is called, Insert() should not be called
-// again, or there will be a panic.
-// TODO: ensure consumers can't cause a panic?
+// everything to stop.  After Stop() is called, Insert() returns ErrStopped.
 func (b *BatchInserter) Stop() {
-	close(b.insertQueue)
+	if !b.closeQueues() {
+		return
+	}
 	b.wg.Wait()
 
 	// Grab all the workers to make sure they are done.
@@ -165,68 +566,228 @@ func (b *BatchInserter) Stop() {
 	}
 }
 
+// closeQueues stops new records from being inserted and closes the queues,
+// returning false if that was already done.  Inserts blocked on a full queue
+// return ErrStopped first, so that the lock can be taken.
+func (b *BatchInserter) closeQueues() bool {
+	b.stopContext()
+	b.cancelStop()
+	b.stopLock.Lock()
+	defer b.stopLock.Unlock()
+	if b.stopped {
+		return false
+	}
+	b.stopped = true
+	if len(b.partitions) == 0 {
+		close(b.insertQueue)
+	}
+	for _, partition := range b.partitions {
+		close(partition)
+	}
+	return true
+}
+
+func (b *BatchInserter) stopContext() context.Context {
+	b.stopOnce.Do(func() {
+		b.stopCtx, b.cancelStop = context.WithCancel(context.Background())
+	})
+	return b.stopCtx
+}
+
 func (b *BatchInserter) batchRecords() {
+	b.batch(b.insertQueue, false)
+}
+
+// batch groups the records in the queue into batches to insert.  If serial
+// is true, each batch is inserted before the next is started.
+func (b *BatchInserter) batch(queue chan queuedRecord, serial bool) {
+	defer b.wg.Done()
 	var (
-		insertRecords bool
-		ticker        <-chan time.Time
-		stop          func()
+		// next is a record that didn't fit in the last batch.
+		next    *queuedRecord
+		stopped bool
+		drain   = b.drainContext()
 	)
-	defer b.wg.Done()
-	for rwt := range b.insertQueue {
-		if b.measures != nil {
-			b.measures.InsertingQueue.Add(-1.0)
+	for !stopped || next != nil {
+		var q queuedRecord
+		if next != nil {
+			q, next = *next, nil
+		} else {
+			select {
+			case r, ok := <-queue:
+				if !ok {
+					return
+				}
+				b.queued(-1.0)
+				q = r
+			case <-drain.Done():
+				b.abandon(queue)
+				return
+			}
+		}
+
+		maxBatchSize := b.config.MaxBatchSize
+		if b.adaptive != nil && maxBatchSize != 0 {
+			maxBatchSize = b.adaptive.size()
 		}
-		ticker, stop = b.ticker(b.config.MaxBatchWaitTime)
-		records := []db.Record{rwt.Record}
-		beginTimes := []time.Time{rwt.Beginning}
-		for {
+		ticker, stop := b.ticker(b.config.MaxBatchWaitTime)
+		batch := []queuedRecord{q}
+		batchBytes := recordBytes(q.Record)
+		reason := ""
+		if b.config.MaxBatchBytes > 0 && batchBytes >= b.config.MaxBatchBytes {
+			reason = BytesReason
+		}
+		for reason == "" {
 			select {
 			case <-ticker:
-				insertRecords = true
-			case r, ok := <-b.insertQueue:
+				reason = WaitTimeReason
+			case r, ok := <-queue:
 				// if ok is false, the queue is closed.
 				if !ok {
-					insertRecords = true
+					reason, stopped = StoppedReason, true
 					break
 				}
-				if b.measures != nil {
-					b.measures.InsertingQueue.Add(-1.0)
-				}
-				records = append(records, r.Record)
-				beginTimes = append(beginTimes, r.Beginning)
-				if b.config.MaxBatchSize != 0 && len(records) >= b.config.MaxBatchSize {
-					insertRecords = true
+				b.queued(-1.0)
+				recordSize := recordBytes(r.Record)
+				if b.config.MaxBatchBytes > 0 && batchBytes+recordSize > b.config.MaxBatchBytes {
+					next, reason = &r, BytesReason
 					break
 				}
-			}
-			if insertRecords {
-				b.insertWorkers.Acquire()
-				go b.insertRecords(records, beginTimes)
-				insertRecords = false
-				break
+				batch = append(batch, r)
+				batchBytes += recordSize
+				if maxBatchSize != 0 && len(batch) >= maxBatchSize {
+					reason = CountReason
+				} else if b.config.MaxBatchBytes > 0 && batchBytes >= b.config.MaxBatchBytes {
+					reason = BytesReason
+				}
+			case <-drain.Done():
+				stop()
+				b.abandon(queue, batch...)
+				return
 			}
 		}
 		stop()
+
+		if b.measures != nil {
+			b.measures.BatchBytes.With(ReasonLabel, reason).Observe(float64(batchBytes))
+		}
+		if !b.acquireWorker(drain) {
+			b.abandon(queue, withNext(batch, next)...)
+			return
+		}
+		if !serial {
+			go b.insertRecords(batch)
+			continue
+		}
+		inserted := make(chan struct{})
+		go func() {
+			defer close(inserted)
+			b.insertRecords(batch)
+		}()
+		select {
+		case <-inserted:
+		case <-drain.Done():
+			// the batch is still being inserted, but the rest of the
+			// partition is given up on.
+			b.abandon(queue, withNext(nil, next)...)
+			return
+		}
+	}
+}
+
+// withNext adds the record that didn't fit in the batch, if there is one.
+func withNext(batch []queuedRecord, next *queuedRecord) []queuedRecord {
+	if next == nil {
+		return batch
 	}
+	return append(batch, *next)
 }
 
-func (b *BatchInserter) insertRecords(records []db.Record, beginTimes []time.Time) {
+// acquireWorker waits for an insert worker, returning false if Shutdown's
+// deadline passes first.
+func (b *BatchInserter) acquireWorker(drain context.Context) bool {
+	if err := b.insertWorkers.AcquireCtx(drain); err != nil {
+		return false
+	}
+	if b.adaptive != nil {
+		if err := b.adaptive.acquire(drain); err != nil {
+			b.insertWorkers.Release()
+			return false
+		}
+	}
+	return true
+}
+
+// recordBytes is roughly how many bytes the record takes up in a batch: the
+// length of its byte and string fields.
+func recordBytes(r db.Record) int {
+	return len(r.Data) + len(r.Nonce) + len(r.DeviceID) + len(r.Alg) + len(r.KID) + len(r.RowID)
+}
+
+func (b *BatchInserter) insertRecords(batch []queuedRecord) {
 	defer b.insertWorkers.Release()
+	if b.adaptive != nil {
+		defer b.adaptive.release()
+	}
+	b.inFlight.Add(int64(len(batch)))
+	defer b.inFlight.Add(-int64(len(batch)))
+	records := make([]db.Record, len(batch))
+	beginTimes := make([]time.Time, len(batch))
+	for i, q := range batch {
+		records[i] = q.Record
+		beginTimes[i] = q.Beginning
+	}
+	start := time.Now()
 	err := b.inserter.InsertRecords(records...)
+	if b.adaptive != nil {
+		b.adaptive.observe(time.Since(start), err)
+	}
 	if err != nil {
-		if b.measures != nil {
-			b.measures.DroppedEventsFromDbFailCount.Add(float64(len(records)))
-		}
 		logging.Error(b.logger, emperror.Context(err)...).Log(logging.MessageKey(),
 			"Failed to add records to the database", logging.ErrorKey(), err.Error())
+		// records that are dropped stay in the write-ahead log, to be
+		// replayed on the next Start.
+		if b.sendToDeadLetter(records, err) {
+			b.ackLog(batch...)
+		} else if b.measures != nil {
+			b.measures.DroppedEventsFromDbFailCount.Add(float64(len(records)))
+		}
+		b.failed.Add(int64(len(batch)))
+		b.forget(batch...)
+		resolve(batch, err)
 		b.sendTimes(beginTimes, time.Now())
 		return
 	}
+	b.inserted.Add(int64(len(batch)))
+	b.ackLog(batch...)
+	resolve(batch, nil)
 	b.sendTimes(beginTimes, time.Now())
 	logging.Debug(b.logger).Log(logging.MessageKey(), "Successfully upserted device information", "records", records)
 	logging.Info(b.logger).Log(logging.MessageKey(), "Successfully upserted device information", "records", len(records))
 }
 
+// resolve resolves the Acks of the records with the result of inserting
+// them.
+func resolve(batch []queuedRecord, err error) {
+	for _, q := range batch {
+		q.ack.resolve(err)
+	}
+}
+
+// sendToDeadLetter returns whether the dead letter sink took the records.
+func (b *BatchInserter) sendToDeadLetter(records []db.Record, cause error) bool {
+	if b.deadLetter == nil {
+		return false
+	}
+	if err := b.deadLetter.Send(records, cause); err != nil {
+		logging.Error(b.logger, emperror.Context(err)...).Log(logging.MessageKey(),
+			"Failed to send records to the dead letter sink", "records", len(records),
+			logging.ErrorKey(), err.Error())
+		return false
+	}
+	return true
+}
+
 func (b *BatchInserter) sendTimes(beginTimes []time.Time, endTime time.Time) {
 	if b.timeTracker != nil {
 		for _, beginTime := range beginTimes {
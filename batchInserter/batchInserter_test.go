@@ -4,8 +4,10 @@
 package batchInserter
 
 import (
+	"context"
 	"errors"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -16,8 +18,11 @@ import (
 	"github.com/go-kit/kit/metrics/provider"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/deadLetter"
+	"github.com/xmidt-org/codex-db/wal"
 	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
 )
 
@@ -31,6 +36,7 @@ func TestNewBatchInserter(t *testing.T) {
 		MaxInsertWorkers: 5000,
 		MaxBatchSize:     100,
 		MaxBatchWaitTime: 5 * time.Hour,
+		OverflowPolicy:   DropOldestPolicy,
 	}
 	tests := []struct {
 		description           string
@@ -59,6 +65,7 @@ func TestNewBatchInserter(t *testing.T) {
 			config: Config{
 				MaxBatchSize:     -5,
 				MaxBatchWaitTime: -2 * time.Minute,
+				OverflowPolicy:   "sometimes",
 			},
 			inserter: goodInserter,
 			registry: goodRegistry,
@@ -71,6 +78,7 @@ func TestNewBatchInserter(t *testing.T) {
 					QueueSize:        defaultMinQueueSize,
 					ParseWorkers:     minParseWorkers,
 					MaxInsertWorkers: defaultInsertWorkers,
+					OverflowPolicy:   BlockPolicy,
 				},
 				logger: defaultLogger,
 			},
@@ -79,6 +87,13 @@ func TestNewBatchInserter(t *testing.T) {
 			description: "Nil Inserter Error",
 			expectedErr: errors.New("no inserter"),
 		},
+		{
+			description: "No Spill Sink Error",
+			config:      Config{OverflowPolicy: SpillPolicy},
+			inserter:    goodInserter,
+			registry:    goodRegistry,
+			expectedErr: errNoSpillSink,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.description, func(t *testing.T) {
@@ -135,6 +150,8 @@ func TestBatchInserter(t *testing.T) {
 		expectedDroppedEvents float64
 		expectStopCalled      bool
 		expectedErr           error
+		useDeadLetter         bool
+		deadLetterErr         error
 	}{
 		{
 			description:     "Success",
@@ -168,6 +185,30 @@ func TestBatchInserter(t *testing.T) {
 			expectedDroppedEvents: 2,
 			expectStopCalled:      true,
 		},
+		{
+			description:     "Insert Records Error With Dead Letter",
+			recordsToInsert: records[3:5],
+			waitBtwnRecords: 1 * time.Millisecond,
+			recordsExpected: [][]db.Record{
+				records[3:5],
+			},
+			insertErr:        errors.New("test insert error"),
+			useDeadLetter:    true,
+			expectStopCalled: true,
+		},
+		{
+			description:     "Dead Letter Error",
+			recordsToInsert: records[3:5],
+			waitBtwnRecords: 1 * time.Millisecond,
+			recordsExpected: [][]db.Record{
+				records[3:5],
+			},
+			insertErr:             errors.New("test insert error"),
+			useDeadLetter:         true,
+			deadLetterErr:         errors.New("test dead letter error"),
+			expectedDroppedEvents: 2,
+			expectStopCalled:      true,
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.description, func(t *testing.T) {
@@ -178,7 +219,7 @@ func TestBatchInserter(t *testing.T) {
 				inserter.On("InsertRecords", r).Return(tc.insertErr).Once()
 				tracker.On("TrackTime", mock.Anything).Times(len(r))
 			}
-			queue := make(chan RecordWithTime, 5)
+			queue := make(chan queuedRecord, 5)
 			p := xmetricstest.NewProvider(nil, Metrics)
 			m := NewMeasures(p)
 			stopCalled := false
@@ -203,6 +244,13 @@ func TestBatchInserter(t *testing.T) {
 				},
 				timeTracker: tracker,
 			}
+			sink := new(mockSink)
+			if tc.useDeadLetter {
+				for _, r := range tc.recordsExpected {
+					sink.On("Send", r, tc.insertErr).Return(tc.deadLetterErr).Once()
+				}
+				WithDeadLetter(sink)(&b)
+			}
 			p.Assert(t, DroppedEventsFromDbFailCounter)(xmetricstest.Value(0))
 			b.wg.Add(1)
 			go b.batchRecords()
@@ -226,8 +274,295 @@ func TestBatchInserter(t *testing.T) {
 			tickerChan <- time.Now()
 			b.Stop()
 			inserter.AssertExpectations(t)
+			sink.AssertExpectations(t)
 			assert.Equal(tc.expectStopCalled, stopCalled)
 			p.Assert(t, DroppedEventsFromDbFailCounter)(xmetricstest.Value(tc.expectedDroppedEvents))
 		})
 	}
 }
+
+func TestOverflow(t *testing.T) {
+	records := []db.Record{
+		{DeviceID: "a", Data: []byte("a")},
+		{DeviceID: "b", Data: []byte("b")},
+		{DeviceID: "c", Data: []byte("c")},
+	}
+	tests := []struct {
+		description     string
+		policy          string
+		tryInsert       bool
+		expectedErr     error
+		expectedQueue   []db.Record
+		expectedSpilled []db.Record
+		expectedOutcome string
+	}{
+		{
+			description:     "Try Insert",
+			policy:          BlockPolicy,
+			tryInsert:       true,
+			expectedErr:     ErrQueueFull,
+			expectedQueue:   records[:2],
+			expectedOutcome: RejectedOutcome,
+		},
+		{
+			description:     "Block Until Done",
+			policy:          BlockPolicy,
+			expectedErr:     context.DeadlineExceeded,
+			expectedQueue:   records[:2],
+			expectedOutcome: RejectedOutcome,
+		},
+		{
+			description:     "Drop Newest",
+			policy:          DropNewestPolicy,
+			expectedErr:     ErrQueueFull,
+			expectedQueue:   records[:2],
+			expectedOutcome: DroppedNewestOutcome,
+		},
+		{
+			description:     "Drop Oldest",
+			policy:          DropOldestPolicy,
+			tryInsert:       true,
+			expectedQueue:   records[1:],
+			expectedOutcome: DroppedOldestOutcome,
+		},
+		{
+			description:     "Spill",
+			policy:          SpillPolicy,
+			expectedQueue:   records[:2],
+			expectedSpilled: records[2:],
+			expectedOutcome: SpilledOutcome,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			p := xmetricstest.NewProvider(nil, Metrics)
+			b := BatchInserter{
+				config:      Config{OverflowPolicy: tc.policy},
+				insertQueue: make(chan queuedRecord, 2),
+				measures:    NewMeasures(p),
+				logger:      log.NewNopLogger(),
+			}
+			spill := newSpillSink(t)
+			if tc.policy == SpillPolicy {
+				b.spill = spill
+			}
+
+			for _, r := range records[:2] {
+				assert.NoError(b.TryInsert(RecordWithTime{Record: r}))
+			}
+			var err error
+			if tc.tryInsert {
+				err = b.TryInsert(RecordWithTime{Record: records[2]})
+			} else {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				err = b.InsertContext(ctx, RecordWithTime{Record: records[2]})
+				cancel()
+			}
+			assert.Equal(tc.expectedErr, err)
+			p.Assert(t, InsertOverflowCounter, OutcomeLabel, tc.expectedOutcome)(xmetricstest.Value(1))
+			p.Assert(t, InsertingQueueDepth)(xmetricstest.Value(float64(len(tc.expectedQueue))))
+
+			b.Stop()
+			var queued []db.Record
+			for rwt := range b.insertQueue {
+				queued = append(queued, rwt.Record)
+			}
+			assert.Equal(tc.expectedQueue, queued)
+			if tc.policy == SpillPolicy {
+				assert.Equal(tc.expectedSpilled, readSpill(t, spill))
+			}
+		})
+	}
+}
+
+func TestInsertAfterStop(t *testing.T) {
+	assert := assert.New(t)
+	b := BatchInserter{
+		insertQueue: make(chan queuedRecord, 1),
+		logger:      log.NewNopLogger(),
+	}
+	b.Stop()
+	b.Stop()
+	rwt := RecordWithTime{Record: db.Record{Data: []byte("a")}}
+	assert.Equal(ErrStopped, b.Insert(rwt))
+	assert.Equal(ErrStopped, b.TryInsert(rwt))
+}
+
+func newSpillSink(t *testing.T) *deadLetter.FileSink {
+	sink, err := deadLetter.NewFileSink(deadLetter.FileConfig{Dir: t.TempDir()}, nil, nil)
+	require.NoError(t, err)
+	return sink
+}
+
+// readSpill replays the spill sink, returning the records in it.
+func readSpill(t *testing.T, sink *deadLetter.FileSink) []db.Record {
+	inserter := new(recordingInserter)
+	_, err := sink.Replay(context.Background(), inserter)
+	require.NoError(t, err)
+	return inserter.records
+}
+
+func TestWriteAheadLog(t *testing.T) {
+	records := []db.Record{
+		{DeviceID: "a", Data: []byte("a")},
+		{DeviceID: "b", Data: []byte("b")},
+		{DeviceID: "c", Data: []byte("c")},
+	}
+	tests := []struct {
+		description   string
+		insertErr     error
+		expectedAfter int
+	}{
+		{
+			description: "Success",
+		},
+		{
+			description:   "Insert Error",
+			insertErr:     errors.New("test insert error"),
+			expectedAfter: 3,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			dir := t.TempDir()
+			// records left from before a restart.
+			l, err := wal.NewLog(wal.Config{Dir: dir}, nil, nil)
+			require.NoError(t, err)
+			for _, r := range records[:2] {
+				_, err := l.Append(r, time.Time{})
+				require.NoError(t, err)
+			}
+			require.NoError(t, l.Close())
+
+			l, err = wal.NewLog(wal.Config{Dir: dir}, nil, nil)
+			require.NoError(t, err)
+			var (
+				lock     sync.Mutex
+				inserted []db.Record
+			)
+			inserter := new(mockInserter)
+			inserter.On("InsertRecords", mock.Anything).Return(tc.insertErr).Run(func(args mock.Arguments) {
+				lock.Lock()
+				defer lock.Unlock()
+				inserted = append(inserted, args.Get(0).([]db.Record)...)
+			})
+			b, err := NewBatchInserter(Config{MaxBatchSize: 10}, nil, xmetricstest.NewProvider(nil, Metrics), inserter, nil, WithWriteAheadLog(l))
+			require.NoError(t, err)
+			b.Start()
+			assert.NoError(b.Insert(RecordWithTime{Record: records[2]}))
+			b.Stop()
+			assert.ElementsMatch(records, inserted)
+			require.NoError(t, l.Close())
+
+			l, err = wal.NewLog(wal.Config{Dir: dir}, nil, nil)
+			require.NoError(t, err)
+			replayed := 0
+			assert.NoError(l.Replay(func(wal.Entry) error {
+				replayed++
+				return nil
+			}))
+			assert.Equal(tc.expectedAfter, replayed)
+			assert.NoError(l.Close())
+		})
+	}
+}
+
+func TestPartitioned(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		lock      sync.Mutex
+		committed = map[string][]int64{}
+	)
+	inserter := new(mockInserter)
+	inserter.On("InsertRecords", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		records := args.Get(0).([]db.Record)
+		// later batches for other devices can overtake this one, but
+		// batches in the same partition can't.
+		time.Sleep(time.Duration(len(records)) * time.Millisecond)
+		lock.Lock()
+		defer lock.Unlock()
+		for _, r := range records {
+			committed[r.DeviceID] = append(committed[r.DeviceID], r.BirthDate)
+		}
+	})
+	b, err := NewBatchInserter(Config{
+		ParseWorkers:     4,
+		MaxInsertWorkers: 4,
+		MaxBatchSize:     3,
+		QueueSize:        40,
+		Partitioned:      true,
+	}, nil, xmetricstest.NewProvider(nil, Metrics), inserter, nil)
+	require.NoError(t, err)
+	require.Len(t, b.partitions, 4)
+	assert.Equal(10, cap(b.partitions[0]))
+
+	devices := []string{"a", "b", "c", "d", "e", "f"}
+	for _, device := range devices {
+		assert.Equal(b.queueFor(db.Record{DeviceID: device}), b.queueFor(db.Record{DeviceID: device}))
+	}
+	b.Start()
+	for i := int64(0); i < 20; i++ {
+		for _, device := range devices {
+			record := db.Record{DeviceID: device, BirthDate: i, Data: []byte("data")}
+			assert.NoError(b.Insert(RecordWithTime{Record: record}))
+		}
+	}
+	b.Stop()
+
+	expected := make([]int64, 20)
+	for i := range expected {
+		expected[i] = int64(i)
+	}
+	for _, device := range devices {
+		assert.Equal(expected, committed[device], device)
+	}
+}
+
+func TestMaxBatchBytes(t *testing.T) {
+	assert := assert.New(t)
+	records := []db.Record{
+		{DeviceID: "a", Data: []byte("aaa")},
+		{DeviceID: "b", Data: []byte("bbb")},
+		{DeviceID: "c", Data: []byte("ccc")},
+		{DeviceID: "d", Data: []byte("ddddddddddd")},
+		{DeviceID: "e", Data: []byte("eee")},
+	}
+	inserter := new(mockInserter)
+	inserter.On("InsertRecords", records[:2]).Return(nil).Once()
+	inserter.On("InsertRecords", records[2:3]).Return(nil).Once()
+	inserter.On("InsertRecords", records[3:4]).Return(nil).Once()
+	inserter.On("InsertRecords", records[4:]).Return(nil).Once()
+	histogram := newRecordingHistogram()
+	b := BatchInserter{
+		config: Config{
+			MaxBatchWaitTime: time.Hour,
+			MaxBatchSize:     10,
+			MaxBatchBytes:    10,
+			MaxInsertWorkers: 1,
+		},
+		inserter:      inserter,
+		insertQueue:   make(chan queuedRecord, len(records)),
+		insertWorkers: semaphore.New(1),
+		measures:      &Measures{InsertingQueue: xmetricstest.NewProvider(nil, Metrics).NewGauge(InsertingQueueDepth), BatchBytes: histogram},
+		logger:        log.NewNopLogger(),
+		ticker: func(d time.Duration) (<-chan time.Time, func()) {
+			return nil, func() {}
+		},
+	}
+	for _, r := range records {
+		assert.NoError(b.Insert(RecordWithTime{Record: r}))
+	}
+	b.wg.Add(1)
+	go b.batch(b.insertQueue, true)
+	b.Stop()
+
+	inserter.AssertExpectations(t)
+	assert.Equal(map[string][]float64{
+		// the third record doesn't fit with the first two, the fourth
+		// doesn't fit with the third and is too large to share a batch.
+		BytesReason:   {8, 4, 12},
+		StoppedReason: {4},
+	}, histogram.observed)
+}
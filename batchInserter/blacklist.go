@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package batchInserter
+
+import (
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+)
+
+// BlacklistHook is told about each record discarded because its device is
+// on the blacklist, and the reason it is.
+type BlacklistHook func(record db.Record, reason string)
+
+// WithBlacklist discards the records inserted for devices on the list.
+func WithBlacklist(list blacklist.List) Option {
+	return func(b *BatchInserter) {
+		b.blacklist = list
+	}
+}
+
+// WithBlacklistHook sets a function to tell about the records discarded for
+// being on the blacklist.
+func WithBlacklistHook(hook BlacklistHook) Option {
+	return func(b *BatchInserter) {
+		b.blacklistHook = hook
+	}
+}
+
+// blacklisted returns whether the record's device is on the blacklist,
+// counting it and telling the hook if it is.
+func (b *BatchInserter) blacklisted(record db.Record) bool {
+	if b.blacklist == nil {
+		return false
+	}
+	reason, ok := b.blacklist.InList(record.DeviceID)
+	if !ok {
+		return false
+	}
+	if b.measures != nil {
+		b.measures.Blacklisted.With(ReasonLabel, reason).Add(1.0)
+	}
+	if b.blacklistHook != nil {
+		b.blacklistHook(record, reason)
+	}
+	return true
+}
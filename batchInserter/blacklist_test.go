@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package batchInserter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func TestBlacklist(t *testing.T) {
+	tests := []struct {
+		description string
+		useHook     bool
+	}{
+		{
+			description: "Without Hook",
+		},
+		{
+			description: "With Hook",
+			useHook:     true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			allowed := db.Record{DeviceID: "mac:allowed", Data: []byte("a")}
+			banned := db.Record{DeviceID: "mac:banned", Data: []byte("b")}
+			other := db.Record{DeviceID: "mac:other", Data: []byte("c")}
+			list := blacklist.NewEmptySyncList()
+			list.UpdateList([]blacklist.BlackListedItem{
+				{ID: "mac:banned", Reason: "spam"},
+				{ID: "^mac:oth", Reason: "testing"},
+			})
+
+			inserter := new(mockInserter)
+			inserter.On("InsertRecords", []db.Record{allowed}).Return(nil).Once()
+			tracker := new(mockTracker)
+			tracker.On("TrackTime", mock.Anything).Once()
+			options := []Option{WithBlacklist(&list)}
+			discarded := map[string]string{}
+			if tc.useHook {
+				options = append(options, WithBlacklistHook(func(record db.Record, reason string) {
+					discarded[record.DeviceID] = reason
+				}))
+			}
+			p := xmetricstest.NewProvider(nil, Metrics)
+			b, err := NewBatchInserter(Config{}, nil, p, inserter, tracker, options...)
+			require.NoError(t, err)
+			b.Start()
+
+			assert.NoError(b.Insert(RecordWithTime{Record: banned, Beginning: time.Now()}))
+			ack, err := b.InsertWithAck(context.Background(), RecordWithTime{Record: other, Beginning: time.Now()})
+			require.NoError(t, err)
+			assert.Equal(ErrBlacklisted, ack.Err())
+			assert.NoError(b.Insert(RecordWithTime{Record: allowed, Beginning: time.Now()}))
+			b.Stop()
+			// a blacklisted record is still turned away once stopped.
+			assert.Equal(ErrStopped, b.Insert(RecordWithTime{Record: banned, Beginning: time.Now()}))
+
+			p.Assert(t, BlacklistedCounter, ReasonLabel, "spam")(xmetricstest.Value(1))
+			p.Assert(t, BlacklistedCounter, ReasonLabel, "testing")(xmetricstest.Value(1))
+			if tc.useHook {
+				assert.Equal(map[string]string{"mac:banned": "spam", "mac:other": "testing"}, discarded)
+			}
+			inserter.AssertExpectations(t)
+			tracker.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package batchInserter
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	db "github.com/xmidt-org/codex-db"
+)
+
+const (
+	defaultDedupWindow     = 10 * time.Second
+	defaultDedupMaxEntries = 10000
+)
+
+// DedupConfig holds the configuration values for dropping records that are
+// inserted again soon after the first time.  Records that aren't inserted
+// into the database, whether they failed, were dropped, or were abandoned,
+// are forgotten so that they can be inserted again.
+type DedupConfig struct {
+	// Enabled turns dropping duplicates on.
+	Enabled bool
+
+	// Window is how long after a record is inserted that records with the
+	// same key are dropped.
+	Window time.Duration
+
+	// MaxEntries is the most keys remembered.  When there are more, the
+	// oldest are forgotten early.
+	MaxEntries int
+}
+
+// DedupKey returns the key of a record, for deciding whether records are
+// duplicates of each other.
+type DedupKey func(db.Record) string
+
+// DefaultDedupKey is a hash of the record's DeviceID, Type, BirthDate, and
+// Data.
+func DefaultDedupKey(r db.Record) string {
+	h := sha256.New()
+	var buf [8]byte
+	// lengths keep the fields from running together.
+	binary.BigEndian.PutUint64(buf[:], uint64(len(r.DeviceID)))
+	h.Write(buf[:])
+	h.Write([]byte(r.DeviceID))
+	binary.BigEndian.PutUint64(buf[:], uint64(r.Type))
+	h.Write(buf[:])
+	binary.BigEndian.PutUint64(buf[:], uint64(r.BirthDate))
+	h.Write(buf[:])
+	h.Write(r.Data)
+	return string(h.Sum(nil))
+}
+
+// WithDedupKey sets the key used to decide whether records are duplicates.
+// It defaults to DefaultDedupKey.
+func WithDedupKey(key DedupKey) Option {
+	return func(b *BatchInserter) {
+		if key != nil {
+			b.dedupKey = key
+		}
+	}
+}
+
+// dedupCache remembers the keys added in the last window, oldest first.
+type dedupCache struct {
+	config    DedupConfig
+	now       func() time.Time
+	sizeGauge metrics.Gauge
+
+	lock  sync.Mutex
+	keys  map[string]*list.Element
+	order *list.List
+}
+
+type dedupEntry struct {
+	key   string
+	added time.Time
+}
+
+// newDedupCache creates a dedupCache, ensuring the configuration values are
+// valid.  If configuration values aren't valid, a default value is used.
+func newDedupCache(config DedupConfig, measures *Measures) *dedupCache {
+	if config.Window <= 0 {
+		config.Window = defaultDedupWindow
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultDedupMaxEntries
+	}
+	c := &dedupCache{
+		config: config,
+		now:    time.Now,
+		keys:   make(map[string]*list.Element),
+		order:  list.New(),
+	}
+	if measures != nil {
+		c.sizeGauge = measures.DedupCacheSize
+	}
+	return c
+}
+
+// add remembers the key, returning false if it was already added in the
+// window.
+func (c *dedupCache) add(key string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.now()
+	for e := c.order.Front(); e != nil; e = c.order.Front() {
+		if now.Sub(e.Value.(dedupEntry).added) < c.config.Window {
+			break
+		}
+		c.removeElement(e)
+	}
+	if _, ok := c.keys[key]; ok {
+		return false
+	}
+	if c.order.Len() >= c.config.MaxEntries {
+		c.removeElement(c.order.Front())
+	}
+	c.keys[key] = c.order.PushBack(dedupEntry{key: key, added: now})
+	c.setGauge()
+	return true
+}
+
+// remove forgets the key, for records that were added but not queued.
+func (c *dedupCache) remove(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e, ok := c.keys[key]; ok {
+		c.removeElement(e)
+		c.setGauge()
+	}
+}
+
+func (c *dedupCache) removeElement(e *list.Element) {
+	c.order.Remove(e)
+	delete(c.keys, e.Value.(dedupEntry).key)
+}
+
+func (c *dedupCache) setGauge() {
+	if c.sizeGauge != nil {
+		c.sizeGauge.Set(float64(c.order.Len()))
+	}
+}
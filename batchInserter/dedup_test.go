@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package batchInserter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func TestDefaultDedupKey(t *testing.T) {
+	assert := assert.New(t)
+	r := db.Record{Type: db.State, DeviceID: "ab", BirthDate: 5, Data: []byte("c"), RowID: "1"}
+	assert.Equal(DefaultDedupKey(r), DefaultDedupKey(db.Record{Type: db.State, DeviceID: "ab", BirthDate: 5, Data: []byte("c"), RowID: "2"}))
+	assert.NotEqual(DefaultDedupKey(r), DefaultDedupKey(db.Record{Type: db.State, DeviceID: "a", BirthDate: 5, Data: []byte("bc")}))
+	assert.NotEqual(DefaultDedupKey(r), DefaultDedupKey(db.Record{Type: db.Default, DeviceID: "ab", BirthDate: 5, Data: []byte("c")}))
+	assert.NotEqual(DefaultDedupKey(r), DefaultDedupKey(db.Record{Type: db.State, DeviceID: "ab", BirthDate: 6, Data: []byte("c")}))
+}
+
+func TestDedupCache(t *testing.T) {
+	assert := assert.New(t)
+	p := xmetricstest.NewProvider(nil, Metrics)
+	c := newDedupCache(DedupConfig{Window: time.Minute, MaxEntries: 2}, NewMeasures(p))
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	assert.True(c.add("a"))
+	assert.False(c.add("a"))
+	assert.True(c.add("b"))
+	p.Assert(t, DedupCacheSizeGauge)(xmetricstest.Value(2))
+
+	// the oldest key is forgotten to make room.
+	assert.True(c.add("c"))
+	assert.True(c.add("a"))
+	p.Assert(t, DedupCacheSizeGauge)(xmetricstest.Value(2))
+
+	c.remove("a")
+	assert.True(c.add("a"))
+
+	// keys older than the window are forgotten.
+	now = now.Add(time.Minute)
+	assert.True(c.add("c"))
+	p.Assert(t, DedupCacheSizeGauge)(xmetricstest.Value(1))
+
+	c = newDedupCache(DedupConfig{}, nil)
+	assert.Equal(DedupConfig{Window: defaultDedupWindow, MaxEntries: defaultDedupMaxEntries}, c.config)
+}
+
+func TestDedup(t *testing.T) {
+	tests := []struct {
+		description string
+		key         DedupKey
+		second      db.Record
+		expected    [][]db.Record
+		duplicates  float64
+	}{
+		{
+			description: "Duplicate",
+			second:      db.Record{DeviceID: "a", Data: []byte("a"), RowID: "2"},
+			expected:    [][]db.Record{{{DeviceID: "a", Data: []byte("a"), RowID: "1"}}},
+			duplicates:  1,
+		},
+		{
+			description: "Different Data",
+			second:      db.Record{DeviceID: "a", Data: []byte("b"), RowID: "2"},
+			expected: [][]db.Record{{
+				{DeviceID: "a", Data: []byte("a"), RowID: "1"},
+				{DeviceID: "a", Data: []byte("b"), RowID: "2"},
+			}},
+		},
+		{
+			description: "Custom Key",
+			key:         func(r db.Record) string { return r.DeviceID },
+			second:      db.Record{DeviceID: "a", Data: []byte("b"), RowID: "2"},
+			expected:    [][]db.Record{{{DeviceID: "a", Data: []byte("a"), RowID: "1"}}},
+			duplicates:  1,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			inserter := new(mockInserter)
+			for _, records := range tc.expected {
+				inserter.On("InsertRecords", records).Return(nil).Once()
+			}
+			tracker := new(mockTracker)
+			tracker.On("TrackTime", mock.Anything)
+			p := xmetricstest.NewProvider(nil, Metrics)
+			b, err := NewBatchInserter(Config{ParseWorkers: 1, MaxBatchSize: 2, Dedup: DedupConfig{Enabled: true}},
+				nil, p, inserter, tracker, WithDedupKey(tc.key))
+			require.NoError(t, err)
+			b.Start()
+
+			first := db.Record{DeviceID: "a", Data: []byte("a"), RowID: "1"}
+			require.NoError(t, b.Insert(RecordWithTime{Record: first, Beginning: time.Now()}))
+			ack, err := b.InsertWithAck(context.Background(), RecordWithTime{Record: tc.second, Beginning: time.Now()})
+			require.NoError(t, err)
+			if tc.duplicates > 0 {
+				assert.Equal(ErrDuplicate, ack.Err())
+			}
+			b.Stop()
+			p.Assert(t, DedupDroppedCounter)(xmetricstest.Value(tc.duplicates))
+			inserter.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDedupAfterFailure(t *testing.T) {
+	assert := assert.New(t)
+	record := db.Record{DeviceID: "a", Data: []byte("a")}
+	testErr := errors.New("test insert error")
+	inserter := new(mockInserter)
+	inserter.On("InsertRecords", []db.Record{record}).Return(testErr).Once()
+	inserter.On("InsertRecords", []db.Record{record}).Return(nil).Once()
+	tracker := new(mockTracker)
+	tracker.On("TrackTime", mock.Anything)
+	p := xmetricstest.NewProvider(nil, Metrics)
+	b, err := NewBatchInserter(Config{MaxBatchWaitTime: time.Millisecond, Dedup: DedupConfig{Enabled: true}},
+		nil, p, inserter, tracker)
+	require.NoError(t, err)
+	b.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ack, err := b.InsertWithAck(ctx, RecordWithTime{Record: record, Beginning: time.Now()})
+	require.NoError(t, err)
+	assert.Equal(testErr, ack.Wait(ctx))
+
+	// the failed record is redelivered, and isn't a duplicate.
+	ack, err = b.InsertWithAck(ctx, RecordWithTime{Record: record, Beginning: time.Now()})
+	require.NoError(t, err)
+	assert.NoError(ack.Wait(ctx))
+	b.Stop()
+	p.Assert(t, DedupDroppedCounter)(xmetricstest.Value(0))
+	inserter.AssertExpectations(t)
+}
+
+func TestDedupNotQueued(t *testing.T) {
+	assert := assert.New(t)
+	record := db.Record{DeviceID: "a", Data: []byte("a")}
+	b, err := NewBatchInserter(Config{QueueSize: 5, OverflowPolicy: DropNewestPolicy, Dedup: DedupConfig{Enabled: true}},
+		nil, xmetricstest.NewProvider(nil, Metrics), new(mockInserter), nil)
+	require.NoError(t, err)
+
+	// without the batchers started, the queue fills up.
+	for i := 0; i < 5; i++ {
+		require.NoError(t, b.Insert(RecordWithTime{Record: db.Record{DeviceID: "b", Data: []byte{byte(i)}}}))
+	}
+	assert.Equal(ErrQueueFull, b.Insert(RecordWithTime{Record: record}))
+	// the record that wasn't queued isn't a duplicate when inserted again.
+	assert.Equal(ErrQueueFull, b.Insert(RecordWithTime{Record: record}))
+}
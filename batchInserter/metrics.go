@@ -12,6 +12,49 @@ import (
 const (
 	InsertingQueueDepth            = "inserting_queue_depth"
 	DroppedEventsFromDbFailCounter = "dropped_events_db_fail_count"
+	InsertOverflowCounter          = "insert_overflow_count"
+	BatchBytesHistogram            = "batch_bytes"
+	EffectiveBatchSizeGauge        = "effective_batch_size"
+	EffectiveInsertWorkersGauge    = "effective_insert_workers"
+	DedupDroppedCounter            = "dedup_dropped_count"
+	DedupCacheSizeGauge            = "dedup_cache_size"
+	BlacklistedCounter             = "blacklisted_count"
+)
+
+const (
+	// OutcomeLabel is for labeling what happened to a record inserted while
+	// the queue was full.
+	OutcomeLabel = "outcome"
+
+	// BlockedOutcome is when Insert waited for room in the queue.
+	BlockedOutcome = "blocked"
+	// RejectedOutcome is when TryInsert found the queue full or the context
+	// given to InsertContext was done before there was room.
+	RejectedOutcome = "rejected"
+	// DroppedNewestOutcome is when the record being inserted was dropped.
+	DroppedNewestOutcome = "dropped_newest"
+	// DroppedOldestOutcome is when a queued record was dropped to make room.
+	DroppedOldestOutcome = "dropped_oldest"
+	// SpilledOutcome is when the record was sent to the spill sink.
+	SpilledOutcome = "spilled"
+	// SpillFailedOutcome is when sending the record to the spill sink failed.
+	SpillFailedOutcome = "spill_failed"
+)
+
+const (
+	// ReasonLabel is for labeling why a batch was inserted when it was, and
+	// with the blacklist's reason for records discarded for being on it.
+	ReasonLabel = "reason"
+
+	// CountReason is when the batch reached MaxBatchSize records.
+	CountReason = "count"
+	// BytesReason is when the batch reached MaxBatchBytes, or the next
+	// record wouldn't fit.
+	BytesReason = "bytes"
+	// WaitTimeReason is when the batch waited MaxBatchWaitTime.
+	WaitTimeReason = "wait_time"
+	// StoppedReason is when the BatchInserter was stopped.
+	StoppedReason = "stopped"
 )
 
 func Metrics() []xmetrics.Metric {
@@ -26,12 +69,58 @@ func Metrics() []xmetrics.Metric {
 			Help: "The total number of events dropped from the database query failing",
 			Type: "counter",
 		},
+		{
+			Name:       InsertOverflowCounter,
+			Help:       "The total number of records inserted while the queue was full, by what happened to them",
+			Type:       "counter",
+			LabelNames: []string{OutcomeLabel},
+		},
+		{
+			Name:       BatchBytesHistogram,
+			Help:       "A histogram of the bytes in each batch inserted, by why it was inserted",
+			Type:       "histogram",
+			Buckets:    []float64{1024, 4096, 16384, 65536, 262144, 1048576, 4194304, 16777216},
+			LabelNames: []string{ReasonLabel},
+		},
+		{
+			Name: EffectiveBatchSizeGauge,
+			Help: "The batch size currently used, when it is tuned to the database",
+			Type: "gauge",
+		},
+		{
+			Name: EffectiveInsertWorkersGauge,
+			Help: "The number of insert workers currently used, when it is tuned to the database",
+			Type: "gauge",
+		},
+		{
+			Name: DedupDroppedCounter,
+			Help: "The total number of records dropped as duplicates of a recent record",
+			Type: "counter",
+		},
+		{
+			Name: DedupCacheSizeGauge,
+			Help: "The number of recent record keys remembered for dropping duplicates",
+			Type: "gauge",
+		},
+		{
+			Name:       BlacklistedCounter,
+			Help:       "The total number of records discarded because their device is on the blacklist, by the blacklist reason",
+			Type:       "counter",
+			LabelNames: []string{ReasonLabel},
+		},
 	}
 }
 
 type Measures struct {
 	InsertingQueue               metrics.Gauge
 	DroppedEventsFromDbFailCount metrics.Counter
+	Overflow                     metrics.Counter
+	BatchBytes                   metrics.Histogram
+	EffectiveBatchSize           metrics.Gauge
+	EffectiveInsertWorkers       metrics.Gauge
+	DuplicatesDropped            metrics.Counter
+	DedupCacheSize               metrics.Gauge
+	Blacklisted                  metrics.Counter
 }
 
 // NewMeasures constructs a Measures given a go-kit metrics Provider
@@ -39,5 +128,12 @@ func NewMeasures(p provider.Provider) *Measures {
 	return &Measures{
 		InsertingQueue:               p.NewGauge(InsertingQueueDepth),
 		DroppedEventsFromDbFailCount: p.NewCounter(DroppedEventsFromDbFailCounter),
+		Overflow:                     p.NewCounter(InsertOverflowCounter),
+		BatchBytes:                   p.NewHistogram(BatchBytesHistogram, 8),
+		EffectiveBatchSize:           p.NewGauge(EffectiveBatchSizeGauge),
+		EffectiveInsertWorkers:       p.NewGauge(EffectiveInsertWorkersGauge),
+		DuplicatesDropped:            p.NewCounter(DedupDroppedCounter),
+		DedupCacheSize:               p.NewGauge(DedupCacheSizeGauge),
+		Blacklisted:                  p.NewCounter(BlacklistedCounter),
 	}
 }
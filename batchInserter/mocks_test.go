@@ -4,8 +4,10 @@
 package batchInserter
 
 import (
+	"sync"
 	"time"
 
+	"github.com/go-kit/kit/metrics"
 	"github.com/stretchr/testify/mock"
 	db "github.com/xmidt-org/codex-db"
 )
@@ -19,6 +21,16 @@ func (c *mockInserter) InsertRecords(records ...db.Record) error {
 	return args.Error(0)
 }
 
+// recordingInserter keeps the records inserted.
+type recordingInserter struct {
+	records []db.Record
+}
+
+func (r *recordingInserter) InsertRecords(records ...db.Record) error {
+	r.records = append(r.records, records...)
+	return nil
+}
+
 type mockTracker struct {
 	mock.Mock
 }
@@ -26,3 +38,59 @@ type mockTracker struct {
 func (t *mockTracker) TrackTime(d time.Duration) {
 	t.Called(d)
 }
+
+type mockSink struct {
+	mock.Mock
+}
+
+func (s *mockSink) Send(records []db.Record, cause error) error {
+	args := s.Called(records, cause)
+	return args.Error(0)
+}
+
+// recordingHistogram keeps the values observed, by the value of the label
+// they were observed with.
+type recordingHistogram struct {
+	lock     *sync.Mutex
+	label    string
+	observed map[string][]float64
+}
+
+func newRecordingHistogram() *recordingHistogram {
+	return &recordingHistogram{lock: new(sync.Mutex), observed: map[string][]float64{}}
+}
+
+func (h *recordingHistogram) With(labelsAndValues ...string) metrics.Histogram {
+	return &recordingHistogram{lock: h.lock, label: labelsAndValues[len(labelsAndValues)-1], observed: h.observed}
+}
+
+func (h *recordingHistogram) Observe(value float64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.observed[h.label] = append(h.observed[h.label], value)
+}
+
+// signalingCounter closes a channel the first time it is added to with the
+// label value given.
+type signalingCounter struct {
+	value  string
+	label  string
+	once   *sync.Once
+	signal chan struct{}
+}
+
+func newSignalingCounter(value string) *signalingCounter {
+	return &signalingCounter{value: value, once: new(sync.Once), signal: make(chan struct{})}
+}
+
+func (c *signalingCounter) With(labelsAndValues ...string) metrics.Counter {
+	return &signalingCounter{value: c.value, label: labelsAndValues[len(labelsAndValues)-1], once: c.once, signal: c.signal}
+}
+
+func (c *signalingCounter) Add(delta float64) {
+	if c.label == c.value {
+		c.once.Do(func() {
+			close(c.signal)
+		})
+	}
+}
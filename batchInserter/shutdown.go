@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package batchInserter
+
+import (
+	"context"
+
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+)
+
+// DrainReport is what happened to the records in the BatchInserter while
+// Shutdown drained it.
+type DrainReport struct {
+	// Flushed is how many records were inserted.
+	Flushed int
+
+	// Failed is how many records were in batches that failed to insert.
+	Failed int
+
+	// Abandoned is how many records were still queued or batched at the
+	// deadline.  They are given to the undrained handler, or else the dead
+	// letter sink.
+	Abandoned int
+
+	// InFlight is how many records were still being inserted at the
+	// deadline.  Their batches finish in the background.
+	InFlight int
+}
+
+// WithUndrainedHandler sets a function to give the records Shutdown
+// abandons to.  Without it, they are sent to the dead letter sink, if there
+// is one, and otherwise dropped.
+func WithUndrainedHandler(f func([]RecordWithTime)) Option {
+	return func(b *BatchInserter) {
+		b.undrainedFunc = f
+	}
+}
+
+// Shutdown stops the BatchInserter like Stop, but only inserts what it can
+// before ctx is done.  The records left queued or batched at that point are
+// abandoned and their Acks resolved with ErrAbandoned.  The report counts
+// what happened to the records, and ctx's error is returned if the deadline
+// passed before everything was inserted.  If the BatchInserter was already
+// stopped, ErrStopped is returned.
+func (b *BatchInserter) Shutdown(ctx context.Context) (DrainReport, error) {
+	inserted, failed := b.inserted.Load(), b.failed.Load()
+	b.drainContext()
+	closed := make(chan bool, 1)
+	go func() {
+		closed <- b.closeQueues()
+	}()
+	var ok bool
+	select {
+	case ok = <-closed:
+	case <-ctx.Done():
+		// the batchers give up on their records once the queues close.
+		b.cancelDrain()
+		ok = <-closed
+	}
+	if !ok {
+		return DrainReport{}, ErrStopped
+	}
+
+	batchersDone := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(batchersDone)
+	}()
+	var err error
+	select {
+	case <-batchersDone:
+		// Grab all the workers to wait for the last batches.
+		for i := 0; i < b.config.MaxInsertWorkers && err == nil; i++ {
+			err = b.insertWorkers.AcquireCtx(ctx)
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	b.cancelDrain()
+	<-batchersDone
+
+	b.undrainedLock.Lock()
+	undrained := b.undrained
+	b.undrained = nil
+	b.undrainedLock.Unlock()
+	b.handOff(undrained)
+
+	return DrainReport{
+		Flushed:   int(b.inserted.Load() - inserted),
+		Failed:    int(b.failed.Load() - failed),
+		Abandoned: len(undrained),
+		InFlight:  int(b.inFlight.Load()),
+	}, err
+}
+
+func (b *BatchInserter) drainContext() context.Context {
+	b.drainOnce.Do(func() {
+		b.drainCtx, b.cancelDrain = context.WithCancel(context.Background())
+	})
+	return b.drainCtx
+}
+
+// abandon gives up on the records given and the rest of the queue, which
+// must be closed.
+func (b *BatchInserter) abandon(queue chan queuedRecord, batch ...queuedRecord) {
+	for q := range queue {
+		b.queued(-1.0)
+		batch = append(batch, q)
+	}
+	b.undrainedLock.Lock()
+	defer b.undrainedLock.Unlock()
+	b.undrained = append(b.undrained, batch...)
+}
+
+// handOff gives the abandoned records to the undrained handler or the dead
+// letter sink.  If neither takes them, they stay in the write-ahead log.
+func (b *BatchInserter) handOff(undrained []queuedRecord) {
+	if len(undrained) == 0 {
+		return
+	}
+	b.forget(undrained...)
+	resolve(undrained, ErrAbandoned)
+	if b.undrainedFunc != nil {
+		rwts := make([]RecordWithTime, len(undrained))
+		for i, q := range undrained {
+			rwts[i] = q.RecordWithTime
+		}
+		b.undrainedFunc(rwts)
+		b.ackLog(undrained...)
+		return
+	}
+	records := make([]db.Record, len(undrained))
+	for i, q := range undrained {
+		records[i] = q.Record
+	}
+	if b.sendToDeadLetter(records, ErrAbandoned) {
+		b.ackLog(undrained...)
+		return
+	}
+	logging.Error(b.logger).Log(logging.MessageKey(), "Dropped records not inserted before the shutdown deadline",
+		"records", len(undrained))
+}
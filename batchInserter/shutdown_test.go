@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package batchInserter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func TestShutdown(t *testing.T) {
+	assert := assert.New(t)
+	records := []db.Record{
+		{DeviceID: "a", Data: []byte("a")},
+		{DeviceID: "b", Data: []byte("b")},
+		{DeviceID: "c", Data: []byte("c")},
+		{DeviceID: "d", Data: []byte("d")},
+	}
+	inserter := new(mockInserter)
+	inserter.On("InsertRecords", records[:2]).Return(nil).Once()
+	inserter.On("InsertRecords", records[2:]).Return(errors.New("test insert error")).Once()
+	tracker := new(mockTracker)
+	tracker.On("TrackTime", mock.Anything).Times(4)
+	b, err := NewBatchInserter(Config{ParseWorkers: 1, MaxBatchSize: 2}, nil, xmetricstest.NewProvider(nil, Metrics), inserter, tracker)
+	require.NoError(t, err)
+	b.Start()
+
+	for _, r := range records {
+		require.NoError(t, b.Insert(RecordWithTime{Record: r, Beginning: time.Now()}))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report, err := b.Shutdown(ctx)
+	assert.NoError(err)
+	assert.Equal(DrainReport{Flushed: 2, Failed: 2}, report)
+
+	_, err = b.Shutdown(ctx)
+	assert.Equal(ErrStopped, err)
+	inserter.AssertExpectations(t)
+	tracker.AssertExpectations(t)
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	tests := []struct {
+		description   string
+		useHandler    bool
+		useDeadLetter bool
+	}{
+		{
+			description: "Undrained Handler",
+			useHandler:  true,
+		},
+		{
+			description:   "Dead Letter",
+			useDeadLetter: true,
+		},
+		{
+			description: "Dropped",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			records := []db.Record{
+				{DeviceID: "a", Data: []byte("a")},
+				{DeviceID: "b", Data: []byte("b")},
+				{DeviceID: "c", Data: []byte("c")},
+				{DeviceID: "d", Data: []byte("d")},
+				{DeviceID: "e", Data: []byte("e")},
+			}
+			inserting, release := make(chan struct{}), make(chan struct{})
+			inserter := new(mockInserter)
+			inserter.On("InsertRecords", records[:2]).Run(func(mock.Arguments) {
+				close(inserting)
+				<-release
+			}).Return(nil).Once()
+			tracker := new(mockTracker)
+			tracker.On("TrackTime", mock.Anything).Times(2)
+
+			var options []Option
+			var undrained []RecordWithTime
+			if tc.useHandler {
+				options = append(options, WithUndrainedHandler(func(rwts []RecordWithTime) {
+					undrained = rwts
+				}))
+			}
+			sink := new(mockSink)
+			if tc.useDeadLetter {
+				sink.On("Send", records[2:], ErrAbandoned).Return(nil).Once()
+				options = append(options, WithDeadLetter(sink))
+			}
+			b, err := NewBatchInserter(Config{ParseWorkers: 1, MaxInsertWorkers: 1, MaxBatchSize: 2}, nil,
+				xmetricstest.NewProvider(nil, Metrics), inserter, tracker, options...)
+			require.NoError(t, err)
+			b.Start()
+
+			var acks []*Ack
+			for _, r := range records {
+				ack, err := b.InsertWithAck(context.Background(), RecordWithTime{Record: r, Beginning: time.Now()})
+				require.NoError(t, err)
+				acks = append(acks, ack)
+			}
+			<-inserting
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			report, err := b.Shutdown(ctx)
+			assert.Equal(context.DeadlineExceeded, err)
+			assert.Equal(DrainReport{Abandoned: 3, InFlight: 2}, report)
+			for _, ack := range acks[2:] {
+				assert.Equal(ErrAbandoned, ack.Err())
+			}
+			if tc.useHandler {
+				require.Len(t, undrained, 3)
+				for i, rwt := range undrained {
+					assert.Equal(records[i+2], rwt.Record)
+				}
+			}
+
+			// the batch in flight still finishes.
+			close(release)
+			for _, ack := range acks[:2] {
+				assert.NoError(ack.Wait(context.Background()))
+			}
+			inserter.AssertExpectations(t)
+			sink.AssertExpectations(t)
+		})
+	}
+}
+
+func TestShutdownBlockedInsert(t *testing.T) {
+	assert := assert.New(t)
+	inserting, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	inserter := new(mockInserter)
+	inserter.On("InsertRecords", mock.Anything).Run(func(mock.Arguments) {
+		close(inserting)
+		<-release
+	}).Return(nil).Once()
+	tracker := new(mockTracker)
+	tracker.On("TrackTime", mock.Anything)
+	b, err := NewBatchInserter(Config{ParseWorkers: 1, MaxInsertWorkers: 1, MaxBatchSize: 2}, nil,
+		xmetricstest.NewProvider(nil, Metrics), inserter, tracker)
+	require.NoError(t, err)
+	blocking := newSignalingCounter(BlockedOutcome)
+	b.measures.Overflow = blocking
+	b.Start()
+
+	record := func(i int) RecordWithTime {
+		return RecordWithTime{Record: db.Record{DeviceID: "a", Data: []byte{byte(i)}}, Beginning: time.Now()}
+	}
+	require.NoError(t, b.Insert(record(0)))
+	require.NoError(t, b.Insert(record(1)))
+	<-inserting
+
+	// the next batch waits for the stalled one, so the queue fills up and
+	// the insert after that blocks.
+	require.NoError(t, b.Insert(record(2)))
+	require.NoError(t, b.Insert(record(3)))
+	for len(b.insertQueue) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	queued := 4
+	for ; b.TryInsert(record(queued)) == nil; queued++ {
+	}
+	blocked := make(chan error, 1)
+	go func() {
+		blocked <- b.Insert(record(queued))
+	}()
+	<-blocking.signal
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		report, err := b.Shutdown(ctx)
+		assert.Equal(context.DeadlineExceeded, err)
+		assert.Equal(DrainReport{Abandoned: queued - 2, InFlight: 2}, report)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown didn't return by its deadline")
+	}
+	assert.Equal(ErrStopped, <-blocked)
+}
@@ -86,6 +86,12 @@ type Updater interface {
 	GetBlacklist() ([]BlackListedItem, error)
 }
 
+// Editor is for adding devices to and removing them from the blacklist.
+type Editor interface {
+	AddBlacklist(item BlackListedItem) error
+	RemoveBlacklist(id string) error
+}
+
 type listRefresher struct {
 	logger log.Logger
 
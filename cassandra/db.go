@@ -6,14 +6,20 @@
 package cassandra
 
 import (
+	"crypto/tls"
 	"errors"
-	"github.com/InVisionApp/go-health/v2"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/InVisionApp/go-health/v2"
+
 	"github.com/go-kit/kit/metrics/provider"
 	"github.com/goph/emperror"
 	db "github.com/xmidt-org/codex-db"
 	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/xmidt-org/codex-db/migrate"
+	"github.com/xmidt-org/codex-db/tlsconfig"
 	"github.com/yugabyte/gocql"
 )
 
@@ -25,6 +31,11 @@ const (
 	defaultMaxNumberConnsPerHost = 2
 )
 
+var (
+	errInvalidConsistency = errors.New("invalid health check consistency")
+	errConflictingTLS     = errors.New("in-memory TLS can't be used with the SSLRootCert, SSLKey, or SSLCert files")
+)
+
 type Config struct {
 	// Hosts to  connect to. Must have at least one
 	Hosts []string
@@ -46,10 +57,18 @@ type Config struct {
 	// See InSecureSkipVerify in http://golang.org/pkg/crypto/tls/ for more info
 	EnableHostVerification bool
 
+	// TLS configures encryption from TLS material held in memory, such as
+	// PEM blobs, and can't be used with the certificate file settings.  It
+	// is validated when connecting.
+	TLS tlsconfig.Config
+
 	// Username to authenticate into the cluster. Password must also be provided.
 	Username string
 	// Password to authenticate into the cluster. Username must also be provided.
 	Password string
+	// PasswordFile is the path of a file holding the password, used if Password is empty.
+	// The file is read each time a session is created, so a rotated password is picked up.
+	PasswordFile string
 
 	// NumRetries for connecting to the db
 	NumRetries int
@@ -59,16 +78,50 @@ type Config struct {
 
 	// MaxConnsPerHost max number of connections per host
 	MaxConnsPerHost int
+
+	// HealthCheckInterval is how often the health check registered with
+	// go-health queries the cluster.
+	HealthCheckInterval time.Duration
+
+	// HealthCheckConsistency is the consistency of the health check query,
+	// such as ONE or LOCAL_QUORUM.  Defaults to ONE.
+	HealthCheckConsistency string
+
+	// AutoMigrate creates the schema, or upgrades it to the latest version,
+	// when connecting.  Schema changes in cassandra aren't transactional, so
+	// only one instance should have this set.
+	AutoMigrate bool
+
+	// Baseline, if set, records that a schema created by hand is at this
+	// version when connecting, before AutoMigrate runs.  Connecting fails if
+	// the schema already has a version, so it is only for a one-off
+	// connection such as codexdb schema baseline.
+	Baseline int
+
+	// SchemaCheck compares the live schema with what the driver needs when
+	// connecting, reporting missing tables, columns, and indexes and columns
+	// of the wrong type.  It is off, warn to log any drift with the health
+	// logger, or error to fail with a migrate.SchemaDrift, which
+	// migrate.AsSchemaDrift finds in the error.  Defaults to off.
+	SchemaCheck string
+
+	// SessionRecovery configures rebuilding the session when it dies or the
+	// credential files change.
+	SessionRecovery SessionRecoveryConfig
 }
 
 type Connection struct {
-	finder       finder
-	findList     findList
-	deviceFinder deviceFinder
-	multiInsert  multiInserter
-	closer       closer
-	pinger       pinger
-
+	finder        finder
+	findList      findList
+	listEditor    listEditor
+	deviceFinder  deviceFinder
+	multiInsert   multiInserter
+	closer        closer
+	pinger        pinger
+	healthQuerier healthQuerier
+
+	hostStates  *hostStates
+	tlsConfig   *tls.Config
 	health      *health.Health
 	measures    Measures
 	stopThreads []chan struct{}
@@ -81,18 +134,83 @@ func CreateDbConnection(config Config, provider provider.Provider, health *healt
 
 	validateConfig(&config)
 
+	healthConsistency, err := gocql.ParseConsistencyWrapper(config.HealthCheckConsistency)
+	if err != nil {
+		return &Connection{}, emperror.WrapWith(errInvalidConsistency, err.Error(), "consistency", config.HealthCheckConsistency)
+	}
+	tlsConfig, err := resolveTLSConfig(config)
+	if err != nil {
+		return &Connection{}, emperror.Wrap(err, "Connecting to database failed")
+	}
+	config.SchemaCheck, err = migrate.ParseSchemaCheck(config.SchemaCheck)
+	if err != nil {
+		return &Connection{}, emperror.Wrap(err, "Invalid cassandra config")
+	}
+	states := newHostStates()
+
+	dbConn := Connection{
+		health:     health,
+		hostStates: states,
+		tlsConfig:  tlsConfig,
+		measures:   NewMeasures(provider),
+	}
+
+	prepared := !config.AutoMigrate && config.Baseline == 0 && config.SchemaCheck == migrate.SchemaCheckOff
+	connect := func() (*dbMeasuresDecorator, error) {
+		if !prepared {
+			if err := dbConn.prepareSchema(config); err != nil {
+				return nil, err
+			}
+			prepared = true
+		}
+		return dbConn.connect(config)
+	}
+	conn, err := connect()
+
+	// retry if it fails
+	waitTime := 1 * time.Second
+	for attempt := 0; attempt < config.NumRetries && err != nil; attempt++ {
+		time.Sleep(waitTime)
+		conn, err = connect()
+		waitTime = waitTime * config.WaitTimeMult
+	}
+	if err != nil {
+		return &Connection{}, emperror.WrapWith(err, "Connecting to database failed", "hosts", config.Hosts)
+	}
+
+	if config.SessionRecovery.Enabled {
+		dbConn.setupSessionRecovery(config, healthConsistency, conn)
+	} else {
+		dbConn.setExecuter(conn)
+	}
+
+	dbConn.setupHealthCheck(config.HealthCheckInterval, healthConsistency)
+
+	return &dbConn, nil
+}
+
+// newClusterConfig creates the configuration for a new session.  Each
+// session needs its own, as the host selection policy can't be shared.
+func newClusterConfig(config Config, tlsConfig *tls.Config, states *hostStates, health *health.Health) (*gocql.ClusterConfig, error) {
 	clusterConfig := gocql.NewCluster(config.Hosts...)
 	clusterConfig.Consistency = gocql.LocalQuorum
 	clusterConfig.Keyspace = config.Database
 	clusterConfig.Timeout = config.OpTimeout
 	// let retry package handle it
 	clusterConfig.RetryPolicy = &gocql.SimpleRetryPolicy{NumRetries: 1}
+	// watch for hosts going up and down
+	clusterConfig.PoolConfig.HostSelectionPolicy = newHostStatePolicy(states)
 	// create warn logger from health logger
-	if health.Logger != nil {
+	if health != nil && health.Logger != nil {
 		clusterConfig.Logger = &goCqlLogger{Logger: health.Logger}
 	}
 	// setup ssl
-	if config.SSLRootCert != "" && config.SSLCert != "" && config.SSLKey != "" {
+	if tlsConfig != nil {
+		clusterConfig.SslOpts = &gocql.SslOptions{
+			Config:                 tlsConfig,
+			EnableHostVerification: config.EnableHostVerification,
+		}
+	} else if config.SSLRootCert != "" && config.SSLCert != "" && config.SSLKey != "" {
 		clusterConfig.SslOpts = &gocql.SslOptions{
 			CertPath:               config.SSLCert,
 			KeyPath:                config.SSLKey,
@@ -101,39 +219,118 @@ func CreateDbConnection(config Config, provider provider.Provider, health *healt
 		}
 	}
 	// setup authentication
-	if config.Username != "" && config.Password != "" {
+	password, err := readPassword(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Username != "" && password != "" {
 		clusterConfig.Authenticator = gocql.PasswordAuthenticator{
 			Username: config.Username,
-			Password: config.Password,
+			Password: password,
 		}
 	}
+	return clusterConfig, nil
+}
 
-	dbConn := Connection{
-		health:   health,
-		measures: NewMeasures(provider),
+// resolveTLSConfig validates and builds the in-memory TLS configuration,
+// returning nil if there is none.
+func resolveTLSConfig(config Config) (*tls.Config, error) {
+	if !config.TLS.IsSet() {
+		return nil, nil
+	}
+	if config.SSLRootCert != "" || config.SSLKey != "" || config.SSLCert != "" {
+		return nil, emperror.Wrap(errConflictingTLS, "Invalid cassandra config")
 	}
+	return config.TLS.Build()
+}
 
-	conn, err := connectWithMetrics(clusterConfig, dbConn.measures)
+// readPassword returns Password, or the contents of PasswordFile if no
+// Password is set.
+func readPassword(config Config) (string, error) {
+	if config.Password != "" || config.PasswordFile == "" {
+		return config.Password, nil
+	}
+	contents, err := os.ReadFile(config.PasswordFile)
+	if err != nil {
+		return "", emperror.WrapWith(err, "Reading password file failed", "password file", config.PasswordFile)
+	}
+	// files commonly end with a newline that isn't part of the password.
+	return strings.TrimRight(string(contents), "\r\n"), nil
+}
 
-	// retry if it fails
-	waitTime := 1 * time.Second
-	for attempt := 0; attempt < config.NumRetries && err != nil; attempt++ {
-		time.Sleep(waitTime)
-		conn, err = connectWithMetrics(clusterConfig, dbConn.measures)
-		waitTime = waitTime * config.WaitTimeMult
+// connect creates a new session to the cluster.
+func (c *Connection) connect(config Config) (*dbMeasuresDecorator, error) {
+	clusterConfig, err := newClusterConfig(config, c.tlsConfig, c.hostStates, c.health)
+	if err != nil {
+		return nil, err
 	}
+	return connectWithMetrics(clusterConfig, c.measures)
+}
+
+// prepareSchema migrates and checks the schema as configured, using a
+// session outside of the keyspace, which may not exist yet.
+func (c *Connection) prepareSchema(config Config) error {
+	clusterConfig, err := newClusterConfig(config, c.tlsConfig, newHostStates(), c.health)
 	if err != nil {
-		return &Connection{}, emperror.WrapWith(err, "Connecting to database failed", "hosts", config.Hosts)
+		return err
+	}
+	clusterConfig.Keyspace = ""
+	session, err := clusterConfig.CreateSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	if config.AutoMigrate || config.Baseline != 0 {
+		migrator, err := migrate.NewCassandra(session)
+		if err != nil {
+			return err
+		}
+		if config.Baseline != 0 {
+			if err = migrator.Baseline(config.Baseline); err != nil {
+				return emperror.Wrap(err, "Baselining database schema failed")
+			}
+		}
+		if config.AutoMigrate {
+			if err = migrator.MigrateUp(); err != nil {
+				return emperror.Wrap(err, "Migrating database schema failed")
+			}
+		}
+	}
+	if config.SchemaCheck == migrate.SchemaCheckOff {
+		return nil
+	}
+	drift, err := migrate.CheckCassandra(session)
+	if err != nil {
+		return emperror.Wrap(err, "Checking database schema failed")
 	}
+	return c.reportDrift(drift, config.SchemaCheck)
+}
 
-	dbConn.finder = conn
-	dbConn.findList = conn
-	dbConn.deviceFinder = conn
-	dbConn.multiInsert = conn
-	dbConn.closer = conn
-	dbConn.pinger = conn
+// reportDrift fails with the drift found if the schema check is set to
+// error, and otherwise logs it as a warning.
+func (c *Connection) reportDrift(drift *migrate.SchemaDrift, mode string) error {
+	if drift.Empty() {
+		return nil
+	}
+	if mode == migrate.SchemaCheckError {
+		return emperror.Wrap(drift, "Checking database schema failed")
+	}
+	if c.health != nil && c.health.Logger != nil {
+		c.health.Logger.Warn(drift.Error())
+	}
+	return nil
+}
 
-	return &dbConn, nil
+func (c *Connection) setExecuter(e executer) {
+	c.finder = e
+	c.findList = e
+	c.listEditor = e
+	c.deviceFinder = e
+	c.multiInsert = e
+	c.closer = e
+	c.pinger = e
+	c.healthQuerier = e
 }
 
 func validateConfig(config *Config) {
@@ -155,6 +352,13 @@ func validateConfig(config *Config) {
 	if config.MaxConnsPerHost <= 0 {
 		config.MaxConnsPerHost = defaultMaxNumberConnsPerHost
 	}
+	if config.HealthCheckInterval == zeroDuration {
+		config.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if config.HealthCheckConsistency == "" {
+		config.HealthCheckConsistency = defaultHealthCheckConsistency
+	}
+	validateSessionRecoveryConfig(&config.SessionRecovery)
 }
 
 // GetRecords returns a list of records for a given device.
@@ -236,6 +440,29 @@ func (c *Connection) GetBlacklist() (list []blacklist.BlackListedItem, err error
 	return
 }
 
+// AddBlacklist adds a device id or pattern to the blacklist, replacing its
+// reason if it is already there.
+func (c *Connection) AddBlacklist(item blacklist.BlackListedItem) error {
+	err := c.listEditor.addBlacklist(item)
+	if err != nil {
+		c.measures.SQLQueryFailureCount.With(db.TypeLabel, db.BlacklistWriteType).Add(1.0)
+		return emperror.WrapWith(err, "Adding to blacklist failed", "id", item.ID)
+	}
+	c.measures.SQLQuerySuccessCount.With(db.TypeLabel, db.BlacklistWriteType).Add(1.0)
+	return nil
+}
+
+// RemoveBlacklist removes a device id or pattern from the blacklist.
+func (c *Connection) RemoveBlacklist(id string) error {
+	err := c.listEditor.removeBlacklist(id)
+	if err != nil {
+		c.measures.SQLQueryFailureCount.With(db.TypeLabel, db.BlacklistWriteType).Add(1.0)
+		return emperror.WrapWith(err, "Removing from blacklist failed", "id", id)
+	}
+	c.measures.SQLQuerySuccessCount.With(db.TypeLabel, db.BlacklistWriteType).Add(1.0)
+	return nil
+}
+
 // GetDeviceList returns a list of device ids where the device id is greater
 // than the offset device id.
 func (c *Connection) GetDeviceList(startDate time.Time, endDate time.Time, offset int, limit int) ([]string, error) {
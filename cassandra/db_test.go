@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
 	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
 	"github.com/yugabyte/gocql"
 )
@@ -496,6 +497,66 @@ func TestPing(t *testing.T) {
 	}
 }
 
+func TestEditBlacklist(t *testing.T) {
+	item := blacklist.BlackListedItem{ID: "mac:112233445566", Reason: "test reason"}
+	tests := []struct {
+		description           string
+		remove                bool
+		expectedSuccessMetric float64
+		expectedFailureMetric float64
+		expectedErr           error
+	}{
+		{
+			description:           "Add Success",
+			expectedSuccessMetric: 1.0,
+		},
+		{
+			description:           "Add Error",
+			expectedFailureMetric: 1.0,
+			expectedErr:           errors.New("test add error"),
+		},
+		{
+			description:           "Remove Success",
+			remove:                true,
+			expectedSuccessMetric: 1.0,
+		},
+		{
+			description:           "Remove Error",
+			remove:                true,
+			expectedFailureMetric: 1.0,
+			expectedErr:           errors.New("test remove error"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			mockObj := new(mockListEditor)
+			p := xmetricstest.NewProvider(nil, Metrics)
+			dbConnection := Connection{
+				measures:   NewMeasures(p),
+				listEditor: mockObj,
+			}
+
+			var err error
+			if tc.remove {
+				mockObj.On("removeBlacklist", item.ID).Return(tc.expectedErr).Once()
+				err = dbConnection.RemoveBlacklist(item.ID)
+			} else {
+				mockObj.On("addBlacklist", item).Return(tc.expectedErr).Once()
+				err = dbConnection.AddBlacklist(item)
+			}
+			mockObj.AssertExpectations(t)
+			p.Assert(t, SQLQuerySuccessCounter, db.TypeLabel, db.BlacklistWriteType)(xmetricstest.Value(tc.expectedSuccessMetric))
+			p.Assert(t, SQLQueryFailureCounter, db.TypeLabel, db.BlacklistWriteType)(xmetricstest.Value(tc.expectedFailureMetric))
+			if tc.expectedErr == nil || err == nil {
+				assert.Equal(tc.expectedErr, err)
+			} else {
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+			}
+		})
+	}
+}
+
 func TestImplementsInterfaces(t *testing.T) {
 	var (
 		dbConn interface{}
@@ -506,4 +567,6 @@ func TestImplementsInterfaces(t *testing.T) {
 	assert.True(ok, "not an inserter")
 	_, ok = dbConn.(db.RecordGetter)
 	assert.True(ok, "not an record getter")
+	_, ok = dbConn.(blacklist.Editor)
+	assert.True(ok, "not a blacklist editor")
 }
@@ -19,6 +19,10 @@ type (
 	findList interface {
 		findBlacklist() ([]blacklist.BlackListedItem, error)
 	}
+	listEditor interface {
+		addBlacklist(item blacklist.BlackListedItem) error
+		removeBlacklist(id string) error
+	}
 	deviceFinder interface {
 		getList(startDate time.Time, endDate time.Time, offset int, limit int) ([]string, error)
 	}
@@ -31,6 +35,9 @@ type (
 	closer interface {
 		close() error
 	}
+	healthQuerier interface {
+		checkHealth(consistency gocql.Consistency) error
+	}
 )
 
 type dbDecorator struct {
@@ -125,6 +132,14 @@ func (b *dbDecorator) findBlacklist() ([]blacklist.BlackListedItem, error) {
 	return records, err
 }
 
+func (b *dbDecorator) addBlacklist(item blacklist.BlackListedItem) error {
+	return b.session.Query("INSERT INTO devices.blacklist (device_id, reason) VALUES (?, ?)", item.ID, item.Reason).Exec()
+}
+
+func (b *dbDecorator) removeBlacklist(id string) error {
+	return b.session.Query("DELETE FROM devices.blacklist WHERE device_id = ?", id).Exec()
+}
+
 func (b *dbDecorator) insert(records []db.Record) (int, error) {
 
 	batch := b.session.NewBatch(gocql.UnloggedBatch)
@@ -152,6 +167,11 @@ func (b *dbDecorator) ping() error {
 	}
 	return nil
 }
+
+func (b *dbDecorator) checkHealth(consistency gocql.Consistency) error {
+	return b.session.Query(healthCheckQuery).Consistency(consistency).Exec()
+}
+
 func (b *dbDecorator) close() error {
 	b.session.Close()
 	return nil
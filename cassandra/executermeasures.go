@@ -15,10 +15,12 @@ type dbMeasuresDecorator struct {
 
 	finder
 	findList
+	listEditor
 	deviceFinder
 	multiInserter
 	pinger
 	closer
+	healthQuerier
 }
 
 const CountLabel = "count"
@@ -54,6 +56,26 @@ func (b *dbMeasuresDecorator) findBlacklist() ([]blacklist.BlackListedItem, erro
 	return records, err
 }
 
+func (b *dbMeasuresDecorator) addBlacklist(item blacklist.BlackListedItem) error {
+	b.measures.PoolInUseConnections.Add(1.0)
+	now := time.Now()
+	err := b.listEditor.addBlacklist(item)
+	b.measures.SQLDuration.With(db.TypeLabel, db.BlacklistWriteType).Observe(time.Since(now).Seconds())
+	b.measures.PoolInUseConnections.Add(-1.0)
+
+	return err
+}
+
+func (b *dbMeasuresDecorator) removeBlacklist(id string) error {
+	b.measures.PoolInUseConnections.Add(1.0)
+	now := time.Now()
+	err := b.listEditor.removeBlacklist(id)
+	b.measures.SQLDuration.With(db.TypeLabel, db.BlacklistWriteType).Observe(time.Since(now).Seconds())
+	b.measures.PoolInUseConnections.Add(-1.0)
+
+	return err
+}
+
 func (b *dbMeasuresDecorator) insert(records []db.Record) (int, error) {
 	b.measures.PoolInUseConnections.Add(1.0)
 	now := time.Now()
@@ -73,6 +95,17 @@ func (b *dbMeasuresDecorator) ping() error {
 
 	return err
 }
+
+func (b *dbMeasuresDecorator) checkHealth(consistency gocql.Consistency) error {
+	b.measures.PoolInUseConnections.Add(1.0)
+	now := time.Now()
+	err := b.healthQuerier.checkHealth(consistency)
+	b.measures.SQLDuration.With(db.TypeLabel, db.PingType).Observe(time.Since(now).Seconds())
+	b.measures.PoolInUseConnections.Add(-1.0)
+
+	return err
+}
+
 func (b *dbMeasuresDecorator) close() error {
 
 	err := b.closer.close()
@@ -91,10 +124,12 @@ func connectWithMetrics(clusterConfig *gocql.ClusterConfig, measures Measures) (
 		measures:      measures,
 		finder:        db,
 		findList:      db,
+		listEditor:    db,
 		deviceFinder:  db,
 		multiInserter: db,
 		pinger:        db,
 		closer:        db,
+		healthQuerier: db,
 	}, nil
 
 }
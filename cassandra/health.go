@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandra
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/InVisionApp/go-health/v2"
+	"github.com/yugabyte/gocql"
+)
+
+const (
+	defaultHealthCheckInterval    = 5 * time.Second
+	defaultHealthCheckConsistency = "ONE"
+
+	healthCheckQuery = "SELECT now() FROM system.local"
+
+	hostUp   = "up"
+	hostDown = "down"
+)
+
+// hostStates keeps track of whether each host of the cluster is up or down,
+// as reported by gocql.
+type hostStates struct {
+	lock  sync.RWMutex
+	hosts map[string]string
+}
+
+func newHostStates() *hostStates {
+	return &hostStates{hosts: make(map[string]string)}
+}
+
+func (h *hostStates) set(host string, state string) {
+	h.lock.Lock()
+	h.hosts[host] = state
+	h.lock.Unlock()
+}
+
+func (h *hostStates) remove(host string) {
+	h.lock.Lock()
+	delete(h.hosts, host)
+	h.lock.Unlock()
+}
+
+func (h *hostStates) snapshot() map[string]string {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	hosts := make(map[string]string, len(h.hosts))
+	for host, state := range h.hosts {
+		hosts[host] = state
+	}
+	return hosts
+}
+
+// hostStatePolicy wraps the host selection policy of a session to watch the
+// host events gocql sends it.
+type hostStatePolicy struct {
+	gocql.HostSelectionPolicy
+	states *hostStates
+}
+
+// newHostStatePolicy wraps gocql's default host selection policy.  A policy
+// can't be shared between sessions, so each session needs a new one.
+func newHostStatePolicy(states *hostStates) gocql.HostSelectionPolicy {
+	return &hostStatePolicy{
+		HostSelectionPolicy: gocql.YBPartitionAwareHostPolicy(gocql.RoundRobinHostPolicy()),
+		states:              states,
+	}
+}
+
+func (p *hostStatePolicy) AddHost(host *gocql.HostInfo) {
+	state := hostDown
+	if host.IsUp() {
+		state = hostUp
+	}
+	p.states.set(host.ConnectAddressAndPort(), state)
+	p.HostSelectionPolicy.AddHost(host)
+}
+
+func (p *hostStatePolicy) RemoveHost(host *gocql.HostInfo) {
+	p.states.remove(host.ConnectAddressAndPort())
+	p.HostSelectionPolicy.RemoveHost(host)
+}
+
+func (p *hostStatePolicy) HostUp(host *gocql.HostInfo) {
+	p.states.set(host.ConnectAddressAndPort(), hostUp)
+	p.HostSelectionPolicy.HostUp(host)
+}
+
+func (p *hostStatePolicy) HostDown(host *gocql.HostInfo) {
+	p.states.set(host.ConnectAddressAndPort(), hostDown)
+	p.HostSelectionPolicy.HostDown(host)
+}
+
+// HealthStatus is the detail reported to go-health by the cassandra health
+// check.
+type HealthStatus struct {
+	// Hosts maps each host of the cluster to "up" or "down".
+	Hosts map[string]string `json:"hosts"`
+
+	// DownHosts lists the hosts that are down.
+	DownHosts []string `json:"downHosts,omitempty"`
+}
+
+// healthChecker is a go-health checker that runs a lightweight query against
+// the cluster.
+type healthChecker struct {
+	querier     healthQuerier
+	consistency gocql.Consistency
+	states      *hostStates
+}
+
+// Status runs the health check query, returning the state of each host.
+func (h *healthChecker) Status() (interface{}, error) {
+	status := HealthStatus{Hosts: h.states.snapshot()}
+	for host, state := range status.Hosts {
+		if state == hostDown {
+			status.DownHosts = append(status.DownHosts, host)
+		}
+	}
+	sort.Strings(status.DownHosts)
+	return status, h.querier.checkHealth(h.consistency)
+}
+
+func (c *Connection) setupHealthCheck(interval time.Duration, consistency gocql.Consistency) {
+	if c.health == nil {
+		return
+	}
+	c.health.AddCheck(&health.Config{
+		Name: "cassandra-check",
+		Checker: &healthChecker{
+			querier:     c,
+			consistency: consistency,
+			states:      c.hostStates,
+		},
+		Interval: interval,
+		Fatal:    true,
+	})
+}
+
+// checkHealth lets the health checker use the connection's current executer.
+func (c *Connection) checkHealth(consistency gocql.Consistency) error {
+	return c.healthQuerier.checkHealth(consistency)
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandra
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/InVisionApp/go-health/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/yugabyte/gocql"
+)
+
+func TestHealthCheckerStatus(t *testing.T) {
+	tests := []struct {
+		description    string
+		hosts          map[string]string
+		queryErr       error
+		expectedStatus HealthStatus
+	}{
+		{
+			description: "Success",
+			hosts:       map[string]string{"10.0.0.1:9042": hostUp, "10.0.0.2:9042": hostUp},
+			expectedStatus: HealthStatus{
+				Hosts: map[string]string{"10.0.0.1:9042": hostUp, "10.0.0.2:9042": hostUp},
+			},
+		},
+		{
+			description: "Host Down",
+			hosts:       map[string]string{"10.0.0.1:9042": hostUp, "10.0.0.3:9042": hostDown, "10.0.0.2:9042": hostDown},
+			expectedStatus: HealthStatus{
+				Hosts:     map[string]string{"10.0.0.1:9042": hostUp, "10.0.0.3:9042": hostDown, "10.0.0.2:9042": hostDown},
+				DownHosts: []string{"10.0.0.2:9042", "10.0.0.3:9042"},
+			},
+		},
+		{
+			description: "Query Error",
+			hosts:       map[string]string{"10.0.0.1:9042": hostDown},
+			queryErr:    errors.New("test query error"),
+			expectedStatus: HealthStatus{
+				Hosts:     map[string]string{"10.0.0.1:9042": hostDown},
+				DownHosts: []string{"10.0.0.1:9042"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			querier := new(mockHealthQuerier)
+			querier.On("checkHealth", gocql.LocalQuorum).Return(tc.queryErr).Once()
+			states := newHostStates()
+			for host, state := range tc.hosts {
+				states.set(host, state)
+			}
+			checker := healthChecker{
+				querier:     querier,
+				consistency: gocql.LocalQuorum,
+				states:      states,
+			}
+
+			status, err := checker.Status()
+			querier.AssertExpectations(t)
+			assert.Equal(tc.expectedStatus, status)
+			assert.Equal(tc.queryErr, err)
+		})
+	}
+}
+
+func TestHostStates(t *testing.T) {
+	assert := assert.New(t)
+	states := newHostStates()
+	states.set("10.0.0.1:9042", hostUp)
+	states.set("10.0.0.2:9042", hostUp)
+	states.set("10.0.0.2:9042", hostDown)
+	snapshot := states.snapshot()
+	states.remove("10.0.0.1:9042")
+	assert.Equal(map[string]string{"10.0.0.1:9042": hostUp, "10.0.0.2:9042": hostDown}, snapshot)
+	assert.Equal(map[string]string{"10.0.0.2:9042": hostDown}, states.snapshot())
+}
+
+func TestSetupHealthCheck(t *testing.T) {
+	assert := assert.New(t)
+	querier := new(mockHealthQuerier)
+	querier.On("checkHealth", gocql.One).Return(nil).Once()
+	h := health.New()
+	dbConnection := Connection{
+		health:        h,
+		healthQuerier: querier,
+		hostStates:    newHostStates(),
+	}
+	dbConnection.setupHealthCheck(defaultHealthCheckInterval, gocql.One)
+	assert.Nil(h.Start())
+	defer h.Stop()
+
+	assert.Eventually(func() bool {
+		states, _, err := h.State()
+		return err == nil && len(states) == 1 && states["cassandra-check"].Status == "ok"
+	}, defaultHealthCheckInterval, 10*defaultHealthCheckInterval/1000)
+	querier.AssertExpectations(t)
+
+	// no health to register with is fine.
+	(&Connection{}).setupHealthCheck(defaultHealthCheckInterval, gocql.One)
+}
@@ -26,6 +26,9 @@ const (
 	SQLInsertedRecordsCounter = "sql_inserted_rows_count"
 	SQLReadRecordsCounter     = "sql_read_rows_count"
 	SQLDeletedRecordsCounter  = "sql_deleted_rows_count"
+
+	SessionReconnectCounter        = "session_reconnect_count"
+	SessionReconnectFailureCounter = "session_reconnect_failure_count"
 )
 
 // Metrics returns the Metrics relevant to this package
@@ -70,6 +73,17 @@ func Metrics() []xmetrics.Metric {
 			Type: "counter",
 			Help: "The total number of rows deleted",
 		},
+		{
+			Name:       SessionReconnectCounter,
+			Type:       "counter",
+			Help:       "The total number of times the session was rebuilt",
+			LabelNames: []string{ReasonLabel},
+		},
+		{
+			Name: SessionReconnectFailureCounter,
+			Type: "counter",
+			Help: "The total number of failed attempts to rebuild the session",
+		},
 	}
 }
 
@@ -81,6 +95,9 @@ type Measures struct {
 	SQLInsertedRecords   metrics.Counter
 	SQLReadRecords       metrics.Counter
 	SQLDeletedRecords    metrics.Counter
+
+	SessionReconnects        metrics.Counter
+	SessionReconnectFailures metrics.Counter
 }
 
 func NewMeasures(p provider.Provider) Measures {
@@ -92,5 +109,8 @@ func NewMeasures(p provider.Provider) Measures {
 		SQLInsertedRecords:   p.NewCounter(SQLInsertedRecordsCounter),
 		SQLReadRecords:       p.NewCounter(SQLReadRecordsCounter),
 		SQLDeletedRecords:    p.NewCounter(SQLDeletedRecordsCounter),
+
+		SessionReconnects:        p.NewCounter(SessionReconnectCounter),
+		SessionReconnectFailures: p.NewCounter(SessionReconnectFailureCounter),
 	}
 }
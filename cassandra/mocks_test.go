@@ -7,6 +7,8 @@ import (
 	"encoding/json"
 	"github.com/stretchr/testify/mock"
 	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/yugabyte/gocql"
 	"time"
 )
 
@@ -59,3 +61,75 @@ func (d *mockPing) ping() error {
 	args := d.Called()
 	return args.Error(0)
 }
+
+type mockHealthQuerier struct {
+	mock.Mock
+}
+
+func (h *mockHealthQuerier) checkHealth(consistency gocql.Consistency) error {
+	args := h.Called(consistency)
+	return args.Error(0)
+}
+
+type mockExecuter struct {
+	mock.Mock
+}
+
+func (e *mockExecuter) findRecords(limit int, filter string, where ...interface{}) ([]db.Record, error) {
+	args := e.Called(limit, filter, where)
+	return args.Get(0).([]db.Record), args.Error(1)
+}
+
+func (e *mockExecuter) findBlacklist() ([]blacklist.BlackListedItem, error) {
+	args := e.Called()
+	return args.Get(0).([]blacklist.BlackListedItem), args.Error(1)
+}
+
+func (e *mockExecuter) getList(startDate time.Time, endDate time.Time, offset int, limit int) ([]string, error) {
+	args := e.Called(startDate, endDate, offset, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (e *mockExecuter) insert(records []db.Record) (int, error) {
+	args := e.Called(records)
+	return args.Int(0), args.Error(1)
+}
+
+func (e *mockExecuter) ping() error {
+	args := e.Called()
+	return args.Error(0)
+}
+
+func (e *mockExecuter) checkHealth(consistency gocql.Consistency) error {
+	args := e.Called(consistency)
+	return args.Error(0)
+}
+
+func (e *mockExecuter) close() error {
+	args := e.Called()
+	return args.Error(0)
+}
+
+func (e *mockExecuter) addBlacklist(item blacklist.BlackListedItem) error {
+	args := e.Called(item)
+	return args.Error(0)
+}
+
+func (e *mockExecuter) removeBlacklist(id string) error {
+	args := e.Called(id)
+	return args.Error(0)
+}
+
+type mockListEditor struct {
+	mock.Mock
+}
+
+func (l *mockListEditor) addBlacklist(item blacklist.BlackListedItem) error {
+	args := l.Called(item)
+	return args.Error(0)
+}
+
+func (l *mockListEditor) removeBlacklist(id string) error {
+	args := l.Called(id)
+	return args.Error(0)
+}
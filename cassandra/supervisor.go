@@ -0,0 +1,333 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandra
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff/v3"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/yugabyte/gocql"
+)
+
+const (
+	// ReasonLabel is for labeling session reconnects with why they happened.
+	ReasonLabel = "reason"
+
+	// DeadReason is when the session was closed or kept failing health checks.
+	DeadReason = "dead"
+	// CredentialsReason is when the password or TLS files changed.
+	CredentialsReason = "credentials"
+
+	defaultSessionCheckInterval = 10 * time.Second
+	defaultSessionMaxFailures   = 3
+	defaultSessionMinBackoff    = time.Second
+	defaultSessionMaxBackoff    = time.Minute
+)
+
+var (
+	errSessionClosed = errors.New("session has been closed")
+)
+
+// SessionRecoveryConfig configures the supervisor that rebuilds the session
+// when it dies or when the credential files it was created with change.
+type SessionRecoveryConfig struct {
+	// Enabled turns on the supervisor.
+	Enabled bool
+
+	// CheckInterval is how often the session and credential files are checked.
+	CheckInterval time.Duration
+
+	// MaxFailures is the number of health checks in a row that must fail
+	// before the session is considered dead.
+	MaxFailures int
+
+	// MinBackoff is how long to wait after the first failed attempt to
+	// rebuild the session.  The wait doubles after each failure.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the longest to wait between attempts to rebuild the session.
+	MaxBackoff time.Duration
+}
+
+func validateSessionRecoveryConfig(config *SessionRecoveryConfig) {
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = defaultSessionCheckInterval
+	}
+	if config.MaxFailures <= 0 {
+		config.MaxFailures = defaultSessionMaxFailures
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultSessionMinBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = defaultSessionMaxBackoff
+		if config.MaxBackoff < config.MinBackoff {
+			config.MaxBackoff = config.MinBackoff
+		}
+	}
+}
+
+type executer interface {
+	finder
+	findList
+	listEditor
+	deviceFinder
+	multiInserter
+	pinger
+	closer
+	healthQuerier
+}
+
+// session is one generation of executer.  Calls hold the read lock while
+// they run, so a session being retired waits for them before it is closed.
+type session struct {
+	executer
+	lock    sync.RWMutex
+	retired bool
+}
+
+// sessionSwapper sends each call to the current session, allowing the
+// session to be replaced without disturbing calls already in flight.
+type sessionSwapper struct {
+	current atomic.Pointer[session]
+
+	swapLock sync.Mutex
+	closed   bool
+}
+
+func newSessionSwapper(e executer) *sessionSwapper {
+	s := &sessionSwapper{}
+	s.current.Store(&session{executer: e})
+	return s
+}
+
+// acquire returns the current session, read locked.  A session retired
+// between loading it and locking it is skipped for its replacement.
+func (s *sessionSwapper) acquire() *session {
+	for {
+		sess := s.current.Load()
+		sess.lock.RLock()
+		if !sess.retired {
+			return sess
+		}
+		sess.lock.RUnlock()
+	}
+}
+
+// swap makes e the current session, then closes the old session once the
+// calls using it are done.  After the swapper is closed, e is closed instead.
+func (s *sessionSwapper) swap(e executer) error {
+	s.swapLock.Lock()
+	defer s.swapLock.Unlock()
+	if s.closed {
+		_ = e.close()
+		return errSessionClosed
+	}
+	old := s.current.Swap(&session{executer: e})
+	old.lock.Lock()
+	old.retired = true
+	old.lock.Unlock()
+	return old.close()
+}
+
+func (s *sessionSwapper) findRecords(limit int, filter string, where ...interface{}) ([]db.Record, error) {
+	sess := s.acquire()
+	defer sess.lock.RUnlock()
+	return sess.findRecords(limit, filter, where...)
+}
+
+func (s *sessionSwapper) findBlacklist() ([]blacklist.BlackListedItem, error) {
+	sess := s.acquire()
+	defer sess.lock.RUnlock()
+	return sess.findBlacklist()
+}
+
+func (s *sessionSwapper) addBlacklist(item blacklist.BlackListedItem) error {
+	sess := s.acquire()
+	defer sess.lock.RUnlock()
+	return sess.addBlacklist(item)
+}
+
+func (s *sessionSwapper) removeBlacklist(id string) error {
+	sess := s.acquire()
+	defer sess.lock.RUnlock()
+	return sess.removeBlacklist(id)
+}
+
+func (s *sessionSwapper) getList(startDate time.Time, endDate time.Time, offset int, limit int) ([]string, error) {
+	sess := s.acquire()
+	defer sess.lock.RUnlock()
+	return sess.getList(startDate, endDate, offset, limit)
+}
+
+func (s *sessionSwapper) insert(records []db.Record) (int, error) {
+	sess := s.acquire()
+	defer sess.lock.RUnlock()
+	return sess.insert(records)
+}
+
+func (s *sessionSwapper) ping() error {
+	sess := s.acquire()
+	defer sess.lock.RUnlock()
+	return sess.ping()
+}
+
+func (s *sessionSwapper) checkHealth(consistency gocql.Consistency) error {
+	sess := s.acquire()
+	defer sess.lock.RUnlock()
+	return sess.checkHealth(consistency)
+}
+
+func (s *sessionSwapper) close() error {
+	s.swapLock.Lock()
+	defer s.swapLock.Unlock()
+	s.closed = true
+	return s.current.Load().close()
+}
+
+// sessionSupervisor watches the session and the credential files, rebuilding
+// the session when it dies or the credentials change.  The old session keeps
+// serving calls until a new one has connected.
+type sessionSupervisor struct {
+	config      SessionRecoveryConfig
+	consistency gocql.Consistency
+	swapper     *sessionSwapper
+	connect     func() (executer, error)
+	fingerprint func() (string, error)
+	measures    Measures
+
+	failures        int
+	lastFingerprint string
+}
+
+func (c *Connection) setupSessionRecovery(config Config, consistency gocql.Consistency, e executer) {
+	swapper := newSessionSwapper(e)
+	c.setExecuter(swapper)
+
+	supervisor := &sessionSupervisor{
+		config:      config.SessionRecovery,
+		consistency: consistency,
+		swapper:     swapper,
+		connect: func() (executer, error) {
+			conn, err := c.connect(config)
+			if err != nil {
+				return nil, err
+			}
+			return conn, nil
+		},
+		fingerprint: func() (string, error) {
+			return credentialFingerprint(config)
+		},
+		measures: c.measures,
+	}
+	// the session was just created from the files as they are now.
+	supervisor.lastFingerprint, _ = supervisor.fingerprint()
+
+	stop := make(chan struct{}, 1)
+	go supervisor.run(stop)
+	c.stopThreads = append(c.stopThreads, stop)
+}
+
+func (s *sessionSupervisor) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !s.check(stop) {
+				return
+			}
+		}
+	}
+}
+
+// check rebuilds the session if needed, returning false if it was told to
+// stop while doing so.
+func (s *sessionSupervisor) check(stop <-chan struct{}) bool {
+	reason := ""
+	if err := s.swapper.ping(); err != nil {
+		reason = DeadReason
+	} else if err := s.swapper.checkHealth(s.consistency); err != nil {
+		s.failures++
+		if s.failures >= s.config.MaxFailures {
+			reason = DeadReason
+		}
+	} else {
+		s.failures = 0
+	}
+
+	// files that can't be read may be in the middle of being rotated, so
+	// wait for the next check to compare them, keeping the last fingerprint
+	// read.
+	fingerprint, err := s.fingerprint()
+	if err != nil {
+		fingerprint = s.lastFingerprint
+	} else if reason == "" && fingerprint != s.lastFingerprint {
+		reason = CredentialsReason
+	}
+	if reason == "" {
+		return true
+	}
+	return s.reconnect(reason, fingerprint, stop)
+}
+
+// reconnect creates a new session, backing off between failed attempts, and
+// swaps it in.  It returns false if it was told to stop first.
+func (s *sessionSupervisor) reconnect(reason string, fingerprint string, stop <-chan struct{}) bool {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = s.config.MinBackoff
+	b.MaxInterval = s.config.MaxBackoff
+	b.MaxElapsedTime = 0
+	b.Reset()
+
+	for {
+		e, err := s.connect()
+		if err == nil {
+			if err = s.swapper.swap(e); errors.Is(err, errSessionClosed) {
+				return false
+			}
+			s.failures = 0
+			s.lastFingerprint = fingerprint
+			s.measures.SessionReconnects.With(ReasonLabel, reason).Add(1.0)
+			return true
+		}
+		s.measures.SessionReconnectFailures.Add(1.0)
+
+		timer := time.NewTimer(b.NextBackOff())
+		select {
+		case <-stop:
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// credentialFingerprint hashes the contents of the password and TLS files,
+// so that a change to any of them can be noticed.
+func credentialFingerprint(config Config) (string, error) {
+	h := sha256.New()
+	for _, path := range []string{config.PasswordFile, config.SSLRootCert, config.SSLCert, config.SSLKey} {
+		if path == "" {
+			continue
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(contents)
+		h.Write(sum[:])
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandra
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+	"github.com/yugabyte/gocql"
+)
+
+func TestSessionSwapper(t *testing.T) {
+	assert := assert.New(t)
+	oldSession := new(mockExecuter)
+	newSession := new(mockExecuter)
+	lateSession := new(mockExecuter)
+	oldSession.On("ping").Return(nil).Once()
+	oldSession.On("close").Return(nil).Once()
+	newSession.On("ping").Return(errors.New("test ping error")).Once()
+	newSession.On("close").Return(nil).Once()
+	lateSession.On("close").Return(nil).Once()
+
+	swapper := newSessionSwapper(oldSession)
+	assert.NoError(swapper.ping())
+	assert.NoError(swapper.swap(newSession))
+	assert.Error(swapper.ping())
+	assert.NoError(swapper.close())
+	assert.Equal(errSessionClosed, swapper.swap(lateSession))
+
+	oldSession.AssertExpectations(t)
+	newSession.AssertExpectations(t)
+	lateSession.AssertExpectations(t)
+}
+
+func TestSessionSupervisorCheck(t *testing.T) {
+	healthErr := errors.New("test health error")
+	tests := []struct {
+		description      string
+		pingErr          error
+		healthErr        error
+		previousFailures int
+		fingerprint      string
+		fingerprintErr   error
+		connectErrs      int
+		expectedReason   string
+		expectedFailures int
+		expectedConnErrs float64
+	}{
+		{
+			description:      "Healthy",
+			fingerprint:      "a",
+			expectedFailures: 0,
+		},
+		{
+			description:      "Health Check Failure",
+			healthErr:        healthErr,
+			previousFailures: 1,
+			fingerprint:      "a",
+			expectedFailures: 2,
+		},
+		{
+			description:      "Too Many Health Check Failures",
+			healthErr:        healthErr,
+			previousFailures: 2,
+			fingerprint:      "a",
+			expectedReason:   DeadReason,
+		},
+		{
+			description:    "Session Closed",
+			pingErr:        errSessionClosed,
+			fingerprint:    "a",
+			expectedReason: DeadReason,
+		},
+		{
+			description:    "Credentials Changed",
+			fingerprint:    "b",
+			expectedReason: CredentialsReason,
+		},
+		{
+			description:    "Unreadable Credentials",
+			fingerprintErr: errors.New("test read error"),
+		},
+		{
+			description:    "Unreadable Credentials While Dead",
+			pingErr:        errSessionClosed,
+			fingerprintErr: errors.New("test read error"),
+			expectedReason: DeadReason,
+		},
+		{
+			description:      "Reconnect Failures",
+			pingErr:          errSessionClosed,
+			fingerprint:      "a",
+			connectErrs:      2,
+			expectedReason:   DeadReason,
+			expectedConnErrs: 2.0,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			p := xmetricstest.NewProvider(nil, Metrics)
+			oldSession := new(mockExecuter)
+			newSession := new(mockExecuter)
+			oldSession.On("ping").Return(tc.pingErr).Once()
+			if tc.pingErr == nil {
+				oldSession.On("checkHealth", gocql.One).Return(tc.healthErr).Once()
+			}
+			if tc.expectedReason != "" {
+				oldSession.On("close").Return(nil).Once()
+			}
+
+			connects := 0
+			supervisor := &sessionSupervisor{
+				config: SessionRecoveryConfig{
+					MaxFailures: 3,
+					MinBackoff:  time.Millisecond,
+					MaxBackoff:  time.Millisecond,
+				},
+				consistency: gocql.One,
+				swapper:     newSessionSwapper(oldSession),
+				connect: func() (executer, error) {
+					connects++
+					if connects <= tc.connectErrs {
+						return nil, errors.New("test connect error")
+					}
+					return newSession, nil
+				},
+				fingerprint: func() (string, error) {
+					return tc.fingerprint, tc.fingerprintErr
+				},
+				measures:        NewMeasures(p),
+				failures:        tc.previousFailures,
+				lastFingerprint: "a",
+			}
+
+			assert.True(supervisor.check(make(chan struct{})))
+			oldSession.AssertExpectations(t)
+			assert.Equal(tc.expectedFailures, supervisor.failures)
+			if tc.fingerprintErr != nil {
+				assert.Equal("a", supervisor.lastFingerprint)
+			} else {
+				assert.Equal(tc.fingerprint, supervisor.lastFingerprint)
+			}
+			p.Assert(t, SessionReconnectFailureCounter)(xmetricstest.Value(tc.expectedConnErrs))
+			if tc.expectedReason != "" {
+				p.Assert(t, SessionReconnectCounter, ReasonLabel, tc.expectedReason)(xmetricstest.Value(1.0))
+				assert.Equal(newSession, supervisor.swapper.current.Load().executer)
+			} else {
+				assert.Equal(oldSession, supervisor.swapper.current.Load().executer)
+			}
+		})
+	}
+}
+
+func TestSessionSupervisorStop(t *testing.T) {
+	assert := assert.New(t)
+	oldSession := new(mockExecuter)
+	oldSession.On("ping").Return(errSessionClosed).Once()
+	supervisor := &sessionSupervisor{
+		config: SessionRecoveryConfig{
+			MaxFailures: 3,
+			MinBackoff:  time.Hour,
+			MaxBackoff:  time.Hour,
+		},
+		swapper: newSessionSwapper(oldSession),
+		connect: func() (executer, error) {
+			return nil, errors.New("test connect error")
+		},
+		fingerprint: func() (string, error) {
+			return "", nil
+		},
+		measures: NewMeasures(xmetricstest.NewProvider(nil, Metrics)),
+	}
+	stop := make(chan struct{}, 1)
+	stop <- struct{}{}
+	assert.False(supervisor.check(stop))
+	oldSession.AssertExpectations(t)
+}
+
+func TestCredentialFingerprint(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(os.WriteFile(passwordFile, []byte("first\n"), 0600))
+	config := Config{PasswordFile: passwordFile}
+
+	first, err := credentialFingerprint(config)
+	require.NoError(err)
+	password, err := readPassword(config)
+	require.NoError(err)
+	assert.Equal("first", password)
+
+	require.NoError(os.WriteFile(passwordFile, []byte("second\n"), 0600))
+	second, err := credentialFingerprint(config)
+	require.NoError(err)
+	assert.NotEqual(first, second)
+
+	config.SSLCert = filepath.Join(dir, "missing.pem")
+	_, err = credentialFingerprint(config)
+	assert.Error(err)
+}
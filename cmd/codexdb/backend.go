@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/goph/emperror"
+	"github.com/spf13/viper"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/xmidt-org/codex-db/cassandra"
+	"github.com/xmidt-org/codex-db/export"
+	"github.com/xmidt-org/codex-db/postgresql"
+)
+
+const (
+	cassandraBackend = "cassandra"
+	postgresBackend  = "postgres"
+)
+
+var (
+	errUnknownBackend   = errors.New("backend must be cassandra or postgres")
+	errPruneUnsupported = errors.New("cassandra expires records with a time to live, so there is nothing to prune")
+)
+
+// fileConfig is the configuration file, holding the driver's own Config.
+type fileConfig struct {
+	// Backend is the database to use: cassandra or postgres.
+	Backend   string
+	Cassandra cassandra.Config
+	Postgres  postgresql.Config
+}
+
+// connectOptions change how the driver connects for a command.
+type connectOptions struct {
+	autoMigrate bool
+	baseline    int
+	schemaCheck string
+}
+
+// deviceOptions page through the device list.  Cassandra pages by offset
+// within a window of birth dates, postgres by the last device id seen.
+type deviceOptions struct {
+	offset string
+	limit  int
+	start  time.Time
+	end    time.Time
+}
+
+// backend is what the commands need from a driver.
+type backend interface {
+	db.RecordGetter
+	db.Inserter
+	blacklist.Updater
+	blacklist.Editor
+	Ping() error
+	Close() error
+	listDevices(opts deviceOptions) ([]string, error)
+	exportDevices(start time.Time, end time.Time) export.DeviceLister
+	pruner() (db.Pruner, error)
+}
+
+type connectFunc func(config fileConfig, opts connectOptions) (backend, error)
+
+func loadConfig(path string) (fileConfig, error) {
+	var config fileConfig
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return config, emperror.WrapWith(err, "Reading config failed", "file", path)
+	}
+	// unknown keys are errors, so that a typo isn't silently ignored.
+	if err := v.UnmarshalExact(&config); err != nil {
+		return config, emperror.WrapWith(err, "Parsing config failed", "file", path)
+	}
+	return config, nil
+}
+
+// connect creates a connection with the driver the config names.
+func connect(config fileConfig, opts connectOptions) (backend, error) {
+	metrics := provider.NewDiscardProvider()
+	switch config.Backend {
+	case cassandraBackend:
+		config.Cassandra.AutoMigrate = opts.autoMigrate
+		config.Cassandra.Baseline = opts.baseline
+		config.Cassandra.SchemaCheck = opts.schemaCheck
+		conn, err := cassandra.CreateDbConnection(config.Cassandra, metrics, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &cassandraConn{Connection: conn}, nil
+	case postgresBackend:
+		config.Postgres.AutoMigrate = opts.autoMigrate
+		config.Postgres.Baseline = opts.baseline
+		config.Postgres.SchemaCheck = opts.schemaCheck
+		conn, err := postgresql.CreateDbConnection(config.Postgres, metrics, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &postgresConn{Connection: conn}, nil
+	}
+	return nil, emperror.With(errUnknownBackend, "backend", config.Backend)
+}
+
+type cassandraConn struct {
+	*cassandra.Connection
+}
+
+func (c *cassandraConn) listDevices(opts deviceOptions) ([]string, error) {
+	offset := 0
+	if opts.offset != "" {
+		var err error
+		if offset, err = strconv.Atoi(opts.offset); err != nil {
+			return nil, emperror.WrapWith(err, "Cassandra offsets must be numbers", "offset", opts.offset)
+		}
+	}
+	return c.GetDeviceList(opts.start, opts.end, offset, opts.limit)
+}
+
+func (c *cassandraConn) exportDevices(start time.Time, end time.Time) export.DeviceLister {
+	return export.CassandraDevices(c.Connection, start, end)
+}
+
+func (c *cassandraConn) pruner() (db.Pruner, error) {
+	return nil, errPruneUnsupported
+}
+
+type postgresConn struct {
+	*postgresql.Connection
+}
+
+func (c *postgresConn) listDevices(opts deviceOptions) ([]string, error) {
+	return c.GetDeviceList(opts.offset, opts.limit)
+}
+
+// exportDevices lists every device; postgres can't list by birth date, so
+// the export filters the records instead.
+func (c *postgresConn) exportDevices(time.Time, time.Time) export.DeviceLister {
+	return export.PostgresDevices(c.Connection)
+}
+
+func (c *postgresConn) pruner() (db.Pruner, error) {
+	return c.Connection, nil
+}
@@ -0,0 +1,288 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/spf13/pflag"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/xmidt-org/codex-db/migrate"
+)
+
+var (
+	errUsage         = errors.New("invalid usage")
+	errUnknownType   = errors.New("record type must be Default or State")
+	errDriftFound    = errors.New("schema drift found")
+	errPartialDelete = errors.New("deleting expired records failed")
+)
+
+// env is what every command runs with.
+type env struct {
+	ctx     context.Context
+	config  fileConfig
+	connect connectFunc
+	out     *printer
+}
+
+type command struct {
+	name  string
+	usage string
+	run   func(e *env, args []string) error
+}
+
+var commands = []command{
+	{"records get", "records get <device> [--type Default|State] [--limit n] [--state-hash hash]", recordsGet},
+	{"devices list", "devices list [--offset offset] [--limit n] [--start time] [--end time]", devicesList},
+	{"blacklist list", "blacklist list", blacklistList},
+	{"blacklist add", "blacklist add <device> [reason...]", blacklistAdd},
+	{"blacklist remove", "blacklist remove <device>", blacklistRemove},
+	{"export", "export <file> [--device id...] [--start time] [--end time] [--gzip] [--checkpoint file] [--max-records n]", exportRecords},
+	{"import", "import <file> [--batch-size n] [--rate n] [--checkpoint file]", importRecords},
+	{"copy", "copy --to file [--device id...] [--workers n] [--batch-size n] [--max-records n] [--checkpoint file]", copyRecords},
+	{"verify", "verify --to file [--device id...] [--workers n] [--max-records n]", verifyRecords},
+	{"prune", "prune [--dry-run] [--shard n] [--limit n]", prune},
+	{"ping", "ping", ping},
+	{"schema migrate", "schema migrate", schemaMigrate},
+	{"schema baseline", "schema baseline <version>", schemaBaseline},
+	{"schema check", "schema check", schemaCheck},
+}
+
+// findCommand matches the command name at the start of args,
+// returning the command and the arguments left for it.
+func findCommand(args []string) (command, []string, bool) {
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) < len(words) {
+			continue
+		}
+		if strings.Join(args[:len(words)], " ") == c.name {
+			return c, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+func newFlagSet(name string) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return flags
+}
+
+// withBackend connects, runs f, and closes the connection.
+func (e *env) withBackend(opts connectOptions, f func(b backend) error) error {
+	b, err := e.connect(e.config, opts)
+	if err != nil {
+		return emperror.Wrap(err, "Connecting to the database failed")
+	}
+	defer b.Close()
+	return f(b)
+}
+
+func recordsGet(e *env, args []string) error {
+	flags := newFlagSet("records get")
+	eventType := flags.String("type", "", "only get records of this type")
+	limit := flags.Int("limit", 10, "the most records to get")
+	stateHash := flags.String("state-hash", "", "only get records newer than this state hash")
+	if err := flags.Parse(args); err != nil {
+		return emperror.Wrap(errUsage, err.Error())
+	}
+	if flags.NArg() != 1 {
+		return emperror.Wrap(errUsage, "records get takes one device id")
+	}
+	deviceID := flags.Arg(0)
+
+	var recordType db.EventType
+	if *eventType != "" {
+		recordType = db.ParseEventType(*eventType)
+		if recordType.String() != *eventType {
+			return emperror.With(errUnknownType, "type", *eventType)
+		}
+	}
+
+	return e.withBackend(connectOptions{}, func(b backend) error {
+		var (
+			records []db.Record
+			err     error
+		)
+		if *eventType != "" {
+			records, err = b.GetRecordsOfType(deviceID, *limit, recordType, *stateHash)
+		} else {
+			records, err = b.GetRecords(deviceID, *limit, *stateHash)
+		}
+		if err != nil {
+			return err
+		}
+		return e.out.records(records)
+	})
+}
+
+func devicesList(e *env, args []string) error {
+	flags := newFlagSet("devices list")
+	offset := flags.String("offset", "", "where to start: a number for cassandra, the last device id seen for postgres")
+	limit := flags.Int("limit", 100, "the most devices to list")
+	start := flags.String("start", "", "cassandra only: the earliest birth date, in RFC 3339")
+	end := flags.String("end", "", "cassandra only: the latest birth date, in RFC 3339")
+	if err := flags.Parse(args); err != nil {
+		return emperror.Wrap(errUsage, err.Error())
+	}
+	if flags.NArg() != 0 {
+		return emperror.Wrap(errUsage, "devices list takes no arguments")
+	}
+
+	opts := deviceOptions{
+		offset: *offset,
+		limit:  *limit,
+		start:  time.Unix(0, 0),
+		end:    time.Now(),
+	}
+	if err := parseTime(*start, &opts.start); err != nil {
+		return err
+	}
+	if err := parseTime(*end, &opts.end); err != nil {
+		return err
+	}
+
+	return e.withBackend(connectOptions{}, func(b backend) error {
+		devices, err := b.listDevices(opts)
+		if err != nil {
+			return err
+		}
+		return e.out.devices(devices)
+	})
+}
+
+func blacklistList(e *env, args []string) error {
+	if len(args) != 0 {
+		return emperror.Wrap(errUsage, "blacklist list takes no arguments")
+	}
+	return e.withBackend(connectOptions{}, func(b backend) error {
+		items, err := b.GetBlacklist()
+		if err != nil {
+			return err
+		}
+		return e.out.blacklist(items)
+	})
+}
+
+func blacklistAdd(e *env, args []string) error {
+	if len(args) == 0 {
+		return emperror.Wrap(errUsage, "blacklist add takes a device id and an optional reason")
+	}
+	item := blacklist.BlackListedItem{
+		ID:     args[0],
+		Reason: strings.Join(args[1:], " "),
+	}
+	return e.withBackend(connectOptions{}, func(b backend) error {
+		if err := b.AddBlacklist(item); err != nil {
+			return err
+		}
+		return e.out.status(status{Status: "ok"})
+	})
+}
+
+func blacklistRemove(e *env, args []string) error {
+	if len(args) != 1 {
+		return emperror.Wrap(errUsage, "blacklist remove takes one device id")
+	}
+	return e.withBackend(connectOptions{}, func(b backend) error {
+		if err := b.RemoveBlacklist(args[0]); err != nil {
+			return err
+		}
+		return e.out.status(status{Status: "ok"})
+	})
+}
+
+func prune(e *env, args []string) error {
+	flags := newFlagSet("prune")
+	dryRun := flags.Bool("dry-run", false, "list the expired records without deleting them")
+	shard := flags.Int("shard", 0, "the shard to prune")
+	limit := flags.Int("limit", 100, "the most records to prune")
+	if err := flags.Parse(args); err != nil {
+		return emperror.Wrap(errUsage, err.Error())
+	}
+	if flags.NArg() != 0 {
+		return emperror.Wrap(errUsage, "prune takes no arguments")
+	}
+
+	return e.withBackend(connectOptions{}, func(b backend) error {
+		pruner, err := b.pruner()
+		if err != nil {
+			return err
+		}
+		expired, err := pruner.GetRecordsToDelete(*shard, *limit, time.Now().UnixNano())
+		if err != nil {
+			return err
+		}
+		result := pruneResult{DryRun: *dryRun, Expired: expired}
+		if !*dryRun {
+			for _, r := range expired {
+				if err = pruner.DeleteRecord(*shard, r.DeathDate, r.RecordID); err != nil {
+					_ = e.out.prune(result)
+					return emperror.WrapWith(errPartialDelete, err.Error(), "record id", r.RecordID)
+				}
+				result.Deleted++
+			}
+		}
+		return e.out.prune(result)
+	})
+}
+
+func ping(e *env, args []string) error {
+	if len(args) != 0 {
+		return emperror.Wrap(errUsage, "ping takes no arguments")
+	}
+	return e.withBackend(connectOptions{}, func(b backend) error {
+		if err := b.Ping(); err != nil {
+			return err
+		}
+		return e.out.status(status{Status: "ok"})
+	})
+}
+
+func schemaMigrate(e *env, args []string) error {
+	if len(args) != 0 {
+		return emperror.Wrap(errUsage, "schema migrate takes no arguments")
+	}
+	return e.withBackend(connectOptions{autoMigrate: true}, func(backend) error {
+		return e.out.status(status{Status: "ok", Detail: "schema is at the latest version"})
+	})
+}
+
+func schemaBaseline(e *env, args []string) error {
+	if len(args) != 1 {
+		return emperror.Wrap(errUsage, "schema baseline takes one version")
+	}
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < 1 {
+		return emperror.WrapWith(errUsage, "schema baseline takes a version of at least 1", "version", args[0])
+	}
+	return e.withBackend(connectOptions{baseline: version}, func(backend) error {
+		return e.out.status(status{Status: "ok", Detail: fmt.Sprintf("schema is baselined at version %d", version)})
+	})
+}
+
+func schemaCheck(e *env, args []string) error {
+	if len(args) != 0 {
+		return emperror.Wrap(errUsage, "schema check takes no arguments")
+	}
+	b, err := e.connect(e.config, connectOptions{schemaCheck: migrate.SchemaCheckError})
+	if drift, ok := migrate.AsSchemaDrift(err); ok {
+		_ = e.out.status(status{Status: "failed", Detail: drift})
+		return errDriftFound
+	}
+	if err != nil {
+		return emperror.Wrap(err, "Connecting to the database failed")
+	}
+	defer b.Close()
+	return e.out.status(status{Status: "ok", Detail: "schema matches"})
+}
@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// codexdb is a command line tool for looking at and maintaining a codex
+// database, using the same drivers and configuration as the services.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/goph/emperror"
+)
+
+const (
+	applicationName = "codexdb"
+	defaultConfig   = "codexdb.yaml"
+)
+
+func main() {
+	// an interrupted export or import saves a checkpoint before exiting.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr, connect)
+	stop()
+	os.Exit(code)
+}
+
+// run runs the command in args, returning the exit code: 0 on success, 1 if
+// the command failed, and 2 if it was used incorrectly.
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer, connect connectFunc) int {
+	flags := newFlagSet(applicationName)
+	flags.SetInterspersed(false)
+	configFile := flags.StringP("config", "c", defaultConfig, "the configuration file")
+	output := flags.StringP("output", "o", tableOutput, "the output format: json or table")
+	if err := flags.Parse(args); err != nil {
+		fmt.Fprintln(stderr, err)
+		usage(stderr)
+		return 2
+	}
+
+	cmd, cmdArgs, ok := findCommand(flags.Args())
+	if !ok {
+		usage(stderr)
+		return 2
+	}
+	out, err := newPrinter(stdout, *output)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	e := &env{
+		ctx:     ctx,
+		config:  config,
+		connect: connect,
+		out:     out,
+	}
+	if err = cmd.run(e, cmdArgs); err != nil {
+		fmt.Fprintln(stderr, err)
+		if isUsage(err) {
+			fmt.Fprintf(stderr, "usage: %s [--config file] [--output json|table] %s\n", applicationName, cmd.usage)
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s [--config file] [--output json|table] <command>\n\ncommands:\n", applicationName)
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %s\n", c.usage)
+	}
+}
+
+// isUsage returns whether err was caused by using a command incorrectly.
+func isUsage(err error) bool {
+	usage := false
+	emperror.ForEachCause(err, func(err error) bool {
+		usage = err == errUsage
+		return !usage
+	})
+	return usage
+}
@@ -0,0 +1,396 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/xmidt-org/codex-db/export"
+	"github.com/xmidt-org/codex-db/migrate"
+)
+
+type fakeBackend struct {
+	records   []db.Record
+	devices   []string
+	blacklist []blacklist.BlackListedItem
+	expired   []db.RecordToDelete
+	err       error
+	noPrune   bool
+
+	added      []blacklist.BlackListedItem
+	removed    []string
+	deleted    []int64
+	inserted   []db.Record
+	gotType    *db.EventType
+	gotDevices deviceOptions
+	closed     bool
+}
+
+func (f *fakeBackend) GetRecords(deviceID string, limit int, stateHash string) ([]db.Record, error) {
+	return f.records, f.err
+}
+
+func (f *fakeBackend) GetRecordsOfType(deviceID string, limit int, eventType db.EventType, stateHash string) ([]db.Record, error) {
+	f.gotType = &eventType
+	return f.records, f.err
+}
+
+func (f *fakeBackend) GetStateHash(records []db.Record) (string, error) {
+	return "", f.err
+}
+
+func (f *fakeBackend) GetBlacklist() ([]blacklist.BlackListedItem, error) {
+	return f.blacklist, f.err
+}
+
+func (f *fakeBackend) AddBlacklist(item blacklist.BlackListedItem) error {
+	f.added = append(f.added, item)
+	return f.err
+}
+
+func (f *fakeBackend) RemoveBlacklist(id string) error {
+	f.removed = append(f.removed, id)
+	return f.err
+}
+
+func (f *fakeBackend) Ping() error {
+	return f.err
+}
+
+func (f *fakeBackend) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeBackend) listDevices(opts deviceOptions) ([]string, error) {
+	f.gotDevices = opts
+	return f.devices, f.err
+}
+
+func (f *fakeBackend) InsertRecords(records ...db.Record) error {
+	f.inserted = append(f.inserted, records...)
+	return f.err
+}
+
+func (f *fakeBackend) exportDevices(time.Time, time.Time) export.DeviceLister {
+	return export.Devices(f.devices)
+}
+
+func (f *fakeBackend) pruner() (db.Pruner, error) {
+	if f.noPrune {
+		return nil, errPruneUnsupported
+	}
+	return f, nil
+}
+
+func (f *fakeBackend) GetRecordsToDelete(shard int, limit int, deathDate int64) ([]db.RecordToDelete, error) {
+	return f.expired, f.err
+}
+
+func (f *fakeBackend) DeleteRecord(shard int, deathdate int64, recordID int64) error {
+	f.deleted = append(f.deleted, recordID)
+	return f.err
+}
+
+func writeConfig(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "codexdb.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func TestRun(t *testing.T) {
+	config := writeConfig(t, "backend: postgres\npostgres:\n  server: localhost\n")
+	tests := []struct {
+		description    string
+		args           []string
+		backend        *fakeBackend
+		connectErr     error
+		expectedCode   int
+		expectedOutput string
+		check          func(*assert.Assertions, *fakeBackend)
+	}{
+		{
+			description: "Records Table",
+			args:        []string{"records", "get", "device-1"},
+			backend: &fakeBackend{records: []db.Record{
+				{DeviceID: "device-1", Type: db.State, BirthDate: 0, DeathDate: 60000000000, RowID: "abc", Data: []byte("data")},
+			}},
+			expectedOutput: "DEVICE ID  TYPE   BIRTH DATE            DEATH DATE            ROW ID  DATA BYTES\n" +
+				"device-1   State  1970-01-01T00:00:00Z  1970-01-01T00:01:00Z  abc     4\n",
+		},
+		{
+			description: "Records Of Type",
+			args:        []string{"-o", "json", "records", "get", "--type", "State", "device-1"},
+			backend:     &fakeBackend{},
+			check: func(assert *assert.Assertions, b *fakeBackend) {
+				if assert.NotNil(b.gotType) {
+					assert.Equal(db.State, *b.gotType)
+				}
+			},
+			expectedOutput: "[]\n",
+		},
+		{
+			description:  "Records Unknown Type",
+			args:         []string{"records", "get", "--type", "Other", "device-1"},
+			backend:      &fakeBackend{},
+			expectedCode: 1,
+		},
+		{
+			description:  "Records Missing Device",
+			args:         []string{"records", "get"},
+			backend:      &fakeBackend{},
+			expectedCode: 2,
+		},
+		{
+			description:    "Devices JSON",
+			args:           []string{"--output", "json", "devices", "list", "--offset", "device-1", "--limit", "2"},
+			backend:        &fakeBackend{devices: []string{"device-2", "device-3"}},
+			expectedOutput: "[\n  \"device-2\",\n  \"device-3\"\n]\n",
+			check: func(assert *assert.Assertions, b *fakeBackend) {
+				assert.Equal("device-1", b.gotDevices.offset)
+				assert.Equal(2, b.gotDevices.limit)
+			},
+		},
+		{
+			description:    "Blacklist List",
+			args:           []string{"blacklist", "list"},
+			backend:        &fakeBackend{blacklist: []blacklist.BlackListedItem{{ID: "device-1", Reason: "noisy"}}},
+			expectedOutput: "ID        REASON\ndevice-1  noisy\n",
+		},
+		{
+			description:    "Blacklist Add",
+			args:           []string{"blacklist", "add", "device-1", "too", "noisy"},
+			backend:        &fakeBackend{},
+			expectedOutput: "ok\n",
+			check: func(assert *assert.Assertions, b *fakeBackend) {
+				assert.Equal([]blacklist.BlackListedItem{{ID: "device-1", Reason: "too noisy"}}, b.added)
+			},
+		},
+		{
+			description:    "Blacklist Remove",
+			args:           []string{"blacklist", "remove", "device-1"},
+			backend:        &fakeBackend{},
+			expectedOutput: "ok\n",
+			check: func(assert *assert.Assertions, b *fakeBackend) {
+				assert.Equal([]string{"device-1"}, b.removed)
+			},
+		},
+		{
+			description: "Prune Dry Run",
+			args:        []string{"prune", "--dry-run"},
+			backend:     &fakeBackend{expired: []db.RecordToDelete{{RecordID: 7, DeathDate: 0}}},
+			expectedOutput: "RECORD ID  DEATH DATE\n7          1970-01-01T00:00:00Z\n" +
+				"1 expired records found, none deleted (dry run)\n",
+			check: func(assert *assert.Assertions, b *fakeBackend) {
+				assert.Empty(b.deleted)
+			},
+		},
+		{
+			description:    "Prune",
+			args:           []string{"-o", "json", "prune"},
+			backend:        &fakeBackend{expired: []db.RecordToDelete{{RecordID: 7}, {RecordID: 8}}},
+			expectedOutput: "{\n  \"dryRun\": false,\n  \"expired\": [\n    {\n      \"deathdate\": 0,\n      \"recordid\": 7\n    },\n    {\n      \"deathdate\": 0,\n      \"recordid\": 8\n    }\n  ],\n  \"deleted\": 2\n}\n",
+			check: func(assert *assert.Assertions, b *fakeBackend) {
+				assert.Equal([]int64{7, 8}, b.deleted)
+			},
+		},
+		{
+			description:  "Prune Unsupported",
+			args:         []string{"prune"},
+			backend:      &fakeBackend{noPrune: true},
+			expectedCode: 1,
+		},
+		{
+			description:    "Ping",
+			args:           []string{"ping"},
+			backend:        &fakeBackend{},
+			expectedOutput: "ok\n",
+		},
+		{
+			description:  "Ping Error",
+			args:         []string{"ping"},
+			backend:      &fakeBackend{err: errors.New("test error")},
+			expectedCode: 1,
+		},
+		{
+			description:  "Connect Error",
+			args:         []string{"ping"},
+			connectErr:   errors.New("test error"),
+			expectedCode: 1,
+		},
+		{
+			description:    "Schema Check Drift",
+			args:           []string{"schema", "check"},
+			connectErr:     &migrate.SchemaDrift{MissingTables: []string{"blacklist"}},
+			expectedCode:   1,
+			expectedOutput: "failed: schema drift found: missing tables: blacklist\n",
+		},
+		{
+			description:    "Schema Migrate",
+			args:           []string{"schema", "migrate"},
+			backend:        &fakeBackend{},
+			expectedOutput: "ok: schema is at the latest version\n",
+		},
+		{
+			description:    "Schema Baseline",
+			args:           []string{"schema", "baseline", "2"},
+			backend:        &fakeBackend{},
+			expectedOutput: "ok: schema is baselined at version 2\n",
+		},
+		{
+			description:  "Schema Baseline Bad Version",
+			args:         []string{"schema", "baseline", "zero"},
+			expectedCode: 2,
+		},
+		{
+			description:  "Unknown Command",
+			args:         []string{"records", "delete"},
+			expectedCode: 2,
+		},
+		{
+			description:  "Unknown Output",
+			args:         []string{"-o", "xml", "ping"},
+			expectedCode: 2,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			var stdout, stderr bytes.Buffer
+			var gotOpts connectOptions
+			connect := func(c fileConfig, opts connectOptions) (backend, error) {
+				assert.Equal(postgresBackend, c.Backend)
+				assert.Equal("localhost", c.Postgres.Server)
+				gotOpts = opts
+				if tc.connectErr != nil {
+					return nil, tc.connectErr
+				}
+				return tc.backend, nil
+			}
+			code := run(context.Background(), append([]string{"--config", config}, tc.args...), &stdout, &stderr, connect)
+			assert.Equal(tc.expectedCode, code, stderr.String())
+			if tc.expectedCode == 0 || tc.expectedOutput != "" {
+				assert.Equal(tc.expectedOutput, stdout.String())
+			}
+			if tc.backend != nil && code == 0 {
+				assert.True(tc.backend.closed)
+			}
+			if tc.description == "Schema Migrate" {
+				assert.True(gotOpts.autoMigrate)
+			}
+			if tc.description == "Schema Baseline" {
+				assert.Equal(2, gotOpts.baseline)
+				assert.False(gotOpts.autoMigrate)
+			}
+			if tc.check != nil {
+				tc.check(assert, tc.backend)
+			}
+		})
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	config := writeConfig(t, "backend: postgres\n")
+	file := filepath.Join(dir, "export.jsonl.gz")
+	records := []db.Record{{DeviceID: "device-1", BirthDate: 1}, {DeviceID: "device-1", BirthDate: 2}}
+	source := &fakeBackend{devices: []string{"device-1"}, records: records}
+	dest := &fakeBackend{}
+	connectTo := func(b *fakeBackend) connectFunc {
+		return func(fileConfig, connectOptions) (backend, error) {
+			return b, nil
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"-c", config, "-o", "json", "export", "--gzip", file}, &stdout, &stderr, connectTo(source))
+	assert.Equal(0, code, stderr.String())
+	var exported export.ExportProgress
+	assert.NoError(json.Unmarshal(stdout.Bytes(), &exported))
+	assert.Equal(int64(1), exported.Devices)
+	assert.Equal(int64(2), exported.Records)
+
+	stdout.Reset()
+	code = run(context.Background(), []string{"-c", config, "import", "--batch-size", "1", file}, &stdout, &stderr, connectTo(dest))
+	assert.Equal(0, code, stderr.String())
+	assert.Equal("LINES  RECORDS\n2      2\n", stdout.String())
+	assert.Equal(records, dest.inserted)
+
+	code = run(context.Background(), []string{"-c", config, "import", filepath.Join(dir, "missing")}, &stdout, &stderr, connectTo(dest))
+	assert.Equal(1, code)
+}
+
+func TestCopyVerify(t *testing.T) {
+	assert := assert.New(t)
+	config := writeConfig(t, "backend: postgres\n")
+	destConfig := writeConfig(t, "backend: cassandra\n")
+	records := []db.Record{{DeviceID: "device-1", BirthDate: 1}}
+	source := &fakeBackend{devices: []string{"device-1"}, records: records}
+	dest := &fakeBackend{}
+	connect := func(c fileConfig, _ connectOptions) (backend, error) {
+		if c.Backend == cassandraBackend {
+			return dest, nil
+		}
+		return source, nil
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"-c", config, "verify", "--to", destConfig}, &stdout, &stderr, connect)
+	assert.Equal(1, code)
+	assert.Equal("DEVICE ID  SOURCE RECORDS  DEST RECORDS  MISSING  DUPLICATED  UNEXPECTED\n"+
+		"device-1   1               0             1        0           0\n0 of 1 devices match\n", stdout.String())
+
+	stdout.Reset()
+	code = run(context.Background(), []string{"-c", config, "copy", "--to", destConfig}, &stdout, &stderr, connect)
+	assert.Equal(0, code, stderr.String())
+	assert.Equal(records, dest.inserted)
+	assert.True(source.closed)
+	assert.True(dest.closed)
+
+	stdout.Reset()
+	dest.records = dest.inserted
+	code = run(context.Background(), []string{"-c", config, "-o", "json", "verify", "--to", destConfig}, &stdout, &stderr, connect)
+	assert.Equal(0, code, stderr.String())
+	var report export.VerifyReport
+	assert.NoError(json.Unmarshal(stdout.Bytes(), &report))
+	assert.Equal(export.VerifyReport{Devices: 1, Matched: 1, Mismatches: []export.Mismatch{}}, report)
+
+	code = run(context.Background(), []string{"-c", config, "copy"}, &stdout, &stderr, connect)
+	assert.Equal(2, code)
+}
+
+func TestLoadConfig(t *testing.T) {
+	assert := assert.New(t)
+	config, err := loadConfig(writeConfig(t, "backend: cassandra\ncassandra:\n  hosts:\n    - a\n    - b\n  opTimeout: 5s\n"))
+	assert.NoError(err)
+	assert.Equal(cassandraBackend, config.Backend)
+	assert.Equal([]string{"a", "b"}, config.Cassandra.Hosts)
+	assert.Equal("5s", config.Cassandra.OpTimeout.String())
+
+	_, err = loadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(err)
+
+	// the postgres port is part of the server, so a port key is a mistake.
+	_, err = loadConfig(writeConfig(t, "backend: postgres\npostgres:\n  server: localhost\n  port: 6432\n"))
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "port")
+	}
+}
+
+func TestConnectUnknownBackend(t *testing.T) {
+	_, err := connect(fileConfig{Backend: "mysql"}, connectOptions{})
+	assert.Contains(t, err.Error(), errUnknownBackend.Error())
+}
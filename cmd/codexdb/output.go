@@ -0,0 +1,205 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/xmidt-org/codex-db/export"
+)
+
+const (
+	jsonOutput  = "json"
+	tableOutput = "table"
+)
+
+var (
+	errUnknownOutput = errors.New("output must be json or table")
+)
+
+// printer writes command results as indented JSON or as a table.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	if format != jsonOutput && format != tableOutput {
+		return nil, errUnknownOutput
+	}
+	return &printer{w: w, format: format}, nil
+}
+
+func (p *printer) json(v interface{}) error {
+	encoder := json.NewEncoder(p.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func (p *printer) table(header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func (p *printer) records(records []db.Record) error {
+	if p.format == jsonOutput {
+		if records == nil {
+			records = []db.Record{}
+		}
+		return p.json(records)
+	}
+	rows := make([][]string, 0, len(records))
+	for _, r := range records {
+		rows = append(rows, []string{
+			r.DeviceID,
+			r.Type.String(),
+			formatTime(r.BirthDate),
+			formatTime(r.DeathDate),
+			r.RowID,
+			fmt.Sprint(len(r.Data)),
+		})
+	}
+	return p.table([]string{"DEVICE ID", "TYPE", "BIRTH DATE", "DEATH DATE", "ROW ID", "DATA BYTES"}, rows)
+}
+
+func (p *printer) devices(devices []string) error {
+	if p.format == jsonOutput {
+		if devices == nil {
+			devices = []string{}
+		}
+		return p.json(devices)
+	}
+	rows := make([][]string, 0, len(devices))
+	for _, d := range devices {
+		rows = append(rows, []string{d})
+	}
+	return p.table([]string{"DEVICE ID"}, rows)
+}
+
+func (p *printer) blacklist(items []blacklist.BlackListedItem) error {
+	if p.format == jsonOutput {
+		if items == nil {
+			items = []blacklist.BlackListedItem{}
+		}
+		return p.json(items)
+	}
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, []string{item.ID, item.Reason})
+	}
+	return p.table([]string{"ID", "REASON"}, rows)
+}
+
+// pruneResult is what prune found, and deleted unless it was a dry run.
+type pruneResult struct {
+	DryRun  bool                `json:"dryRun"`
+	Expired []db.RecordToDelete `json:"expired"`
+	Deleted int                 `json:"deleted"`
+}
+
+func (p *printer) prune(result pruneResult) error {
+	if p.format == jsonOutput {
+		if result.Expired == nil {
+			result.Expired = []db.RecordToDelete{}
+		}
+		return p.json(result)
+	}
+	rows := make([][]string, 0, len(result.Expired))
+	for _, r := range result.Expired {
+		rows = append(rows, []string{fmt.Sprint(r.RecordID), formatTime(r.DeathDate)})
+	}
+	if err := p.table([]string{"RECORD ID", "DEATH DATE"}, rows); err != nil {
+		return err
+	}
+	if result.DryRun {
+		_, err := fmt.Fprintf(p.w, "%d expired records found, none deleted (dry run)\n", len(result.Expired))
+		return err
+	}
+	_, err := fmt.Fprintf(p.w, "%d of %d expired records deleted\n", result.Deleted, len(result.Expired))
+	return err
+}
+
+func (p *printer) exportProgress(progress export.ExportProgress) error {
+	if p.format == jsonOutput {
+		return p.json(progress)
+	}
+	return p.table([]string{"DEVICES", "RECORDS", "TRUNCATED", "BYTES"}, [][]string{{
+		fmt.Sprint(progress.Devices),
+		fmt.Sprint(progress.Records),
+		fmt.Sprint(progress.Truncated),
+		fmt.Sprint(progress.Bytes),
+	}})
+}
+
+func (p *printer) importProgress(progress export.ImportProgress) error {
+	if p.format == jsonOutput {
+		return p.json(progress)
+	}
+	return p.table([]string{"LINES", "RECORDS"}, [][]string{{
+		fmt.Sprint(progress.Lines),
+		fmt.Sprint(progress.Records),
+	}})
+}
+
+func (p *printer) copyProgress(progress export.CopyProgress) error {
+	if p.format == jsonOutput {
+		return p.json(progress)
+	}
+	return p.table([]string{"DEVICES", "RECORDS", "TRUNCATED"}, [][]string{{
+		fmt.Sprint(progress.Devices),
+		fmt.Sprint(progress.Records),
+		fmt.Sprint(progress.Truncated),
+	}})
+}
+
+func (p *printer) verifyReport(report export.VerifyReport) error {
+	if p.format == jsonOutput {
+		return p.json(report)
+	}
+	rows := make([][]string, 0, len(report.Mismatches))
+	for _, m := range report.Mismatches {
+		rows = append(rows, []string{m.DeviceID, fmt.Sprint(m.SourceCount), fmt.Sprint(m.DestCount),
+			fmt.Sprint(m.Missing), fmt.Sprint(m.Duplicated), fmt.Sprint(m.Unexpected)})
+	}
+	if err := p.table([]string{"DEVICE ID", "SOURCE RECORDS", "DEST RECORDS", "MISSING", "DUPLICATED", "UNEXPECTED"}, rows); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(p.w, "%d of %d devices match\n", report.Matched, report.Devices)
+	return err
+}
+
+// status is the result of a command with nothing else to show.
+type status struct {
+	Status string      `json:"status"`
+	Detail interface{} `json:"detail,omitempty"`
+}
+
+func (p *printer) status(s status) error {
+	if p.format == jsonOutput {
+		return p.json(s)
+	}
+	if s.Detail != nil {
+		_, err := fmt.Fprintf(p.w, "%s: %v\n", s.Status, s.Detail)
+		return err
+	}
+	_, err := fmt.Fprintln(p.w, s.Status)
+	return err
+}
+
+// formatTime shows a unix nanosecond timestamp as RFC 3339 in UTC.
+func formatTime(unixNano int64) string {
+	return time.Unix(0, unixNano).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/xmidt-org/codex-db/export"
+)
+
+var (
+	errMismatches = errors.New("the databases hold different records")
+)
+
+func exportRecords(e *env, args []string) error {
+	flags := newFlagSet("export")
+	devices := flags.StringArray("device", nil, "export only this device; may be repeated")
+	start := flags.String("start", "", "the earliest birth date to export, in RFC 3339")
+	end := flags.String("end", "", "the latest birth date to export, in RFC 3339")
+	compress := flags.Bool("gzip", false, "compress the export")
+	checkpoint := flags.String("checkpoint", "", "save progress to this file, and resume from it if it exists")
+	maxRecords := flags.Int("max-records", 0, "the most records to export for one device")
+	if err := flags.Parse(args); err != nil {
+		return emperror.Wrap(errUsage, err.Error())
+	}
+	if flags.NArg() != 1 {
+		return emperror.Wrap(errUsage, "export takes one file")
+	}
+
+	config := export.ExportConfig{
+		MaxRecords:     *maxRecords,
+		Gzip:           *compress,
+		CheckpointFile: *checkpoint,
+	}
+	if err := parseTime(*start, &config.Start); err != nil {
+		return err
+	}
+	if err := parseTime(*end, &config.End); err != nil {
+		return err
+	}
+
+	return e.withBackend(connectOptions{}, func(b backend) error {
+		var lister export.DeviceLister = export.Devices(*devices)
+		if len(*devices) == 0 {
+			listStart, listEnd := config.Start, config.End
+			if listStart.IsZero() {
+				listStart = time.Unix(0, 0)
+			}
+			if listEnd.IsZero() {
+				listEnd = time.Now()
+			}
+			lister = b.exportDevices(listStart, listEnd)
+		}
+		exporter, err := export.NewExporter(config, b, lister)
+		if err != nil {
+			return err
+		}
+		progress, err := exporter.Export(e.ctx, flags.Arg(0))
+		if printErr := e.out.exportProgress(progress); err == nil {
+			err = printErr
+		}
+		return err
+	})
+}
+
+func importRecords(e *env, args []string) error {
+	flags := newFlagSet("import")
+	batchSize := flags.Int("batch-size", 0, "how many records to insert at a time")
+	rate := flags.Float64("rate", 0, "the most records to insert a second; 0 is no limit")
+	checkpoint := flags.String("checkpoint", "", "save progress to this file, and resume from it if it exists")
+	if err := flags.Parse(args); err != nil {
+		return emperror.Wrap(errUsage, err.Error())
+	}
+	if flags.NArg() != 1 {
+		return emperror.Wrap(errUsage, "import takes one file")
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	config := export.ImportConfig{
+		BatchSize:        *batchSize,
+		RecordsPerSecond: *rate,
+		CheckpointFile:   *checkpoint,
+	}
+	return e.withBackend(connectOptions{}, func(b backend) error {
+		importer, err := export.NewImporter(config, b)
+		if err != nil {
+			return err
+		}
+		progress, err := importer.Import(e.ctx, f)
+		if printErr := e.out.importProgress(progress); err == nil {
+			err = printErr
+		}
+		return err
+	})
+}
+
+// parseTime parses an RFC 3339 flag value into t, leaving t alone if the
+// flag wasn't set.
+func parseTime(value string, t *time.Time) error {
+	if value == "" {
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return emperror.Wrap(errUsage, err.Error())
+	}
+	*t = parsed
+	return nil
+}
+
+func copyRecords(e *env, args []string) error {
+	flags := newFlagSet("copy")
+	to := flags.String("to", "", "the configuration file of the database to copy to")
+	devices := flags.StringArray("device", nil, "copy only this device; may be repeated")
+	workers := flags.Int("workers", 0, "how many devices to copy at once")
+	batchSize := flags.Int("batch-size", 0, "how many records to insert at a time")
+	maxRecords := flags.Int("max-records", 0, "the most records to copy for one device")
+	checkpoint := flags.String("checkpoint", "", "save progress to this file, and resume from it if it exists")
+	if err := flags.Parse(args); err != nil {
+		return emperror.Wrap(errUsage, err.Error())
+	}
+	if flags.NArg() != 0 || *to == "" {
+		return emperror.Wrap(errUsage, "copy takes the --to configuration file")
+	}
+
+	config := export.CopyConfig{
+		Workers:        *workers,
+		BatchSize:      *batchSize,
+		MaxRecords:     *maxRecords,
+		CheckpointFile: *checkpoint,
+	}
+	return e.withBackends(*to, func(source backend, dest backend) error {
+		copier, err := export.NewCopier(config, source, listerFor(source, *devices), dest)
+		if err != nil {
+			return err
+		}
+		progress, err := copier.Copy(e.ctx)
+		if printErr := e.out.copyProgress(progress); err == nil {
+			err = printErr
+		}
+		return err
+	})
+}
+
+func verifyRecords(e *env, args []string) error {
+	flags := newFlagSet("verify")
+	to := flags.String("to", "", "the configuration file of the database to compare with")
+	devices := flags.StringArray("device", nil, "compare only this device; may be repeated")
+	workers := flags.Int("workers", 0, "how many devices to compare at once")
+	maxRecords := flags.Int("max-records", 0, "the most records to compare for one device")
+	if err := flags.Parse(args); err != nil {
+		return emperror.Wrap(errUsage, err.Error())
+	}
+	if flags.NArg() != 0 || *to == "" {
+		return emperror.Wrap(errUsage, "verify takes the --to configuration file")
+	}
+
+	config := export.VerifyConfig{
+		Workers:    *workers,
+		MaxRecords: *maxRecords,
+	}
+	return e.withBackends(*to, func(source backend, dest backend) error {
+		verifier, err := export.NewVerifier(config, source, listerFor(source, *devices), dest)
+		if err != nil {
+			return err
+		}
+		report, err := verifier.Verify(e.ctx)
+		if err != nil {
+			return err
+		}
+		if err = e.out.verifyReport(report); err != nil {
+			return err
+		}
+		if len(report.Mismatches) > 0 {
+			return emperror.With(errMismatches, "mismatches", len(report.Mismatches))
+		}
+		return nil
+	})
+}
+
+// withBackends connects to the configured database and the one configured
+// in the file at path, runs f, and closes both connections.
+func (e *env) withBackends(path string, f func(source backend, dest backend) error) error {
+	destConfig, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	return e.withBackend(connectOptions{}, func(source backend) error {
+		dest, err := e.connect(destConfig, connectOptions{})
+		if err != nil {
+			return emperror.WrapWith(err, "Connecting to the database failed", "file", path)
+		}
+		defer dest.Close()
+		return f(source, dest)
+	})
+}
+
+// listerFor lists the devices given, or every device in b if none were.
+func listerFor(b backend, devices []string) export.DeviceLister {
+	if len(devices) > 0 {
+		return export.Devices(devices)
+	}
+	return b.exportDevices(time.Unix(0, 0), time.Now())
+}
@@ -16,6 +16,8 @@ const (
 	ReadType          = "read"
 	PingType          = "ping"
 	BlacklistReadType = "blacklistRead"
+	// BlacklistWriteType is for adding to and removing from the blacklist.
+	BlacklistWriteType = "blacklistWrite"
 )
 
 // Record is the struct used to insert an event into the database.  It includes
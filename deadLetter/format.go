@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package deadLetter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/goph/emperror"
+	db "github.com/xmidt-org/codex-db"
+)
+
+// The formats a spool file can be written in.
+const (
+	// JSONFormat writes one batch per line as JSON.
+	JSONFormat = "jsonl"
+	// BinaryFormat writes each batch gob-encoded, after its length as four
+	// big endian bytes.
+	BinaryFormat = "binary"
+)
+
+const (
+	jsonExt   = ".jsonl"
+	binaryExt = ".bin"
+
+	// maxFrameSize is the largest batch a binary spool file is trusted to
+	// hold, so a corrupt length can't make the reader allocate gigabytes.
+	maxFrameSize = 256 << 20
+)
+
+var (
+	errCorrupt = errors.New("spool file is corrupt")
+)
+
+// batch is what is written to the spool for each failed insert.
+type batch struct {
+	Time    time.Time   `json:"time"`
+	Error   string      `json:"error,omitempty"`
+	Records []db.Record `json:"records"`
+}
+
+// encodeBatch returns b as it is written to a spool file in the format given.
+func encodeBatch(format string, b batch) ([]byte, error) {
+	if format == BinaryFormat {
+		var buf bytes.Buffer
+		buf.Write(make([]byte, 4))
+		if err := gob.NewEncoder(&buf).Encode(b); err != nil {
+			return nil, err
+		}
+		frame := buf.Bytes()
+		binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+		return frame, nil
+	}
+	frame, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	return append(frame, '\n'), nil
+}
+
+// batchReader reads the batches in a spool file one at a time.
+type batchReader interface {
+	// next returns the next batch and how many bytes of the file it took up,
+	// or io.EOF once the file is done.  An error wrapping errCorrupt means
+	// the batch can't be read; if the size returned isn't zero the batch can
+	// be skipped, otherwise the rest of the file can't be read.
+	next() (batch, int64, error)
+}
+
+func newBatchReader(ext string, r io.Reader) batchReader {
+	if ext == binaryExt {
+		return &binaryReader{r: bufio.NewReader(r)}
+	}
+	return &jsonReader{r: bufio.NewReader(r)}
+}
+
+type jsonReader struct {
+	r *bufio.Reader
+}
+
+func (j *jsonReader) next() (batch, int64, error) {
+	var b batch
+	line, err := j.r.ReadBytes('\n')
+	if err == io.EOF {
+		if len(line) == 0 {
+			return b, 0, io.EOF
+		}
+		return b, 0, emperror.Wrap(errCorrupt, "last line is incomplete")
+	}
+	if err != nil {
+		return b, 0, err
+	}
+	if err := json.Unmarshal(line, &b); err != nil {
+		return b, int64(len(line)), emperror.Wrap(errCorrupt, err.Error())
+	}
+	return b, int64(len(line)), nil
+}
+
+type binaryReader struct {
+	r *bufio.Reader
+}
+
+func (r *binaryReader) next() (batch, int64, error) {
+	var (
+		b      batch
+		header [4]byte
+	)
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return b, 0, emperror.Wrap(errCorrupt, "last batch is incomplete")
+		}
+		return b, 0, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return b, 0, emperror.WrapWith(errCorrupt, "batch is too large", "size", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r.r, body); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return b, 0, emperror.Wrap(errCorrupt, "last batch is incomplete")
+		}
+		return b, 0, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(body)).Decode(&b); err != nil {
+		return b, int64(len(body) + len(header)), emperror.Wrap(errCorrupt, err.Error())
+	}
+	return b, int64(len(body) + len(header)), nil
+}
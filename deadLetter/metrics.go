@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package deadLetter
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics"
+)
+
+const (
+	SpooledCounter   = "dead_letter_spooled_count"
+	ReplayedCounter  = "dead_letter_replayed_count"
+	DiscardedCounter = "dead_letter_discarded_count"
+	SpoolSizeGauge   = "dead_letter_spool_bytes"
+)
+
+const (
+	// ReasonLabel is for labeling discarded records with why they weren't
+	// spooled or replayed.
+	ReasonLabel = "reason"
+
+	// SpoolFullReason is when spooling the records would go past the spool's
+	// size cap.
+	SpoolFullReason = "spool_full"
+	// WriteFailedReason is when the records couldn't be written to the spool.
+	WriteFailedReason = "write_failed"
+	// QuarantinedReason is when the records failed to replay too many times
+	// and were moved to a quarantine file.
+	QuarantinedReason = "quarantined"
+	// CorruptReason is when a batch in a spool file can't be read, such as
+	// one cut off by a crash.  Its records can't be counted, so each batch
+	// skipped counts as one.
+	CorruptReason = "corrupt"
+)
+
+func Metrics() []xmetrics.Metric {
+	return []xmetrics.Metric{
+		{
+			Name: SpooledCounter,
+			Help: "The total number of records written to the dead letter spool",
+			Type: "counter",
+		},
+		{
+			Name: ReplayedCounter,
+			Help: "The total number of records from the dead letter spool inserted into the database",
+			Type: "counter",
+		},
+		{
+			Name:       DiscardedCounter,
+			Help:       "The total number of records sent to the dead letter spool that couldn't be kept or replayed, by reason",
+			Type:       "counter",
+			LabelNames: []string{ReasonLabel},
+		},
+		{
+			Name: SpoolSizeGauge,
+			Help: "The number of bytes in the dead letter spool",
+			Type: "gauge",
+		},
+	}
+}
+
+type Measures struct {
+	Spooled   metrics.Counter
+	Replayed  metrics.Counter
+	Discarded metrics.Counter
+	SpoolSize metrics.Gauge
+}
+
+// NewMeasures constructs a Measures given a go-kit metrics Provider
+func NewMeasures(p provider.Provider) *Measures {
+	return &Measures{
+		Spooled:   p.NewCounter(SpooledCounter),
+		Replayed:  p.NewCounter(ReplayedCounter),
+		Discarded: p.NewCounter(DiscardedCounter),
+		SpoolSize: p.NewGauge(SpoolSizeGauge),
+	}
+}
@@ -0,0 +1,18 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package deadLetter
+
+import (
+	"github.com/stretchr/testify/mock"
+	db "github.com/xmidt-org/codex-db"
+)
+
+type mockInserter struct {
+	mock.Mock
+}
+
+func (c *mockInserter) InsertRecords(records ...db.Record) error {
+	args := c.Called(records)
+	return args.Error(0)
+}
@@ -0,0 +1,281 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package deadLetter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/goph/emperror"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+)
+
+const (
+	defaultReplayInterval = time.Minute
+)
+
+var (
+	errNoSink     = errors.New("no file sink")
+	errNoInserter = errors.New("no inserter")
+)
+
+// Replay inserts the spooled batches into the inserter, oldest first,
+// removing each spool file once all of its batches are inserted.  The
+// current spool file is closed first so that everything sent before Replay
+// was called is included.  Replay stops at the first batch that fails to
+// insert, returning the error along with how many records were inserted;
+// calling it again picks up at that batch.  Once a batch has failed
+// MaxReplays times it is moved to a quarantine file and counted as
+// discarded, and the batches after it are replayed.
+//
+// Batches that can't be read are logged, counted as discarded, and skipped.
+// If one is cut off, such as by a crash, the rest of the file is skipped.
+func (s *FileSink) Replay(ctx context.Context, inserter db.Inserter) (int, error) {
+	s.replayLock.Lock()
+	defer s.replayLock.Unlock()
+
+	s.lock.Lock()
+	err := s.rotate()
+	files := make([]spoolFile, len(s.files))
+	copy(files, s.files)
+	s.lock.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, file := range files {
+		n, err := s.replayFile(ctx, file.path, inserter)
+		replayed += n
+		if err != nil {
+			return replayed, err
+		}
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			return replayed, emperror.WrapWith(err, "Removing spool file failed", "file", file.path)
+		}
+		delete(s.offsets, file.path)
+		delete(s.attempts, file.path)
+
+		s.lock.Lock()
+		for i, f := range s.files {
+			if f.path == file.path {
+				s.files = append(s.files[:i], s.files[i+1:]...)
+				break
+			}
+		}
+		s.totalSize -= file.size
+		s.setSize()
+		s.lock.Unlock()
+	}
+	return replayed, nil
+}
+
+func (s *FileSink) replayFile(ctx context.Context, path string, inserter db.Inserter) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, emperror.WrapWith(err, "Opening spool file failed", "file", path)
+	}
+	defer f.Close()
+	offset := s.offsets[path]
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, emperror.WrapWith(err, "Seeking in spool file failed", "file", path)
+	}
+
+	replayed := 0
+	reader := newBatchReader(filepath.Ext(path), f)
+	for {
+		if err := ctx.Err(); err != nil {
+			return replayed, err
+		}
+		b, size, err := reader.next()
+		if err == io.EOF {
+			return replayed, nil
+		}
+		if err != nil {
+			if !isCorrupt(err) {
+				return replayed, emperror.WrapWith(err, "Reading spool file failed", "file", path)
+			}
+			s.discard(CorruptReason, 1)
+			if size == 0 {
+				logging.Error(s.logger, emperror.Context(err)...).Log(logging.MessageKey(),
+					"Skipping the rest of a corrupt spool file", "file", path, "offset", offset,
+					logging.ErrorKey(), err.Error())
+				return replayed, nil
+			}
+			logging.Error(s.logger, emperror.Context(err)...).Log(logging.MessageKey(),
+				"Skipping a corrupt batch", "file", path, "offset", offset, logging.ErrorKey(), err.Error())
+			s.advance(path, size)
+			offset += size
+			continue
+		}
+		if len(b.Records) == 0 {
+			s.advance(path, size)
+			offset += size
+			continue
+		}
+		if err := inserter.InsertRecords(b.Records...); err != nil {
+			s.attempts[path]++
+			if s.attempts[path] < s.config.MaxReplays {
+				return replayed, emperror.WrapWith(err, "Replaying batch failed", "file", path, "records", len(b.Records))
+			}
+			if qerr := s.quarantine(path, b); qerr != nil {
+				return replayed, qerr
+			}
+			logging.Error(s.logger, emperror.Context(err)...).Log(logging.MessageKey(),
+				"Quarantined a batch that failed to replay", "file", path, "offset", offset,
+				"records", len(b.Records), "attempts", s.attempts[path], logging.ErrorKey(), err.Error())
+			s.discard(QuarantinedReason, len(b.Records))
+		} else {
+			replayed += len(b.Records)
+			if s.measures != nil {
+				s.measures.Replayed.Add(float64(len(b.Records)))
+			}
+		}
+		s.advance(path, size)
+		offset += size
+	}
+}
+
+// advance moves past a batch of the spool file that is done with.  The
+// replayLock must be held.
+func (s *FileSink) advance(path string, size int64) {
+	s.offsets[path] += size
+	delete(s.attempts, path)
+}
+
+// quarantine appends b to the quarantine file for the spool file given,
+// where it is kept for inspection but not replayed.  The replayLock must be
+// held.
+func (s *FileSink) quarantine(path string, b batch) error {
+	ext := filepath.Ext(path)
+	format := JSONFormat
+	if ext == binaryExt {
+		format = BinaryFormat
+	}
+	frame, err := encodeBatch(format, b)
+	if err != nil {
+		return emperror.Wrap(err, "Encoding batch failed")
+	}
+	name := quarantinePrefix + strings.TrimPrefix(filepath.Base(path), filePrefix)
+	quarantinePath := filepath.Join(filepath.Dir(path), name)
+	f, err := os.OpenFile(quarantinePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return emperror.WrapWith(err, "Opening quarantine file failed", "file", quarantinePath)
+	}
+	_, err = f.Write(frame)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return emperror.WrapWith(err, "Writing to quarantine file failed", "file", quarantinePath)
+	}
+	return nil
+}
+
+func isCorrupt(err error) bool {
+	corrupt := false
+	emperror.ForEachCause(err, func(err error) bool {
+		corrupt = err == errCorrupt
+		return !corrupt
+	})
+	return corrupt
+}
+
+// ReplayerConfig holds the configuration values for a replayer.
+type ReplayerConfig struct {
+	// Interval is how long to wait between attempts to replay the spool.
+	Interval time.Duration
+}
+
+// Replayer replays a FileSink's spool in the background, trying again every
+// interval so that spooled batches are inserted once the database recovers.
+type Replayer struct {
+	sink     *FileSink
+	inserter db.Inserter
+	config   ReplayerConfig
+	logger   log.Logger
+
+	lock   sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewReplayer creates a Replayer with the given values, ensuring that the
+// configuration and other values given are valid.  If configuration values
+// aren't valid, a default value is used.
+func NewReplayer(config ReplayerConfig, logger log.Logger, sink *FileSink, inserter db.Inserter) (*Replayer, error) {
+	if sink == nil {
+		return nil, errNoSink
+	}
+	if inserter == nil {
+		return nil, errNoInserter
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultReplayInterval
+	}
+	if logger == nil {
+		logger = defaultLogger
+	}
+	return &Replayer{
+		sink:     sink,
+		inserter: inserter,
+		config:   config,
+		logger:   logger,
+	}, nil
+}
+
+// Start starts replaying the spool every interval.
+func (r *Replayer) Start() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go r.run(ctx)
+}
+
+// Stop stops replaying, waiting for the batch being inserted to finish.
+func (r *Replayer) Stop() {
+	r.lock.Lock()
+	cancel := r.cancel
+	r.lock.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	r.wg.Wait()
+}
+
+func (r *Replayer) run(ctx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		replayed, err := r.sink.Replay(ctx, r.inserter)
+		if err != nil && ctx.Err() == nil {
+			logging.Error(r.logger, emperror.Context(err)...).Log(logging.MessageKey(),
+				"Failed to replay the dead letter spool", "records", replayed, logging.ErrorKey(), err.Error())
+			continue
+		}
+		if replayed > 0 {
+			logging.Info(r.logger).Log(logging.MessageKey(), "Replayed the dead letter spool", "records", replayed)
+		}
+	}
+}
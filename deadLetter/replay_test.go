@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package deadLetter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func TestReplay(t *testing.T) {
+	for _, format := range []string{JSONFormat, BinaryFormat} {
+		t.Run(format, func(t *testing.T) {
+			assert := assert.New(t)
+			dir := t.TempDir()
+			p := xmetricstest.NewProvider(nil, Metrics)
+			s, err := NewFileSink(FileConfig{Dir: dir, Format: format}, nil, p)
+			require.NoError(t, err)
+			require.NoError(t, s.Send(testRecords[:1], nil))
+			require.NoError(t, s.Send(testRecords[1:], nil))
+
+			// the database is still down for the second batch.
+			inserter := new(mockInserter)
+			inserter.On("InsertRecords", testRecords[:1]).Return(nil).Once()
+			inserter.On("InsertRecords", testRecords[1:]).Return(errors.New("test error")).Once()
+			replayed, err := s.Replay(context.Background(), inserter)
+			assert.Equal(1, replayed)
+			assert.Contains(err.Error(), "test error")
+			assert.Equal([]string{fileName(0, format)}, spoolFiles(t, dir))
+
+			// the next replay picks up at the batch that failed.
+			inserter.On("InsertRecords", testRecords[1:]).Return(nil).Once()
+			replayed, err = s.Replay(context.Background(), inserter)
+			assert.Equal(1, replayed)
+			assert.NoError(err)
+			assert.Empty(spoolFiles(t, dir))
+			assert.Empty(s.files)
+			inserter.AssertExpectations(t)
+			p.Assert(t, ReplayedCounter)(xmetricstest.Value(2))
+			p.Assert(t, SpoolSizeGauge)(xmetricstest.Value(0))
+
+			// batches sent after a replay go to a new file.
+			require.NoError(t, s.Send(testRecords, nil))
+			assert.Equal([]string{fileName(1, format)}, spoolFiles(t, dir))
+		})
+	}
+}
+
+func TestReplayCorrupt(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	frame, err := encodeBatch(BinaryFormat, batch{Records: testRecords})
+	require.NoError(t, err)
+	// a batch cut off partway through follows a whole one.
+	contents := append(append([]byte{}, frame...), frame[:len(frame)/2]...)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fileName(0, BinaryFormat)), contents, 0600))
+	// a line that isn't a batch is skipped on its own.
+	line, err := encodeBatch(JSONFormat, batch{Records: testRecords[:1]})
+	require.NoError(t, err)
+	contents = append(append([]byte("not a batch\n"), line...), line[:len(line)/2]...)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fileName(1, JSONFormat)), contents, 0600))
+
+	p := xmetricstest.NewProvider(nil, Metrics)
+	s, err := NewFileSink(FileConfig{Dir: dir}, nil, p)
+	require.NoError(t, err)
+	inserter := new(mockInserter)
+	inserter.On("InsertRecords", testRecords).Return(nil).Once()
+	inserter.On("InsertRecords", testRecords[:1]).Return(nil).Once()
+	replayed, err := s.Replay(context.Background(), inserter)
+	assert.NoError(err)
+	assert.Equal(3, replayed)
+	assert.Empty(spoolFiles(t, dir))
+	inserter.AssertExpectations(t)
+	p.Assert(t, DiscardedCounter, ReasonLabel, CorruptReason)(xmetricstest.Value(3))
+}
+
+func TestReplayQuarantine(t *testing.T) {
+	for _, format := range []string{JSONFormat, BinaryFormat} {
+		t.Run(format, func(t *testing.T) {
+			assert := assert.New(t)
+			dir := t.TempDir()
+			p := xmetricstest.NewProvider(nil, Metrics)
+			s, err := NewFileSink(FileConfig{Dir: dir, Format: format, MaxReplays: 2}, nil, p)
+			require.NoError(t, err)
+			require.NoError(t, s.Send(testRecords[:1], nil))
+			require.NoError(t, s.Send(testRecords[1:], nil))
+
+			// the database always rejects the first batch.
+			inserter := new(mockInserter)
+			inserter.On("InsertRecords", testRecords[:1]).Return(errors.New("test error")).Twice()
+			inserter.On("InsertRecords", testRecords[1:]).Return(nil).Once()
+			replayed, err := s.Replay(context.Background(), inserter)
+			assert.Equal(0, replayed)
+			assert.Contains(err.Error(), "test error")
+
+			// once it has failed MaxReplays times it is moved aside.
+			replayed, err = s.Replay(context.Background(), inserter)
+			assert.Equal(1, replayed)
+			assert.NoError(err)
+			inserter.AssertExpectations(t)
+			p.Assert(t, DiscardedCounter, ReasonLabel, QuarantinedReason)(xmetricstest.Value(1))
+			p.Assert(t, ReplayedCounter)(xmetricstest.Value(1))
+
+			quarantineFile := quarantinePrefix + strings.TrimPrefix(fileName(0, format), filePrefix)
+			assert.Equal([]string{quarantineFile}, spoolFiles(t, dir))
+			f, err := os.Open(filepath.Join(dir, quarantineFile))
+			require.NoError(t, err)
+			defer f.Close()
+			reader := newBatchReader(filepath.Ext(quarantineFile), f)
+			b, _, err := reader.next()
+			require.NoError(t, err)
+			assert.Equal(testRecords[:1], b.Records)
+			_, _, err = reader.next()
+			assert.Equal(io.EOF, err)
+
+			// the quarantine file isn't replayed.
+			s, err = NewFileSink(FileConfig{Dir: dir, Format: format}, nil, nil)
+			require.NoError(t, err)
+			assert.Empty(s.files)
+		})
+	}
+}
+
+func TestReplayer(t *testing.T) {
+	assert := assert.New(t)
+	s, err := NewFileSink(FileConfig{Dir: t.TempDir()}, nil, nil)
+	require.NoError(t, err)
+	inserter := new(mockInserter)
+
+	_, err = NewReplayer(ReplayerConfig{}, nil, nil, inserter)
+	assert.Equal(errNoSink, err)
+	_, err = NewReplayer(ReplayerConfig{}, nil, s, nil)
+	assert.Equal(errNoInserter, err)
+	r, err := NewReplayer(ReplayerConfig{}, nil, s, inserter)
+	require.NoError(t, err)
+	assert.Equal(defaultReplayInterval, r.config.Interval)
+
+	done := make(chan struct{})
+	inserter.On("InsertRecords", testRecords).Return(errors.New("test error")).Once()
+	inserter.On("InsertRecords", testRecords).Return(nil).Once().Run(func(_ mock.Arguments) {
+		close(done)
+	})
+	require.NoError(t, s.Send(testRecords, nil))
+	r, err = NewReplayer(ReplayerConfig{Interval: time.Millisecond}, nil, s, inserter)
+	require.NoError(t, err)
+	r.Start()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("spool was never replayed")
+	}
+	r.Stop()
+	r.Stop()
+	inserter.AssertExpectations(t)
+}
@@ -0,0 +1,287 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// package deadLetter provides a place to keep batches of records that
+// couldn't be inserted into the database, and a way to insert them once the
+// database recovers.
+package deadLetter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/goph/emperror"
+	db "github.com/xmidt-org/codex-db"
+)
+
+const (
+	defaultMaxFileSize = 64 << 20
+	defaultMaxReplays  = 10
+	filePrefix         = "deadletter-"
+	quarantinePrefix   = "quarantine-"
+)
+
+var (
+	defaultLogger = log.NewNopLogger()
+)
+
+var (
+	errNoDir         = errors.New("no spool directory")
+	errUnknownFormat = errors.New("format must be jsonl or binary")
+	errSpoolFull     = errors.New("dead letter spool is full")
+	errSinkClosed    = errors.New("dead letter sink is closed")
+)
+
+// Sink is something that keeps batches of records that failed to insert,
+// along with the error from inserting them.
+type Sink interface {
+	Send(records []db.Record, cause error) error
+}
+
+// FileConfig holds the configuration values for a file sink.
+type FileConfig struct {
+	// Dir is the directory the spool files are kept in.  It is created if it
+	// doesn't exist.
+	Dir string
+
+	// Format is JSONFormat or BinaryFormat.  It defaults to JSONFormat.
+	// Files in either format are replayed, so it can be changed between
+	// runs.
+	Format string
+
+	// MaxFileSize is how many bytes a spool file holds before a new one is
+	// started.
+	MaxFileSize int64
+
+	// MaxTotalSize is how many bytes all of the spool files can hold.
+	// Batches that would go past it are discarded.  Zero is no limit.
+	MaxTotalSize int64
+
+	// MaxReplays is how many times Replay tries to insert a batch before
+	// moving it to a quarantine file next to its spool file, so that a batch
+	// the database always rejects doesn't hold up the rest of the spool.
+	// Failures while the database is down count too, so it should allow for
+	// outages.  Defaults to 10.
+	MaxReplays int
+}
+
+// spoolFile is a spool file that is no longer being written to.
+type spoolFile struct {
+	path string
+	size int64
+}
+
+// FileSink is a Sink that appends batches to files in a directory, starting
+// a new file once the current one is large enough.  The files can be
+// replayed into an inserter with Replay.
+type FileSink struct {
+	config   FileConfig
+	logger   log.Logger
+	measures *Measures
+
+	lock        sync.Mutex
+	current     *os.File
+	currentPath string
+	currentSize int64
+	files       []spoolFile
+	totalSize   int64
+	nextSeq     uint64
+	closed      bool
+
+	// replayLock keeps one Replay at a time, and guards offsets: how far
+	// into each file an earlier Replay got before failing, and attempts: how
+	// many times the batch at that offset has failed.
+	replayLock sync.Mutex
+	offsets    map[string]int64
+	attempts   map[string]int
+}
+
+// NewFileSink creates a FileSink with the given values, ensuring that the
+// configuration values given are valid.  If configuration values aren't
+// valid, a default value is used.  Spool files already in the directory are
+// kept, to be replayed.
+func NewFileSink(config FileConfig, logger log.Logger, metricsRegistry provider.Provider) (*FileSink, error) {
+	if config.Dir == "" {
+		return nil, errNoDir
+	}
+	switch config.Format {
+	case "":
+		config.Format = JSONFormat
+	case JSONFormat, BinaryFormat:
+	default:
+		return nil, emperror.With(errUnknownFormat, "format", config.Format)
+	}
+	if config.MaxFileSize <= 0 {
+		config.MaxFileSize = defaultMaxFileSize
+	}
+	if config.MaxTotalSize < 0 {
+		config.MaxTotalSize = 0
+	}
+	if config.MaxReplays <= 0 {
+		config.MaxReplays = defaultMaxReplays
+	}
+	if logger == nil {
+		logger = defaultLogger
+	}
+	if err := os.MkdirAll(config.Dir, 0700); err != nil {
+		return nil, emperror.WrapWith(err, "Creating spool directory failed", "dir", config.Dir)
+	}
+
+	s := &FileSink{
+		config:   config,
+		logger:   logger,
+		offsets:  make(map[string]int64),
+		attempts: make(map[string]int),
+	}
+	if metricsRegistry != nil {
+		s.measures = NewMeasures(metricsRegistry)
+	}
+	entries, err := os.ReadDir(config.Dir)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "Reading spool directory failed", "dir", config.Dir)
+	}
+	for _, entry := range entries {
+		seq, ok := parseFileName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, emperror.WrapWith(err, "Reading spool file failed", "file", entry.Name())
+		}
+		s.files = append(s.files, spoolFile{path: filepath.Join(config.Dir, entry.Name()), size: info.Size()})
+		s.totalSize += info.Size()
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(s.files, func(i, j int) bool {
+		return s.files[i].path < s.files[j].path
+	})
+	s.setSize()
+	return s, nil
+}
+
+// fileName is the name of the spool file with the sequence number given.
+// Sorting the names sorts the files from oldest to newest.
+func fileName(seq uint64, format string) string {
+	ext := jsonExt
+	if format == BinaryFormat {
+		ext = binaryExt
+	}
+	return fmt.Sprintf("%s%020d%s", filePrefix, seq, ext)
+}
+
+func parseFileName(name string) (uint64, bool) {
+	ext := filepath.Ext(name)
+	if !strings.HasPrefix(name, filePrefix) || (ext != jsonExt && ext != binaryExt) {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), ext), 10, 64)
+	return seq, err == nil
+}
+
+// Send appends the records to the current spool file.  If they can't be
+// kept, they are counted as discarded and an error is returned.
+func (s *FileSink) Send(records []db.Record, cause error) error {
+	if len(records) == 0 {
+		return nil
+	}
+	b := batch{Time: time.Now().UTC(), Records: records}
+	if cause != nil {
+		b.Error = cause.Error()
+	}
+	frame, err := encodeBatch(s.config.Format, b)
+	if err != nil {
+		s.discard(WriteFailedReason, len(records))
+		return emperror.Wrap(err, "Encoding batch failed")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		s.discard(WriteFailedReason, len(records))
+		return errSinkClosed
+	}
+	if s.config.MaxTotalSize > 0 && s.totalSize+int64(len(frame)) > s.config.MaxTotalSize {
+		s.discard(SpoolFullReason, len(records))
+		return emperror.With(errSpoolFull, "records", len(records), "size", s.totalSize)
+	}
+	if s.current == nil {
+		path := filepath.Join(s.config.Dir, fileName(s.nextSeq, s.config.Format))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			s.discard(WriteFailedReason, len(records))
+			return emperror.WrapWith(err, "Creating spool file failed", "file", path)
+		}
+		s.nextSeq++
+		s.current, s.currentPath, s.currentSize = f, path, 0
+	}
+
+	n, err := s.current.Write(frame)
+	s.currentSize += int64(n)
+	s.totalSize += int64(n)
+	s.setSize()
+	if err != nil {
+		s.discard(WriteFailedReason, len(records))
+		// start over in a new file, leaving the partial batch at the end of
+		// this one.
+		if closeErr := s.rotate(); closeErr != nil {
+			err = closeErr
+		}
+		return emperror.WrapWith(err, "Writing to spool file failed", "file", s.currentPath)
+	}
+	if s.measures != nil {
+		s.measures.Spooled.Add(float64(len(records)))
+	}
+	if s.currentSize >= s.config.MaxFileSize {
+		return s.rotate()
+	}
+	return nil
+}
+
+// Close closes the current spool file.  Batches sent after Close are
+// discarded, but the spool can still be replayed.
+func (s *FileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	return s.rotate()
+}
+
+// rotate closes the current spool file, if there is one, so that the next
+// batch starts a new one.  The lock must be held.
+func (s *FileSink) rotate() error {
+	if s.current == nil {
+		return nil
+	}
+	err := s.current.Close()
+	s.files = append(s.files, spoolFile{path: s.currentPath, size: s.currentSize})
+	s.current, s.currentPath, s.currentSize = nil, "", 0
+	if err != nil {
+		return emperror.Wrap(err, "Closing spool file failed")
+	}
+	return nil
+}
+
+func (s *FileSink) discard(reason string, records int) {
+	if s.measures != nil {
+		s.measures.Discarded.With(ReasonLabel, reason).Add(float64(records))
+	}
+}
+
+// setSize updates the spool size gauge.  The lock must be held.
+func (s *FileSink) setSize() {
+	if s.measures != nil {
+		s.measures.SpoolSize.Set(float64(s.totalSize))
+	}
+}
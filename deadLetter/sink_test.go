@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package deadLetter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+var testRecords = []db.Record{
+	{DeviceID: "a", Data: []byte("a"), BirthDate: 1},
+	{DeviceID: "b", Data: []byte("b"), Nonce: []byte("n"), Alg: "alg", KID: "kid"},
+}
+
+func spoolFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestImplementsInterfaces(t *testing.T) {
+	var s interface{} = &FileSink{}
+	_, ok := s.(Sink)
+	assert.True(t, ok, "not a sink")
+}
+
+func TestNewFileSink(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewFileSink(FileConfig{}, nil, nil)
+	assert.Equal(errNoDir, err)
+	_, err = NewFileSink(FileConfig{Dir: t.TempDir(), Format: "xml"}, nil, nil)
+	assert.Contains(err.Error(), errUnknownFormat.Error())
+
+	dir := filepath.Join(t.TempDir(), "spool")
+	s, err := NewFileSink(FileConfig{Dir: dir, MaxFileSize: -1, MaxTotalSize: -1, MaxReplays: -1}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(FileConfig{Dir: dir, Format: JSONFormat, MaxFileSize: defaultMaxFileSize, MaxReplays: defaultMaxReplays}, s.config)
+	assert.Equal(defaultLogger, s.logger)
+
+	// files already there are picked up, and new ones come after them.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, fileName(7, BinaryFormat)), []byte("abc"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("abc"), 0600))
+	p := xmetricstest.NewProvider(nil, Metrics)
+	s, err = NewFileSink(FileConfig{Dir: dir}, nil, p)
+	require.NoError(t, err)
+	assert.Equal([]spoolFile{{path: filepath.Join(dir, fileName(7, BinaryFormat)), size: 3}}, s.files)
+	assert.Equal(uint64(8), s.nextSeq)
+	p.Assert(t, SpoolSizeGauge)(xmetricstest.Value(3))
+}
+
+func TestSend(t *testing.T) {
+	for _, format := range []string{JSONFormat, BinaryFormat} {
+		t.Run(format, func(t *testing.T) {
+			assert := assert.New(t)
+			dir := t.TempDir()
+			p := xmetricstest.NewProvider(nil, Metrics)
+			// every batch is larger than a byte, so each gets its own file.
+			s, err := NewFileSink(FileConfig{Dir: dir, Format: format, MaxFileSize: 1}, nil, p)
+			require.NoError(t, err)
+
+			assert.NoError(s.Send(nil, nil))
+			assert.NoError(s.Send(testRecords, errors.New("test error")))
+			assert.NoError(s.Send(testRecords[:1], nil))
+			assert.Equal([]string{fileName(0, format), fileName(1, format)}, spoolFiles(t, dir))
+			p.Assert(t, SpooledCounter)(xmetricstest.Value(3))
+
+			f, err := os.Open(filepath.Join(dir, fileName(0, format)))
+			require.NoError(t, err)
+			defer f.Close()
+			b, _, err := newBatchReader(filepath.Ext(f.Name()), f).next()
+			require.NoError(t, err)
+			assert.Equal(testRecords, b.Records)
+			assert.Equal("test error", b.Error)
+			assert.False(b.Time.IsZero())
+
+			assert.NoError(s.Close())
+			assert.Equal(errSinkClosed, s.Send(testRecords, nil))
+			p.Assert(t, DiscardedCounter, ReasonLabel, WriteFailedReason)(xmetricstest.Value(2))
+		})
+	}
+}
+
+func TestSendSpoolFull(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	p := xmetricstest.NewProvider(nil, Metrics)
+	s, err := NewFileSink(FileConfig{Dir: dir, MaxTotalSize: 200}, nil, p)
+	require.NoError(t, err)
+
+	assert.NoError(s.Send(testRecords[:1], nil))
+	err = s.Send(testRecords, nil)
+	assert.Contains(err.Error(), errSpoolFull.Error())
+	p.Assert(t, SpooledCounter)(xmetricstest.Value(1))
+	p.Assert(t, DiscardedCounter, ReasonLabel, SpoolFullReason)(xmetricstest.Value(2))
+	assert.NoError(s.Close())
+}
@@ -0,0 +1,245 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// package dualInserter provides a db.Inserter that writes every batch to two
+// inserters, for moving from one database to another without downtime.
+package dualInserter
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/goph/emperror"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/deadLetter"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+)
+
+// The policies for writing to the secondary.
+const (
+	// BestEffort inserts into the secondary in the background, once the
+	// primary insert succeeds.  Batches the secondary can't take go to the
+	// dead letter sink.
+	BestEffort = "bestEffort"
+	// RequireBoth inserts into the secondary once the primary insert
+	// succeeds, failing the insert if the secondary fails.  The batch isn't
+	// sent to the dead letter sink then, since the caller is told it failed.
+	RequireBoth = "requireBoth"
+)
+
+const (
+	defaultQueueSize        = 100
+	defaultSecondaryWorkers = 1
+)
+
+var (
+	defaultLogger = log.NewNopLogger()
+)
+
+var (
+	errNoPrimary     = errors.New("no primary inserter")
+	errNoSecondary   = errors.New("no secondary inserter")
+	errUnknownPolicy = errors.New("policy must be bestEffort or requireBoth")
+)
+
+// Config holds the configuration values for a dual inserter.
+type Config struct {
+	// Policy is BestEffort or RequireBoth.  It defaults to BestEffort.
+	Policy string
+
+	// QueueSize is how many batches can wait for the secondary when the
+	// policy is BestEffort.
+	QueueSize int
+
+	// SecondaryWorkers is how many batches are inserted into the secondary
+	// at once when the policy is BestEffort.
+	SecondaryWorkers int
+}
+
+// Option configures a DualInserter beyond its Config.
+type Option func(*DualInserter)
+
+// WithDeadLetter sends the batches that weren't inserted into the secondary
+// under BestEffort to the sink, such as a deadLetter.FileSink that a
+// deadLetter.Replayer inserts into the secondary later.  Without it, they
+// are dropped.  The caller closes the sink once the DualInserter is stopped.
+func WithDeadLetter(sink deadLetter.Sink) Option {
+	return func(d *DualInserter) {
+		d.deadLetter = sink
+	}
+}
+
+// DualInserter is a db.Inserter that inserts into a primary and then a
+// secondary inserter.  Records are only sent to the secondary once the
+// primary has them.
+type DualInserter struct {
+	primary    db.Inserter
+	secondary  db.Inserter
+	config     Config
+	measures   *Measures
+	logger     log.Logger
+	deadLetter deadLetter.Sink
+
+	queue   chan []db.Record
+	lock    sync.RWMutex
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// NewDualInserter creates a DualInserter with the given values, ensuring
+// that the configuration and other values given are valid.  If configuration
+// values aren't valid, a default value is used.
+func NewDualInserter(config Config, logger log.Logger, metricsRegistry provider.Provider, primary db.Inserter, secondary db.Inserter, options ...Option) (*DualInserter, error) {
+	if primary == nil {
+		return nil, errNoPrimary
+	}
+	if secondary == nil {
+		return nil, errNoSecondary
+	}
+	switch config.Policy {
+	case "":
+		config.Policy = BestEffort
+	case BestEffort, RequireBoth:
+	default:
+		return nil, emperror.With(errUnknownPolicy, "policy", config.Policy)
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
+	if config.SecondaryWorkers <= 0 {
+		config.SecondaryWorkers = defaultSecondaryWorkers
+	}
+	if logger == nil {
+		logger = defaultLogger
+	}
+
+	d := &DualInserter{
+		primary:   primary,
+		secondary: secondary,
+		config:    config,
+		logger:    logger,
+		queue:     make(chan []db.Record, config.QueueSize),
+	}
+	if metricsRegistry != nil {
+		d.measures = NewMeasures(metricsRegistry)
+	}
+	for _, o := range options {
+		o(d)
+	}
+	return d, nil
+}
+
+// Start starts the workers inserting into the secondary in the background.
+func (d *DualInserter) Start() {
+	if d.config.Policy != BestEffort {
+		return
+	}
+	for i := 0; i < d.config.SecondaryWorkers; i++ {
+		d.wg.Add(1)
+		go d.insertSecondary()
+	}
+}
+
+// Stop waits for the batches queued for the secondary to be inserted.
+// Batches inserted after Stop is called are still inserted into the primary,
+// but are sent to the dead letter sink instead of the secondary.
+func (d *DualInserter) Stop() {
+	d.lock.Lock()
+	if d.stopped {
+		d.lock.Unlock()
+		return
+	}
+	d.stopped = true
+	close(d.queue)
+	d.lock.Unlock()
+
+	d.wg.Wait()
+}
+
+// InsertRecords inserts the records into the primary, returning an error if
+// that fails.  The records are then inserted into the secondary according to
+// the policy.
+func (d *DualInserter) InsertRecords(records ...db.Record) error {
+	if err := d.primary.InsertRecords(records...); err != nil {
+		d.count(false, PrimaryStore, len(records))
+		return err
+	}
+	d.count(true, PrimaryStore, len(records))
+
+	if d.config.Policy == RequireBoth {
+		if err := d.secondary.InsertRecords(records...); err != nil {
+			d.count(false, SecondaryStore, len(records))
+			return emperror.Wrap(err, "Inserting into the secondary failed")
+		}
+		d.count(true, SecondaryStore, len(records))
+		return nil
+	}
+
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	if d.stopped {
+		d.sendToDeadLetter(records, StoppedReason, nil)
+		return nil
+	}
+	// the caller may reuse its slice once this returns.
+	queued := make([]db.Record, len(records))
+	copy(queued, records)
+	select {
+	case d.queue <- queued:
+		if d.measures != nil {
+			d.measures.SecondaryQueue.Add(1.0)
+		}
+	default:
+		d.sendToDeadLetter(records, QueueFullReason, nil)
+	}
+	return nil
+}
+
+func (d *DualInserter) insertSecondary() {
+	defer d.wg.Done()
+	for records := range d.queue {
+		if d.measures != nil {
+			d.measures.SecondaryQueue.Add(-1.0)
+		}
+		if err := d.secondary.InsertRecords(records...); err != nil {
+			d.count(false, SecondaryStore, len(records))
+			d.sendToDeadLetter(records, FailedReason, err)
+			continue
+		}
+		d.count(true, SecondaryStore, len(records))
+	}
+}
+
+func (d *DualInserter) count(success bool, store string, records int) {
+	if d.measures == nil {
+		return
+	}
+	if success {
+		d.measures.Success.With(StoreLabel, store).Add(float64(records))
+		return
+	}
+	d.measures.Failure.With(StoreLabel, store).Add(float64(records))
+}
+
+func (d *DualInserter) sendToDeadLetter(records []db.Record, reason string, cause error) {
+	if d.measures != nil {
+		d.measures.DeadLetters.With(ReasonLabel, reason).Add(float64(len(records)))
+	}
+	keyvals := []interface{}{logging.MessageKey(), "Records not inserted into the secondary",
+		"reason", reason, "records", len(records)}
+	if cause != nil {
+		keyvals = append(keyvals, logging.ErrorKey(), cause.Error())
+	}
+	logging.Error(d.logger, emperror.Context(cause)...).Log(keyvals...)
+
+	if d.deadLetter == nil {
+		return
+	}
+	if err := d.deadLetter.Send(records, cause); err != nil {
+		logging.Error(d.logger, emperror.Context(err)...).Log(logging.MessageKey(),
+			"Failed to send records to the dead letter sink", "records", len(records),
+			logging.ErrorKey(), err.Error())
+	}
+}
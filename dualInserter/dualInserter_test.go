@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package dualInserter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/deadLetter"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+var testRecords = []db.Record{
+	{DeviceID: "a", Data: []byte("a")},
+	{DeviceID: "b", Data: []byte("b")},
+}
+
+func newDeadLetterSink(t *testing.T) *deadLetter.FileSink {
+	sink, err := deadLetter.NewFileSink(deadLetter.FileConfig{Dir: t.TempDir()}, nil, nil)
+	require.NoError(t, err)
+	return sink
+}
+
+// readDeadLetters replays the spool, returning the records in it.
+func readDeadLetters(t *testing.T, sink *deadLetter.FileSink) []db.Record {
+	inserter := new(recordingInserter)
+	_, err := sink.Replay(context.Background(), inserter)
+	require.NoError(t, err)
+	return inserter.records
+}
+
+func TestImplementsInterfaces(t *testing.T) {
+	var d interface{} = &DualInserter{}
+	_, ok := d.(db.Inserter)
+	assert.True(t, ok, "not an inserter")
+}
+
+func TestNewDualInserter(t *testing.T) {
+	assert := assert.New(t)
+	primary, secondary := new(mockInserter), new(mockInserter)
+
+	_, err := NewDualInserter(Config{}, nil, nil, nil, secondary)
+	assert.Equal(errNoPrimary, err)
+	_, err = NewDualInserter(Config{}, nil, nil, primary, nil)
+	assert.Equal(errNoSecondary, err)
+	_, err = NewDualInserter(Config{Policy: "sometimes"}, nil, nil, primary, secondary)
+	assert.Contains(err.Error(), errUnknownPolicy.Error())
+
+	d, err := NewDualInserter(Config{QueueSize: -1}, nil, nil, primary, secondary)
+	assert.NoError(err)
+	assert.Equal(Config{
+		Policy:           BestEffort,
+		QueueSize:        defaultQueueSize,
+		SecondaryWorkers: defaultSecondaryWorkers,
+	}, d.config)
+	assert.Equal(defaultLogger, d.logger)
+}
+
+func TestInsertRecords(t *testing.T) {
+	testErr := errors.New("test error")
+	tests := []struct {
+		description        string
+		policy             string
+		primaryErr         error
+		secondaryErr       error
+		expectSecondary    bool
+		expectedErr        bool
+		expectedDeadLetter []db.Record
+		expectedSuccess    map[string]float64
+		expectedFailure    map[string]float64
+	}{
+		{
+			description:     "Best Effort Success",
+			policy:          BestEffort,
+			expectSecondary: true,
+			expectedSuccess: map[string]float64{PrimaryStore: 2, SecondaryStore: 2},
+			expectedFailure: map[string]float64{PrimaryStore: 0, SecondaryStore: 0},
+		},
+		{
+			description:        "Best Effort Secondary Failure",
+			policy:             BestEffort,
+			secondaryErr:       testErr,
+			expectSecondary:    true,
+			expectedDeadLetter: testRecords,
+			expectedSuccess:    map[string]float64{PrimaryStore: 2, SecondaryStore: 0},
+			expectedFailure:    map[string]float64{PrimaryStore: 0, SecondaryStore: 2},
+		},
+		{
+			description:     "Primary Failure",
+			policy:          BestEffort,
+			primaryErr:      testErr,
+			expectedErr:     true,
+			expectedSuccess: map[string]float64{PrimaryStore: 0, SecondaryStore: 0},
+			expectedFailure: map[string]float64{PrimaryStore: 2, SecondaryStore: 0},
+		},
+		{
+			description:     "Require Both Success",
+			policy:          RequireBoth,
+			expectSecondary: true,
+			expectedSuccess: map[string]float64{PrimaryStore: 2, SecondaryStore: 2},
+			expectedFailure: map[string]float64{PrimaryStore: 0, SecondaryStore: 0},
+		},
+		{
+			description:     "Require Both Secondary Failure",
+			policy:          RequireBoth,
+			secondaryErr:    testErr,
+			expectSecondary: true,
+			expectedErr:     true,
+			expectedSuccess: map[string]float64{PrimaryStore: 2, SecondaryStore: 0},
+			expectedFailure: map[string]float64{PrimaryStore: 0, SecondaryStore: 2},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			primary, secondary := new(mockInserter), new(mockInserter)
+			primary.On("InsertRecords", testRecords).Return(tc.primaryErr).Once()
+			if tc.expectSecondary {
+				secondary.On("InsertRecords", testRecords).Return(tc.secondaryErr).Once()
+			}
+			p := xmetricstest.NewProvider(nil, Metrics)
+			sink := newDeadLetterSink(t)
+			d, err := NewDualInserter(Config{Policy: tc.policy}, nil, p, primary, secondary, WithDeadLetter(sink))
+			require.NoError(t, err)
+
+			d.Start()
+			err = d.InsertRecords(testRecords...)
+			d.Stop()
+			if tc.expectedErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+			assert.Equal(tc.expectedDeadLetter, readDeadLetters(t, sink))
+			for store, value := range tc.expectedSuccess {
+				p.Assert(t, DualInsertSuccessCounter, StoreLabel, store)(xmetricstest.Value(value))
+			}
+			for store, value := range tc.expectedFailure {
+				p.Assert(t, DualInsertFailureCounter, StoreLabel, store)(xmetricstest.Value(value))
+			}
+			p.Assert(t, SecondaryQueueDepth)(xmetricstest.Value(0))
+			primary.AssertExpectations(t)
+			secondary.AssertExpectations(t)
+		})
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	assert := assert.New(t)
+	primary, secondary := new(mockInserter), new(mockInserter)
+	primary.On("InsertRecords", testRecords).Return(nil)
+	secondary.On("InsertRecords", testRecords).Return(nil).Once()
+	p := xmetricstest.NewProvider(nil, Metrics)
+	sink := newDeadLetterSink(t)
+	d, err := NewDualInserter(Config{QueueSize: 1}, nil, p, primary, secondary, WithDeadLetter(sink))
+	require.NoError(t, err)
+
+	// without the workers started, the second batch finds the queue full.
+	assert.NoError(d.InsertRecords(testRecords...))
+	assert.NoError(d.InsertRecords(testRecords...))
+	p.Assert(t, SecondaryQueueDepth)(xmetricstest.Value(1))
+	p.Assert(t, DeadLetterCounter, ReasonLabel, QueueFullReason)(xmetricstest.Value(2))
+
+	d.Start()
+	d.Stop()
+	assert.NoError(d.InsertRecords(testRecords...))
+	p.Assert(t, DeadLetterCounter, ReasonLabel, StoppedReason)(xmetricstest.Value(2))
+	// the batches dropped for a full queue and after Stop are both kept.
+	assert.Equal(append(testRecords, testRecords...), readDeadLetters(t, sink))
+	secondary.AssertExpectations(t)
+}
@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package dualInserter
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics"
+)
+
+const (
+	DualInsertSuccessCounter = "dual_insert_success_count"
+	DualInsertFailureCounter = "dual_insert_failure_count"
+	SecondaryQueueDepth      = "secondary_queue_depth"
+	DeadLetterCounter        = "dead_letter_count"
+)
+
+const (
+	// StoreLabel is for labeling metrics with the store written to.
+	StoreLabel = "store"
+
+	PrimaryStore   = "primary"
+	SecondaryStore = "secondary"
+
+	// ReasonLabel is for labeling dead letters with why the records weren't
+	// written to the secondary.
+	ReasonLabel = "reason"
+
+	// FailedReason is when inserting into the secondary failed.
+	FailedReason = "failed"
+	// QueueFullReason is when the secondary's queue had no room.
+	QueueFullReason = "queue_full"
+	// StoppedReason is when the records came in after Stop was called.
+	StoppedReason = "stopped"
+)
+
+func Metrics() []xmetrics.Metric {
+	return []xmetrics.Metric{
+		{
+			Name:       DualInsertSuccessCounter,
+			Help:       "The total number of records inserted, by store",
+			Type:       "counter",
+			LabelNames: []string{StoreLabel},
+		},
+		{
+			Name:       DualInsertFailureCounter,
+			Help:       "The total number of records that failed to insert, by store",
+			Type:       "counter",
+			LabelNames: []string{StoreLabel},
+		},
+		{
+			Name: SecondaryQueueDepth,
+			Help: "The number of batches waiting to be inserted into the secondary",
+			Type: "gauge",
+		},
+		{
+			Name:       DeadLetterCounter,
+			Help:       "The total number of records sent to the dead letter sink instead of the secondary",
+			Type:       "counter",
+			LabelNames: []string{ReasonLabel},
+		},
+	}
+}
+
+type Measures struct {
+	Success        metrics.Counter
+	Failure        metrics.Counter
+	SecondaryQueue metrics.Gauge
+	DeadLetters    metrics.Counter
+}
+
+// NewMeasures constructs a Measures given a go-kit metrics Provider
+func NewMeasures(p provider.Provider) *Measures {
+	return &Measures{
+		Success:        p.NewCounter(DualInsertSuccessCounter),
+		Failure:        p.NewCounter(DualInsertFailureCounter),
+		SecondaryQueue: p.NewGauge(SecondaryQueueDepth),
+		DeadLetters:    p.NewCounter(DeadLetterCounter),
+	}
+}
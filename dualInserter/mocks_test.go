@@ -0,0 +1,28 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package dualInserter
+
+import (
+	"github.com/stretchr/testify/mock"
+	db "github.com/xmidt-org/codex-db"
+)
+
+type mockInserter struct {
+	mock.Mock
+}
+
+func (c *mockInserter) InsertRecords(records ...db.Record) error {
+	args := c.Called(records)
+	return args.Error(0)
+}
+
+// recordingInserter keeps the records inserted.
+type recordingInserter struct {
+	records []db.Record
+}
+
+func (r *recordingInserter) InsertRecords(records ...db.Record) error {
+	r.records = append(r.records, records...)
+	return nil
+}
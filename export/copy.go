@@ -0,0 +1,279 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package export
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+
+	"github.com/goph/emperror"
+	db "github.com/xmidt-org/codex-db"
+)
+
+const (
+	defaultWorkers = 4
+)
+
+// CopyConfig configures copying records from one database to another.
+type CopyConfig struct {
+	// Workers is how many devices are copied at once.
+	Workers int
+
+	// PageSize is how many devices are listed at a time.
+	PageSize int
+
+	// MaxRecords is the most records copied for one device.  Records are
+	// read newest first, so a device with more loses its oldest records.
+	MaxRecords int
+
+	// BatchSize is how many records are given to the inserter at a time.
+	BatchSize int
+
+	// CheckpointFile is where progress is saved after each batch.  If the
+	// file exists when copying, the copy resumes from it.  It is removed once
+	// the copy is done.  Leave empty to not checkpoint.
+	CheckpointFile string
+}
+
+// CopyProgress is how far a copy got.  It is also what is saved in the
+// checkpoint file.
+type CopyProgress struct {
+	// Offset is the page of devices being copied, Done lists the page's
+	// devices that have been copied, and Partial marks the last record
+	// copied of the page's devices that were being copied.
+	Offset  string                `json:"offset"`
+	Done    []string              `json:"done"`
+	Partial map[string]RecordMark `json:"partial,omitempty"`
+
+	// Truncated counts the devices that hit MaxRecords, which may have had
+	// older records that weren't copied.
+	Devices   int64 `json:"devices"`
+	Records   int64 `json:"records"`
+	Truncated int64 `json:"truncated"`
+}
+
+// RecordMark identifies a record by its birth date and a hash of its
+// contents, which unlike its row id are the same in both databases.
+type RecordMark struct {
+	BirthDate int64  `json:"birthDate"`
+	Hash      string `json:"hash"`
+}
+
+func markRecord(r db.Record) RecordMark {
+	return RecordMark{BirthDate: r.BirthDate, Hash: hex.EncodeToString(hashRecord(r))}
+}
+
+// after returns the records, read newest first, that come after the marked
+// one.  If the marked record is gone, such as by expiring, the records born
+// before it are returned.
+func (m RecordMark) after(records []db.Record) []db.Record {
+	for i, r := range records {
+		if r.BirthDate == m.BirthDate && markRecord(r) == m {
+			return records[i+1:]
+		}
+	}
+	for i, r := range records {
+		if r.BirthDate < m.BirthDate {
+			return records[i:]
+		}
+	}
+	return nil
+}
+
+// Copier copies the records of every device a DeviceLister lists from one
+// database to another, a device at a time.
+type Copier struct {
+	config CopyConfig
+	source db.RecordGetter
+	lister DeviceLister
+	dest   db.Inserter
+}
+
+// NewCopier creates a Copier reading records with source, for the devices
+// listed by lister, and inserting them with dest.
+func NewCopier(config CopyConfig, source db.RecordGetter, lister DeviceLister, dest db.Inserter) (*Copier, error) {
+	if source == nil {
+		return nil, errNilGetter
+	}
+	if lister == nil {
+		return nil, errNilLister
+	}
+	if dest == nil {
+		return nil, errNilInserter
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = defaultPageSize
+	}
+	if config.MaxRecords <= 0 {
+		config.MaxRecords = defaultMaxRecords
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	return &Copier{
+		config: config,
+		source: source,
+		lister: lister,
+		dest:   dest,
+	}, nil
+}
+
+// Copy copies the records, resuming from the checkpoint file if there is
+// one.  It stops at the first device that fails, or when ctx is cancelled,
+// once the devices being copied are done.  A device that was partly copied
+// is resumed after the last batch that was inserted, so no records are
+// inserted twice; records added to the source while copying may not be
+// copied.
+func (c *Copier) Copy(ctx context.Context) (CopyProgress, error) {
+	var progress CopyProgress
+	if _, err := loadCheckpoint(c.config.CheckpointFile, &progress); err != nil {
+		return progress, emperror.WrapWith(err, "Loading checkpoint failed", "file", c.config.CheckpointFile)
+	}
+
+	for {
+		devices, next, err := c.lister.ListDevices(progress.Offset, c.config.PageSize)
+		if err != nil {
+			return progress, emperror.WrapWith(err, "Listing devices failed", "offset", progress.Offset)
+		}
+		if err = c.copyPage(ctx, devices, &progress); err != nil {
+			return progress, err
+		}
+		if next == "" {
+			break
+		}
+		progress.Offset = next
+		progress.Done = nil
+		progress.Partial = nil
+		if err = saveCheckpoint(c.config.CheckpointFile, progress); err != nil {
+			return progress, emperror.WrapWith(err, "Saving checkpoint failed", "file", c.config.CheckpointFile)
+		}
+	}
+
+	if err := removeCheckpoint(c.config.CheckpointFile); err != nil {
+		return progress, emperror.WrapWith(err, "Removing checkpoint failed", "file", c.config.CheckpointFile)
+	}
+	return progress, nil
+}
+
+// copyPage copies the page's devices that aren't done yet with the workers,
+// saving a checkpoint as each finishes.
+func (c *Copier) copyPage(ctx context.Context, devices []string, progress *CopyProgress) error {
+	done := make(map[string]bool, len(progress.Done))
+	for _, d := range progress.Done {
+		done[d] = true
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		lock     sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+		jobs     = make(chan string)
+	)
+	fail := func(err error) {
+		lock.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		lock.Unlock()
+		cancel()
+	}
+
+	for i := 0; i < c.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for deviceID := range jobs {
+				lock.Lock()
+				mark, partial := progress.Partial[deviceID]
+				lock.Unlock()
+				var after *RecordMark
+				if partial {
+					after = &mark
+				}
+				save := func(mark RecordMark) error {
+					lock.Lock()
+					defer lock.Unlock()
+					if progress.Partial == nil {
+						progress.Partial = make(map[string]RecordMark)
+					}
+					progress.Partial[deviceID] = mark
+					return saveCheckpoint(c.config.CheckpointFile, progress)
+				}
+				read, err := c.copyDevice(deviceID, after, save)
+				if err != nil {
+					fail(emperror.WrapWith(err, "Copying device failed", "device id", deviceID))
+					continue
+				}
+				lock.Lock()
+				delete(progress.Partial, deviceID)
+				progress.Done = append(progress.Done, deviceID)
+				progress.Devices++
+				progress.Records += int64(read)
+				if read >= c.config.MaxRecords {
+					progress.Truncated++
+				}
+				err = saveCheckpoint(c.config.CheckpointFile, progress)
+				lock.Unlock()
+				if err != nil {
+					fail(emperror.WrapWith(err, "Saving checkpoint failed", "file", c.config.CheckpointFile))
+				}
+			}
+		}()
+	}
+
+send:
+	for _, deviceID := range devices {
+		if done[deviceID] {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			break send
+		case jobs <- deviceID:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// copyDevice copies the device's records in batches, starting after the
+// record marked by after if it is set, and saving the mark of the last
+// record of each batch but the final one.  It returns how many records were
+// read.
+func (c *Copier) copyDevice(deviceID string, after *RecordMark, save func(RecordMark) error) (int, error) {
+	records, err := c.source.GetRecords(deviceID, c.config.MaxRecords, "")
+	if err != nil {
+		return 0, err
+	}
+	remaining := records
+	if after != nil {
+		remaining = after.after(records)
+	}
+	for start := 0; start < len(remaining); start += c.config.BatchSize {
+		end := start + c.config.BatchSize
+		if end > len(remaining) {
+			end = len(remaining)
+		}
+		if err = c.dest.InsertRecords(remaining[start:end]...); err != nil {
+			return 0, err
+		}
+		if end < len(remaining) {
+			if err = save(markRecord(remaining[end-1])); err != nil {
+				return 0, emperror.WrapWith(err, "Saving checkpoint failed", "file", c.config.CheckpointFile)
+			}
+		}
+	}
+	return len(records), nil
+}
@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package export
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+)
+
+func TestCopy(t *testing.T) {
+	assert := assert.New(t)
+	recordsA := []db.Record{{DeviceID: "a", BirthDate: 1}, {DeviceID: "a", BirthDate: 2}, {DeviceID: "a", BirthDate: 3}}
+	recordsB := []db.Record{{DeviceID: "b", BirthDate: 1}}
+	source := new(mockGetter)
+	source.On("GetRecords", "a", 3, "").Return(recordsA, nil).Once()
+	source.On("GetRecords", "b", 3, "").Return(recordsB, nil).Once()
+	dest := new(mockInserter)
+	dest.On("InsertRecords", recordsA[:2]).Return(nil).Once()
+	dest.On("InsertRecords", recordsA[2:]).Return(nil).Once()
+	dest.On("InsertRecords", recordsB).Return(nil).Once()
+
+	config := CopyConfig{
+		Workers:        2,
+		MaxRecords:     3,
+		BatchSize:      2,
+		CheckpointFile: filepath.Join(t.TempDir(), "checkpoint"),
+	}
+	copier, err := NewCopier(config, source, Devices{"a", "b"}, dest)
+	require.NoError(t, err)
+	progress, err := copier.Copy(context.Background())
+	assert.NoError(err)
+	assert.ElementsMatch([]string{"a", "b"}, progress.Done)
+	assert.Equal(int64(2), progress.Devices)
+	assert.Equal(int64(4), progress.Records)
+	assert.Equal(int64(1), progress.Truncated)
+	assert.NoFileExists(config.CheckpointFile)
+	source.AssertExpectations(t)
+	dest.AssertExpectations(t)
+}
+
+func TestCopyResume(t *testing.T) {
+	assert := assert.New(t)
+	records := map[string][]db.Record{
+		"a": {{DeviceID: "a"}},
+		"b": {{DeviceID: "b"}},
+		"c": {{DeviceID: "c"}},
+	}
+	source := new(mockGetter)
+	for id, r := range records {
+		source.On("GetRecords", id, mock.Anything, "").Return(r, nil).Once()
+	}
+	lister := new(mockPostgresDevices)
+	lister.On("GetDeviceList", "", 2).Return([]string{"a", "b"}, nil)
+	lister.On("GetDeviceList", "b", 2).Return([]string{"c"}, nil)
+	dest := new(mockInserter)
+	dest.On("InsertRecords", records["a"]).Return(nil).Once()
+	dest.On("InsertRecords", records["b"]).Return(errors.New("test error")).Once()
+
+	config := CopyConfig{
+		Workers:        1,
+		PageSize:       2,
+		CheckpointFile: filepath.Join(t.TempDir(), "checkpoint"),
+	}
+	copier, err := NewCopier(config, source, PostgresDevices(lister), dest)
+	require.NoError(t, err)
+	progress, err := copier.Copy(context.Background())
+	assert.Contains(err.Error(), "Copying device failed")
+	assert.Equal([]string{"a"}, progress.Done)
+	assert.FileExists(config.CheckpointFile)
+
+	// a was copied, so only b and c are copied when resuming.
+	source.On("GetRecords", "b", mock.Anything, "").Return(records["b"], nil).Once()
+	dest.On("InsertRecords", records["b"]).Return(nil).Once()
+	dest.On("InsertRecords", records["c"]).Return(nil).Once()
+	progress, err = copier.Copy(context.Background())
+	assert.NoError(err)
+	assert.Equal(int64(3), progress.Devices)
+	assert.Equal("b", progress.Offset)
+	assert.NoFileExists(config.CheckpointFile)
+	source.AssertExpectations(t)
+	dest.AssertExpectations(t)
+}
+
+func TestCopyResumePartialDevice(t *testing.T) {
+	assert := assert.New(t)
+	records := []db.Record{
+		{DeviceID: "a", BirthDate: 5},
+		{DeviceID: "a", BirthDate: 4},
+		{DeviceID: "a", BirthDate: 3},
+		{DeviceID: "a", BirthDate: 2},
+		{DeviceID: "a", BirthDate: 1},
+	}
+	source := new(mockGetter)
+	source.On("GetRecords", "a", mock.Anything, "").Return(records, nil).Twice()
+	dest := new(mockInserter)
+	dest.On("InsertRecords", records[0:2]).Return(nil).Once()
+	dest.On("InsertRecords", records[2:4]).Return(errors.New("test error")).Once()
+
+	config := CopyConfig{
+		Workers:        1,
+		BatchSize:      2,
+		CheckpointFile: filepath.Join(t.TempDir(), "checkpoint"),
+	}
+	copier, err := NewCopier(config, source, Devices{"a"}, dest)
+	require.NoError(t, err)
+	progress, err := copier.Copy(context.Background())
+	assert.Contains(err.Error(), "Copying device failed")
+	assert.Empty(progress.Done)
+	var saved CopyProgress
+	_, err = loadCheckpoint(config.CheckpointFile, &saved)
+	require.NoError(t, err)
+	assert.Equal(map[string]RecordMark{"a": markRecord(records[1])}, saved.Partial)
+
+	// the first batch isn't inserted again.
+	dest.On("InsertRecords", records[2:4]).Return(nil).Once()
+	dest.On("InsertRecords", records[4:]).Return(nil).Once()
+	progress, err = copier.Copy(context.Background())
+	assert.NoError(err)
+	assert.Equal([]string{"a"}, progress.Done)
+	assert.Empty(progress.Partial)
+	assert.Equal(int64(5), progress.Records)
+	assert.NoFileExists(config.CheckpointFile)
+	source.AssertExpectations(t)
+	dest.AssertExpectations(t)
+}
+
+func TestRecordMarkAfter(t *testing.T) {
+	records := []db.Record{
+		{DeviceID: "a", BirthDate: 3, Data: []byte("c")},
+		{DeviceID: "a", BirthDate: 2, Data: []byte("b")},
+		{DeviceID: "a", BirthDate: 2, Data: []byte("a")},
+		{DeviceID: "a", BirthDate: 1},
+	}
+	tests := []struct {
+		description     string
+		mark            RecordMark
+		expectedRecords []db.Record
+	}{
+		{
+			description:     "Found",
+			mark:            markRecord(records[1]),
+			expectedRecords: records[2:],
+		},
+		{
+			description:     "Last",
+			mark:            markRecord(records[3]),
+			expectedRecords: []db.Record{},
+		},
+		{
+			description:     "Gone",
+			mark:            markRecord(db.Record{DeviceID: "a", BirthDate: 2, Data: []byte("x")}),
+			expectedRecords: records[3:],
+		},
+		{
+			description: "Gone With Nothing Older",
+			mark:        markRecord(db.Record{DeviceID: "a", BirthDate: 0}),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert.Equal(t, tc.expectedRecords, tc.mark.after(records))
+		})
+	}
+}
+
+func TestCopyCancelled(t *testing.T) {
+	assert := assert.New(t)
+	copier, err := NewCopier(CopyConfig{}, new(mockGetter), Devices{"a"}, new(mockInserter))
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	progress, err := copier.Copy(ctx)
+	assert.Equal(context.Canceled, err)
+	assert.Empty(progress.Done)
+}
+
+func TestNewCopier(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewCopier(CopyConfig{}, nil, Devices{}, new(mockInserter))
+	assert.Equal(errNilGetter, err)
+	_, err = NewCopier(CopyConfig{}, new(mockGetter), nil, new(mockInserter))
+	assert.Equal(errNilLister, err)
+	_, err = NewCopier(CopyConfig{}, new(mockGetter), Devices{}, nil)
+	assert.Equal(errNilInserter, err)
+
+	copier, err := NewCopier(CopyConfig{}, new(mockGetter), Devices{}, new(mockInserter))
+	assert.NoError(err)
+	assert.Equal(CopyConfig{
+		Workers:    defaultWorkers,
+		PageSize:   defaultPageSize,
+		MaxRecords: defaultMaxRecords,
+		BatchSize:  defaultBatchSize,
+	}, copier.config)
+}
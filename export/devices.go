@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package export
+
+import (
+	"strconv"
+	"time"
+)
+
+// DeviceLister pages through the devices to export.
+type DeviceLister interface {
+	// ListDevices returns up to limit devices starting at offset, along with
+	// the offset of the next page.  An empty next offset means there are no
+	// more pages.
+	ListDevices(offset string, limit int) (devices []string, next string, err error)
+}
+
+// Devices lists the devices given, in order, as a single page.
+type Devices []string
+
+// ListDevices returns all of the devices.
+func (d Devices) ListDevices(string, int) ([]string, string, error) {
+	return d, "", nil
+}
+
+type postgresDeviceGetter interface {
+	GetDeviceList(offset string, limit int) ([]string, error)
+}
+
+type postgresDevices struct {
+	getter postgresDeviceGetter
+}
+
+// PostgresDevices lists devices from the postgres driver, which pages by the
+// last device id seen.
+func PostgresDevices(getter postgresDeviceGetter) DeviceLister {
+	return postgresDevices{getter: getter}
+}
+
+func (p postgresDevices) ListDevices(offset string, limit int) ([]string, string, error) {
+	devices, err := p.getter.GetDeviceList(offset, limit)
+	if err != nil || len(devices) < limit {
+		return devices, "", err
+	}
+	return devices, devices[len(devices)-1], nil
+}
+
+type cassandraDeviceGetter interface {
+	GetDeviceList(startDate time.Time, endDate time.Time, offset int, limit int) ([]string, error)
+}
+
+type cassandraDevices struct {
+	getter cassandraDeviceGetter
+	start  time.Time
+	end    time.Time
+}
+
+// CassandraDevices lists devices from the cassandra driver with records born
+// between start and end, paging by the number of devices already seen.
+func CassandraDevices(getter cassandraDeviceGetter, start time.Time, end time.Time) DeviceLister {
+	return cassandraDevices{getter: getter, start: start, end: end}
+}
+
+func (c cassandraDevices) ListDevices(offset string, limit int) ([]string, string, error) {
+	n := 0
+	if offset != "" {
+		var err error
+		if n, err = strconv.Atoi(offset); err != nil {
+			return nil, "", err
+		}
+	}
+	devices, err := c.getter.GetDeviceList(c.start, c.end, n, limit)
+	if err != nil || len(devices) < limit {
+		return devices, "", err
+	}
+	return devices, strconv.Itoa(n + len(devices)), nil
+}
@@ -0,0 +1,289 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package export copies records out of a database to newline-delimited JSON,
+// one db.Record per line, and imports them back in through any db.Inserter.
+// It can also copy records straight from one database to another and verify
+// that the two hold the same records.  Exports, imports, and copies can save
+// checkpoints as they go, so that an interrupted run can be resumed where it
+// stopped.
+package export
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/goph/emperror"
+	db "github.com/xmidt-org/codex-db"
+)
+
+const (
+	defaultPageSize           = 100
+	defaultMaxRecords         = 100000
+	defaultCheckpointInterval = 100
+)
+
+var (
+	errNilGetter   = errors.New("no record getter given")
+	errNilLister   = errors.New("no device lister given")
+	errNilInserter = errors.New("no inserter given")
+)
+
+// ExportConfig configures what is exported and how.
+type ExportConfig struct {
+	// PageSize is how many devices are listed at a time.
+	PageSize int
+
+	// MaxRecords is the most records exported for one device.  Records are
+	// read newest first, so a device with more loses its oldest records.
+	MaxRecords int
+
+	// Start and End limit the export to records born in that window.  A zero
+	// time leaves that end of the window open.
+	Start time.Time
+	End   time.Time
+
+	// Gzip compresses the output.
+	Gzip bool
+
+	// CheckpointFile is where progress is saved.  If the file exists when
+	// exporting, the export resumes from it.  It is removed once the export
+	// is done.  Leave empty to not checkpoint.
+	CheckpointFile string
+
+	// CheckpointInterval is how many devices are exported between checkpoints.
+	CheckpointInterval int
+}
+
+// ExportProgress is how far an export got.  It is also what is saved in the
+// checkpoint file.
+type ExportProgress struct {
+	// Offset is the page of devices being exported, and Done is how many of
+	// the page's devices have been exported.
+	Offset string `json:"offset"`
+	Done   int    `json:"done"`
+
+	// Bytes is how much of the output file holds the exported devices.
+	Bytes int64 `json:"bytes"`
+
+	// Records counts the records written.  Truncated counts the devices
+	// that hit MaxRecords, which may have had older records that weren't
+	// exported.
+	Devices   int64 `json:"devices"`
+	Records   int64 `json:"records"`
+	Truncated int64 `json:"truncated"`
+}
+
+// Exporter writes the records of every device a DeviceLister lists to a file.
+type Exporter struct {
+	config ExportConfig
+	getter db.RecordGetter
+	lister DeviceLister
+}
+
+// NewExporter creates an Exporter reading records with getter, for the
+// devices listed by lister.
+func NewExporter(config ExportConfig, getter db.RecordGetter, lister DeviceLister) (*Exporter, error) {
+	if getter == nil {
+		return nil, errNilGetter
+	}
+	if lister == nil {
+		return nil, errNilLister
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = defaultPageSize
+	}
+	if config.MaxRecords <= 0 {
+		config.MaxRecords = defaultMaxRecords
+	}
+	if config.CheckpointInterval <= 0 {
+		config.CheckpointInterval = defaultCheckpointInterval
+	}
+	return &Exporter{
+		config: config,
+		getter: getter,
+		lister: lister,
+	}, nil
+}
+
+// Export writes the records to the file at path, resuming from the
+// checkpoint file if there is one.  If ctx is cancelled, the export stops
+// after the device it is on and saves a checkpoint.
+func (e *Exporter) Export(ctx context.Context, path string) (ExportProgress, error) {
+	var progress ExportProgress
+	resumed, err := loadCheckpoint(e.config.CheckpointFile, &progress)
+	if err != nil {
+		return progress, emperror.WrapWith(err, "Loading checkpoint failed", "file", e.config.CheckpointFile)
+	}
+	flags := os.O_WRONLY | os.O_CREATE
+	if !resumed {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return progress, emperror.WrapWith(err, "Opening export file failed", "file", path)
+	}
+	defer f.Close()
+	if resumed {
+		// drop anything written after the checkpoint was saved.
+		if err = f.Truncate(progress.Bytes); err != nil {
+			return progress, emperror.WrapWith(err, "Truncating export file failed", "file", path)
+		}
+		if _, err = f.Seek(progress.Bytes, io.SeekStart); err != nil {
+			return progress, emperror.WrapWith(err, "Seeking export file failed", "file", path)
+		}
+	}
+
+	w := newRecordWriter(f, progress.Bytes, e.config.Gzip)
+	committed := progress
+	checkpoint := func() error {
+		bytes, err := w.commit()
+		if err != nil {
+			return emperror.WrapWith(err, "Writing export file failed", "file", path)
+		}
+		if err = f.Sync(); err != nil {
+			return emperror.WrapWith(err, "Syncing export file failed", "file", path)
+		}
+		progress.Bytes = bytes
+		if err = saveCheckpoint(e.config.CheckpointFile, progress); err != nil {
+			return emperror.WrapWith(err, "Saving checkpoint failed", "file", e.config.CheckpointFile)
+		}
+		committed = progress
+		return nil
+	}
+
+	sinceCheckpoint := 0
+	for {
+		devices, next, err := e.lister.ListDevices(progress.Offset, e.config.PageSize)
+		if err != nil {
+			return committed, emperror.WrapWith(err, "Listing devices failed", "offset", progress.Offset)
+		}
+		for progress.Done < len(devices) {
+			if ctx.Err() != nil {
+				if err = checkpoint(); err != nil {
+					return committed, err
+				}
+				return committed, ctx.Err()
+			}
+			read, written, err := e.exportDevice(w, devices[progress.Done])
+			if err != nil {
+				// the device may be partly written, so resuming must start
+				// from the last checkpoint.
+				return committed, emperror.WrapWith(err, "Exporting device failed", "device id", devices[progress.Done])
+			}
+			progress.Done++
+			progress.Devices++
+			progress.Records += int64(written)
+			if read >= e.config.MaxRecords {
+				progress.Truncated++
+			}
+			sinceCheckpoint++
+			if sinceCheckpoint >= e.config.CheckpointInterval {
+				if err = checkpoint(); err != nil {
+					return committed, err
+				}
+				sinceCheckpoint = 0
+			}
+		}
+		if next == "" {
+			break
+		}
+		progress.Offset = next
+		progress.Done = 0
+	}
+
+	if err = checkpoint(); err != nil {
+		return committed, err
+	}
+	if err = removeCheckpoint(e.config.CheckpointFile); err != nil {
+		return committed, emperror.WrapWith(err, "Removing checkpoint failed", "file", e.config.CheckpointFile)
+	}
+	return committed, nil
+}
+
+// exportDevice writes the device's records born in the window, returning
+// how many records were read and how many were written.
+func (e *Exporter) exportDevice(w *recordWriter, deviceID string) (int, int, error) {
+	records, err := e.getter.GetRecords(deviceID, e.config.MaxRecords, "")
+	if err != nil {
+		return 0, 0, err
+	}
+	written := 0
+	for _, r := range records {
+		if !e.config.Start.IsZero() && r.BirthDate < e.config.Start.UnixNano() {
+			continue
+		}
+		if !e.config.End.IsZero() && r.BirthDate > e.config.End.UnixNano() {
+			continue
+		}
+		if err = w.write(r); err != nil {
+			return 0, 0, err
+		}
+		written++
+	}
+	return len(records), written, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// recordWriter encodes records one per line, optionally compressed.
+type recordWriter struct {
+	out     *countingWriter
+	gz      *gzip.Writer
+	buf     *bufio.Writer
+	encoder *json.Encoder
+	dirty   bool
+}
+
+// newRecordWriter writes records to w, which already holds offset bytes.
+func newRecordWriter(w io.Writer, offset int64, compress bool) *recordWriter {
+	r := &recordWriter{out: &countingWriter{w: w, n: offset}}
+	var dest io.Writer = r.out
+	if compress {
+		r.gz = gzip.NewWriter(r.out)
+		dest = r.gz
+	}
+	r.buf = bufio.NewWriter(dest)
+	r.encoder = json.NewEncoder(r.buf)
+	return r
+}
+
+func (r *recordWriter) write(record db.Record) error {
+	r.dirty = true
+	return r.encoder.Encode(record)
+}
+
+// commit flushes everything written so far, returning the size of the
+// output.  Compressed output ends a gzip member, so the output can be cut
+// here and appended to later.
+func (r *recordWriter) commit() (int64, error) {
+	if !r.dirty {
+		return r.out.n, nil
+	}
+	if err := r.buf.Flush(); err != nil {
+		return 0, err
+	}
+	if r.gz != nil {
+		if err := r.gz.Close(); err != nil {
+			return 0, err
+		}
+		r.gz.Reset(r.out)
+	}
+	r.dirty = false
+	return r.out.n, nil
+}
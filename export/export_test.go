@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package export
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+)
+
+func readRecords(t *testing.T, path string, compressed bool) []db.Record {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var r io.Reader = f
+	if compressed {
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		r = gz
+	}
+	var records []db.Record
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var record db.Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestExport(t *testing.T) {
+	recordsA := []db.Record{
+		{DeviceID: "a", BirthDate: 3000, Data: []byte("a3")},
+		{DeviceID: "a", BirthDate: 1000, Data: []byte("a1")},
+	}
+	recordsB := []db.Record{
+		{DeviceID: "b", BirthDate: 2000, Data: []byte("b2")},
+	}
+	tests := []struct {
+		description      string
+		config           ExportConfig
+		expectedRecords  []db.Record
+		expectedProgress ExportProgress
+	}{
+		{
+			description:      "Plain",
+			expectedRecords:  append(append([]db.Record{}, recordsA...), recordsB...),
+			expectedProgress: ExportProgress{Done: 2, Devices: 2, Records: 3},
+		},
+		{
+			description:      "Gzip",
+			config:           ExportConfig{Gzip: true, CheckpointInterval: 1},
+			expectedRecords:  append(append([]db.Record{}, recordsA...), recordsB...),
+			expectedProgress: ExportProgress{Done: 2, Devices: 2, Records: 3},
+		},
+		{
+			description:      "Window",
+			config:           ExportConfig{Start: time.Unix(0, 1500), End: time.Unix(0, 2500)},
+			expectedRecords:  recordsB,
+			expectedProgress: ExportProgress{Done: 2, Devices: 2, Records: 1},
+		},
+		{
+			description:      "Truncated",
+			config:           ExportConfig{MaxRecords: 2},
+			expectedRecords:  append(append([]db.Record{}, recordsA...), recordsB...),
+			expectedProgress: ExportProgress{Done: 2, Devices: 2, Records: 3, Truncated: 1},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			dir := t.TempDir()
+			getter := new(mockGetter)
+			getter.On("GetRecords", "a", mock.Anything, "").Return(recordsA, nil).Once()
+			getter.On("GetRecords", "b", mock.Anything, "").Return(recordsB, nil).Once()
+
+			tc.config.CheckpointFile = filepath.Join(dir, "checkpoint")
+			exporter, err := NewExporter(tc.config, getter, Devices{"a", "b"})
+			require.NoError(t, err)
+			path := filepath.Join(dir, "export.jsonl")
+			progress, err := exporter.Export(context.Background(), path)
+			assert.NoError(err)
+
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			tc.expectedProgress.Bytes = info.Size()
+			assert.Equal(tc.expectedProgress, progress)
+			assert.Equal(tc.expectedRecords, readRecords(t, path, tc.config.Gzip))
+			assert.NoFileExists(tc.config.CheckpointFile)
+			getter.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExportResume(t *testing.T) {
+	for _, compressed := range []bool{false, true} {
+		assert := assert.New(t)
+		dir := t.TempDir()
+		config := ExportConfig{
+			Gzip:               compressed,
+			CheckpointFile:     filepath.Join(dir, "checkpoint"),
+			CheckpointInterval: 1,
+			PageSize:           2,
+		}
+		records := map[string][]db.Record{
+			"a": {{DeviceID: "a", Data: []byte("a")}},
+			"b": {{DeviceID: "b", Data: []byte("b")}},
+			"c": {{DeviceID: "c", Data: []byte("c")}},
+		}
+		getter := new(mockGetter)
+		lister := new(mockPostgresDevices)
+		lister.On("GetDeviceList", "", 2).Return([]string{"a", "b"}, nil)
+		lister.On("GetDeviceList", "b", 2).Return([]string{"c"}, nil)
+		getter.On("GetRecords", "a", mock.Anything, "").Return(records["a"], nil).Once()
+		getter.On("GetRecords", "b", mock.Anything, "").Return(records["b"], nil).Once()
+		getter.On("GetRecords", "c", mock.Anything, "").Return([]db.Record{}, errors.New("test error")).Once()
+
+		exporter, err := NewExporter(config, getter, PostgresDevices(lister))
+		require.NoError(t, err)
+		path := filepath.Join(dir, "export.jsonl")
+		progress, err := exporter.Export(context.Background(), path)
+		assert.Error(err)
+		assert.Equal(int64(2), progress.Devices)
+		assert.FileExists(config.CheckpointFile)
+
+		// something written after the checkpoint is dropped when resuming.
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+		require.NoError(t, err)
+		_, err = f.WriteString("partial")
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		getter.On("GetRecords", "c", mock.Anything, "").Return(records["c"], nil).Once()
+		progress, err = exporter.Export(context.Background(), path)
+		assert.NoError(err)
+		assert.Equal(int64(3), progress.Devices)
+		assert.Equal(int64(3), progress.Records)
+		assert.Equal([]db.Record{records["a"][0], records["b"][0], records["c"][0]}, readRecords(t, path, compressed))
+		assert.NoFileExists(config.CheckpointFile)
+		getter.AssertExpectations(t)
+	}
+}
+
+func TestExportCancelled(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	config := ExportConfig{CheckpointFile: filepath.Join(dir, "checkpoint")}
+	exporter, err := NewExporter(config, new(mockGetter), Devices{"a"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	progress, err := exporter.Export(ctx, filepath.Join(dir, "export.jsonl"))
+	assert.Equal(context.Canceled, err)
+	assert.Equal(ExportProgress{}, progress)
+
+	var saved ExportProgress
+	found, err := loadCheckpoint(config.CheckpointFile, &saved)
+	assert.True(found)
+	assert.NoError(err)
+	assert.Equal(progress, saved)
+}
+
+func TestNewExporter(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewExporter(ExportConfig{}, nil, Devices{})
+	assert.Equal(errNilGetter, err)
+	_, err = NewExporter(ExportConfig{}, new(mockGetter), nil)
+	assert.Equal(errNilLister, err)
+
+	exporter, err := NewExporter(ExportConfig{}, new(mockGetter), Devices{})
+	assert.NoError(err)
+	assert.Equal(ExportConfig{
+		PageSize:           defaultPageSize,
+		MaxRecords:         defaultMaxRecords,
+		CheckpointInterval: defaultCheckpointInterval,
+	}, exporter.config)
+}
+
+func TestCassandraDevices(t *testing.T) {
+	assert := assert.New(t)
+	start, end := time.Unix(10, 0), time.Unix(20, 0)
+	getter := new(mockCassandraDevices)
+	getter.On("GetDeviceList", start, end, 0, 2).Return([]string{"a", "b"}, nil).Once()
+	getter.On("GetDeviceList", start, end, 2, 2).Return([]string{"c"}, nil).Once()
+
+	lister := CassandraDevices(getter, start, end)
+	devices, next, err := lister.ListDevices("", 2)
+	assert.NoError(err)
+	assert.Equal([]string{"a", "b"}, devices)
+	assert.Equal("2", next)
+	devices, next, err = lister.ListDevices(next, 2)
+	assert.NoError(err)
+	assert.Equal([]string{"c"}, devices)
+	assert.Empty(next)
+	_, _, err = lister.ListDevices("x", 2)
+	assert.Error(err)
+	getter.AssertExpectations(t)
+}
@@ -0,0 +1,188 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/goph/emperror"
+	db "github.com/xmidt-org/codex-db"
+)
+
+const (
+	defaultBatchSize = 100
+	maxLineSize      = 64 * 1024 * 1024
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// ImportConfig configures how records are imported.
+type ImportConfig struct {
+	// BatchSize is how many records are given to the inserter at a time.
+	BatchSize int
+
+	// RecordsPerSecond limits how fast records are inserted.  Zero is no limit.
+	RecordsPerSecond float64
+
+	// CheckpointFile is where progress is saved.  If the file exists when
+	// importing, the lines it counts as imported are skipped.  It is removed
+	// once the import is done.  Leave empty to not checkpoint.
+	CheckpointFile string
+}
+
+// ImportProgress is how far an import got.  It is also what is saved in the
+// checkpoint file.
+type ImportProgress struct {
+	// Lines is how many lines of the input have been imported.
+	Lines   int64 `json:"lines"`
+	Records int64 `json:"records"`
+}
+
+// Importer inserts records read from newline-delimited JSON.
+type Importer struct {
+	config   ImportConfig
+	inserter db.Inserter
+}
+
+// NewImporter creates an Importer inserting records with inserter.
+func NewImporter(config ImportConfig, inserter db.Inserter) (*Importer, error) {
+	if inserter == nil {
+		return nil, errNilInserter
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.RecordsPerSecond < 0 {
+		config.RecordsPerSecond = 0
+	}
+	return &Importer{
+		config:   config,
+		inserter: inserter,
+	}, nil
+}
+
+// Import inserts the records read from r, which may be gzip compressed,
+// resuming from the checkpoint file if there is one.  If ctx is cancelled,
+// the import stops after the batch it is on.
+func (i *Importer) Import(ctx context.Context, r io.Reader) (ImportProgress, error) {
+	var progress ImportProgress
+	if _, err := loadCheckpoint(i.config.CheckpointFile, &progress); err != nil {
+		return progress, emperror.WrapWith(err, "Loading checkpoint failed", "file", i.config.CheckpointFile)
+	}
+
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return progress, emperror.Wrap(err, "Reading gzip header failed")
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	limiter := newRateLimiter(i.config.RecordsPerSecond)
+	var (
+		line  int64
+		batch = make([]db.Record, 0, i.config.BatchSize)
+	)
+	insert := func() error {
+		if len(batch) == 0 {
+			progress.Lines = line
+			return nil
+		}
+		if err := limiter.wait(ctx, len(batch)); err != nil {
+			return err
+		}
+		if err := i.inserter.InsertRecords(batch...); err != nil {
+			return emperror.WrapWith(err, "Inserting records failed", "line", line)
+		}
+		progress.Lines = line
+		progress.Records += int64(len(batch))
+		batch = batch[:0]
+		if err := saveCheckpoint(i.config.CheckpointFile, progress); err != nil {
+			return emperror.WrapWith(err, "Saving checkpoint failed", "file", i.config.CheckpointFile)
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		line++
+		if line <= progress.Lines {
+			continue
+		}
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var record db.Record
+		if err := json.Unmarshal(text, &record); err != nil {
+			return progress, emperror.WrapWith(err, "Decoding record failed", "line", line)
+		}
+		batch = append(batch, record)
+		if len(batch) < i.config.BatchSize {
+			continue
+		}
+		if err := insert(); err != nil {
+			return progress, err
+		}
+		if ctx.Err() != nil {
+			return progress, ctx.Err()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return progress, emperror.WrapWith(err, "Reading records failed", "line", line)
+	}
+	if err := insert(); err != nil {
+		return progress, err
+	}
+	if err := removeCheckpoint(i.config.CheckpointFile); err != nil {
+		return progress, emperror.WrapWith(err, "Removing checkpoint failed", "file", i.config.CheckpointFile)
+	}
+	return progress, nil
+}
+
+// rateLimiter spaces out batches so that records are sent no faster than
+// the rate on average.
+type rateLimiter struct {
+	rate  float64
+	start time.Time
+	sent  int
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{rate: rate, start: time.Now()}
+}
+
+// wait blocks until n more records can be sent.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l.rate == 0 {
+		return nil
+	}
+	due := l.start.Add(time.Duration(float64(l.sent) / l.rate * float64(time.Second)))
+	l.sent += n
+	wait := time.Until(due)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+)
+
+const importInput = `{"deviceid":"a","birthdate":1}
+{"deviceid":"b","birthdate":2}
+
+{"deviceid":"c","birthdate":3}
+`
+
+var (
+	recordA = db.Record{DeviceID: "a", BirthDate: 1}
+	recordB = db.Record{DeviceID: "b", BirthDate: 2}
+	recordC = db.Record{DeviceID: "c", BirthDate: 3}
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		description string
+		input       []byte
+	}{
+		{
+			description: "Plain",
+			input:       []byte(importInput),
+		},
+		{
+			description: "Gzip",
+			input:       gzipped(t, importInput),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			inserter := new(mockInserter)
+			inserter.On("InsertRecords", []db.Record{recordA, recordB}).Return(nil).Once()
+			inserter.On("InsertRecords", []db.Record{recordC}).Return(nil).Once()
+			importer, err := NewImporter(ImportConfig{BatchSize: 2}, inserter)
+			require.NoError(t, err)
+			progress, err := importer.Import(context.Background(), bytes.NewReader(tc.input))
+			assert.NoError(err)
+			assert.Equal(ImportProgress{Lines: 4, Records: 3}, progress)
+			inserter.AssertExpectations(t)
+		})
+	}
+}
+
+func TestImportResume(t *testing.T) {
+	assert := assert.New(t)
+	config := ImportConfig{
+		BatchSize:      1,
+		CheckpointFile: filepath.Join(t.TempDir(), "checkpoint"),
+	}
+	inserter := new(mockInserter)
+	inserter.On("InsertRecords", []db.Record{recordA}).Return(nil).Once()
+	inserter.On("InsertRecords", []db.Record{recordB}).Return(errors.New("test error")).Once()
+	importer, err := NewImporter(config, inserter)
+	require.NoError(t, err)
+
+	progress, err := importer.Import(context.Background(), strings.NewReader(importInput))
+	assert.Error(err)
+	assert.Equal(ImportProgress{Lines: 1, Records: 1}, progress)
+	assert.FileExists(config.CheckpointFile)
+
+	inserter.On("InsertRecords", []db.Record{recordB}).Return(nil).Once()
+	inserter.On("InsertRecords", []db.Record{recordC}).Return(nil).Once()
+	progress, err = importer.Import(context.Background(), strings.NewReader(importInput))
+	assert.NoError(err)
+	assert.Equal(ImportProgress{Lines: 4, Records: 3}, progress)
+	assert.NoFileExists(config.CheckpointFile)
+	inserter.AssertExpectations(t)
+}
+
+func TestImportBadLine(t *testing.T) {
+	assert := assert.New(t)
+	importer, err := NewImporter(ImportConfig{}, new(mockInserter))
+	require.NoError(t, err)
+	_, err = importer.Import(context.Background(), strings.NewReader("{\"deviceid\":\"a\"}\nnot json\n"))
+	assert.Contains(err.Error(), "Decoding record failed")
+}
+
+func TestImportRateLimit(t *testing.T) {
+	assert := assert.New(t)
+	inserter := new(mockInserter)
+	inserter.On("InsertRecords", []db.Record{recordA}).Return(nil).Once()
+	inserter.On("InsertRecords", []db.Record{recordB}).Return(nil).Once()
+	inserter.On("InsertRecords", []db.Record{recordC}).Return(nil).Once()
+	importer, err := NewImporter(ImportConfig{BatchSize: 1, RecordsPerSecond: 20}, inserter)
+	require.NoError(t, err)
+
+	start := time.Now()
+	progress, err := importer.Import(context.Background(), strings.NewReader(importInput))
+	assert.NoError(err)
+	assert.Equal(int64(3), progress.Records)
+	// the first record goes right away and the next two wait 50ms each.
+	assert.GreaterOrEqual(time.Since(start), 100*time.Millisecond)
+	inserter.AssertExpectations(t)
+}
+
+func TestNewImporter(t *testing.T) {
+	assert := assert.New(t)
+	_, err := NewImporter(ImportConfig{}, nil)
+	assert.Equal(errNilInserter, err)
+	importer, err := NewImporter(ImportConfig{RecordsPerSecond: -1}, new(mockInserter))
+	assert.NoError(err)
+	assert.Equal(ImportConfig{BatchSize: defaultBatchSize}, importer.config)
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package export
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	db "github.com/xmidt-org/codex-db"
+)
+
+type mockGetter struct {
+	mock.Mock
+}
+
+func (g *mockGetter) GetRecords(deviceID string, limit int, stateHash string) ([]db.Record, error) {
+	args := g.Called(deviceID, limit, stateHash)
+	return args.Get(0).([]db.Record), args.Error(1)
+}
+
+func (g *mockGetter) GetRecordsOfType(deviceID string, limit int, eventType db.EventType, stateHash string) ([]db.Record, error) {
+	args := g.Called(deviceID, limit, eventType, stateHash)
+	return args.Get(0).([]db.Record), args.Error(1)
+}
+
+func (g *mockGetter) GetStateHash(records []db.Record) (string, error) {
+	args := g.Called(records)
+	return args.String(0), args.Error(1)
+}
+
+type mockInserter struct {
+	mock.Mock
+}
+
+func (c *mockInserter) InsertRecords(records ...db.Record) error {
+	args := c.Called(records)
+	return args.Error(0)
+}
+
+type mockPostgresDevices struct {
+	mock.Mock
+}
+
+func (m *mockPostgresDevices) GetDeviceList(offset string, limit int) ([]string, error) {
+	args := m.Called(offset, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+type mockCassandraDevices struct {
+	mock.Mock
+}
+
+func (m *mockCassandraDevices) GetDeviceList(startDate time.Time, endDate time.Time, offset int, limit int) ([]string, error) {
+	args := m.Called(startDate, endDate, offset, limit)
+	return args.Get(0).([]string), args.Error(1)
+}
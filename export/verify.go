@@ -0,0 +1,248 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	"github.com/goph/emperror"
+	db "github.com/xmidt-org/codex-db"
+)
+
+// VerifyConfig configures comparing the records of two databases.
+type VerifyConfig struct {
+	// Workers is how many devices are compared at once.
+	Workers int
+
+	// PageSize is how many devices are listed at a time.
+	PageSize int
+
+	// MaxRecords is the most records compared for one device.
+	MaxRecords int
+}
+
+// Mismatch is a device whose records differ between the databases.
+// Missing counts the source's records that dest doesn't have, Duplicated the
+// extra copies dest has of the source's records, and Unexpected dest's
+// records that aren't in the source at all.
+type Mismatch struct {
+	DeviceID    string `json:"deviceID"`
+	SourceCount int    `json:"sourceCount"`
+	DestCount   int    `json:"destCount"`
+	SourceHash  string `json:"sourceHash"`
+	DestHash    string `json:"destHash"`
+	Missing     int    `json:"missing"`
+	Duplicated  int    `json:"duplicated"`
+	Unexpected  int    `json:"unexpected"`
+}
+
+// VerifyReport is what comparing the databases found.
+type VerifyReport struct {
+	Devices    int64      `json:"devices"`
+	Matched    int64      `json:"matched"`
+	Mismatches []Mismatch `json:"mismatches"`
+}
+
+// Verifier compares the records of every device a DeviceLister lists in two
+// databases.
+type Verifier struct {
+	config VerifyConfig
+	source db.RecordGetter
+	lister DeviceLister
+	dest   db.RecordGetter
+}
+
+// NewVerifier creates a Verifier comparing the records in source and dest
+// for the devices listed by lister.
+func NewVerifier(config VerifyConfig, source db.RecordGetter, lister DeviceLister, dest db.RecordGetter) (*Verifier, error) {
+	if source == nil || dest == nil {
+		return nil, errNilGetter
+	}
+	if lister == nil {
+		return nil, errNilLister
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = defaultPageSize
+	}
+	if config.MaxRecords <= 0 {
+		config.MaxRecords = defaultMaxRecords
+	}
+	return &Verifier{
+		config: config,
+		source: source,
+		lister: lister,
+		dest:   dest,
+	}, nil
+}
+
+// Verify compares the number of records each device has in both databases
+// and a hash of their contents, reporting the devices that differ and how
+// many of their records are missing, duplicated, or unexpected.  Row ids are
+// left out of the hash, as cassandra makes its own when inserting.
+func (v *Verifier) Verify(ctx context.Context) (VerifyReport, error) {
+	report := VerifyReport{Mismatches: []Mismatch{}}
+	offset := ""
+	for {
+		devices, next, err := v.lister.ListDevices(offset, v.config.PageSize)
+		if err != nil {
+			return report, emperror.WrapWith(err, "Listing devices failed", "offset", offset)
+		}
+		if err = v.verifyPage(ctx, devices, &report); err != nil {
+			return report, err
+		}
+		if next == "" {
+			sort.Slice(report.Mismatches, func(i, j int) bool {
+				return report.Mismatches[i].DeviceID < report.Mismatches[j].DeviceID
+			})
+			return report, nil
+		}
+		offset = next
+	}
+}
+
+func (v *Verifier) verifyPage(ctx context.Context, devices []string, report *VerifyReport) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		lock     sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+		jobs     = make(chan string)
+	)
+
+	for i := 0; i < v.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for deviceID := range jobs {
+				mismatch, err := v.verifyDevice(deviceID)
+				lock.Lock()
+				switch {
+				case err != nil:
+					if firstErr == nil {
+						firstErr = emperror.WrapWith(err, "Verifying device failed", "device id", deviceID)
+					}
+					cancel()
+				case mismatch != nil:
+					report.Devices++
+					report.Mismatches = append(report.Mismatches, *mismatch)
+				default:
+					report.Devices++
+					report.Matched++
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+
+send:
+	for _, deviceID := range devices {
+		select {
+		case <-ctx.Done():
+			break send
+		case jobs <- deviceID:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// verifyDevice returns how the device's records differ, or nil if they match.
+func (v *Verifier) verifyDevice(deviceID string) (*Mismatch, error) {
+	source, err := v.source.GetRecords(deviceID, v.config.MaxRecords, "")
+	if err != nil {
+		return nil, err
+	}
+	dest, err := v.dest.GetRecords(deviceID, v.config.MaxRecords, "")
+	if err != nil {
+		return nil, err
+	}
+	sourceHash, destHash := hashRecords(source), hashRecords(dest)
+	if len(source) == len(dest) && sourceHash == destHash {
+		return nil, nil
+	}
+	mismatch := &Mismatch{
+		DeviceID:    deviceID,
+		SourceCount: len(source),
+		DestCount:   len(dest),
+		SourceHash:  sourceHash,
+		DestHash:    destHash,
+	}
+	counts := make(map[string]int, len(source))
+	for _, r := range source {
+		counts[string(hashRecord(r))]++
+	}
+	destCounts := make(map[string]int, len(dest))
+	for _, r := range dest {
+		destCounts[string(hashRecord(r))]++
+	}
+	for hash, n := range counts {
+		if destCounts[hash] < n {
+			mismatch.Missing += n - destCounts[hash]
+		}
+	}
+	for hash, n := range destCounts {
+		switch {
+		case counts[hash] == 0:
+			mismatch.Unexpected += n
+		case n > counts[hash]:
+			mismatch.Duplicated += n - counts[hash]
+		}
+	}
+	return mismatch, nil
+}
+
+// hashRecords hashes the records regardless of their order, since databases
+// may return records born at the same time in any order.
+func hashRecords(records []db.Record) string {
+	hashes := make([][]byte, 0, len(records))
+	for _, r := range records {
+		hashes = append(hashes, hashRecord(r))
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i], hashes[j]) < 0
+	})
+	h := sha256.New()
+	for _, sum := range hashes {
+		h.Write(sum)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func hashRecord(r db.Record) []byte {
+	h := sha256.New()
+	var buf [8]byte
+	writeInt := func(n int64) {
+		binary.BigEndian.PutUint64(buf[:], uint64(n))
+		h.Write(buf[:])
+	}
+	// lengths keep one field's bytes from running into the next.
+	writeBytes := func(b []byte) {
+		writeInt(int64(len(b)))
+		h.Write(b)
+	}
+	writeInt(int64(r.Type))
+	writeBytes([]byte(r.DeviceID))
+	writeInt(r.BirthDate)
+	writeInt(r.DeathDate)
+	writeBytes(r.Data)
+	writeBytes(r.Nonce)
+	writeBytes([]byte(r.Alg))
+	writeBytes([]byte(r.KID))
+	return h.Sum(nil)
+}
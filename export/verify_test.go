@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package export
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+)
+
+func TestVerify(t *testing.T) {
+	assert := assert.New(t)
+	first := db.Record{DeviceID: "a", BirthDate: 1, Data: []byte("one"), RowID: "1"}
+	second := db.Record{DeviceID: "a", BirthDate: 1, Data: []byte("two"), RowID: "2"}
+	changed := db.Record{DeviceID: "c", BirthDate: 1, Data: []byte("old")}
+
+	source := new(mockGetter)
+	source.On("GetRecords", "a", mock.Anything, "").Return([]db.Record{first, second}, nil)
+	source.On("GetRecords", "b", mock.Anything, "").Return([]db.Record{first}, nil)
+	source.On("GetRecords", "c", mock.Anything, "").Return([]db.Record{changed}, nil)
+	source.On("GetRecords", "d", mock.Anything, "").Return([]db.Record{first, second}, nil)
+	dest := new(mockGetter)
+	// same records in another order, with the row ids the database made.
+	second.RowID, first.RowID = "x", "y"
+	dest.On("GetRecords", "a", mock.Anything, "").Return([]db.Record{second, first}, nil)
+	dest.On("GetRecords", "b", mock.Anything, "").Return([]db.Record{}, nil)
+	changed.Data = []byte("new")
+	dest.On("GetRecords", "c", mock.Anything, "").Return([]db.Record{changed}, nil)
+	// a record copied twice, such as by an interrupted copy.
+	dest.On("GetRecords", "d", mock.Anything, "").Return([]db.Record{first, second, first}, nil)
+
+	verifier, err := NewVerifier(VerifyConfig{Workers: 2}, source, Devices{"d", "c", "b", "a"}, dest)
+	require.NoError(t, err)
+	report, err := verifier.Verify(context.Background())
+	assert.NoError(err)
+	assert.Equal(int64(4), report.Devices)
+	assert.Equal(int64(1), report.Matched)
+	if assert.Len(report.Mismatches, 3) {
+		assert.Equal("b", report.Mismatches[0].DeviceID)
+		assert.Equal(1, report.Mismatches[0].SourceCount)
+		assert.Equal(0, report.Mismatches[0].DestCount)
+		assert.Equal(1, report.Mismatches[0].Missing)
+		assert.Equal("c", report.Mismatches[1].DeviceID)
+		assert.Equal(1, report.Mismatches[1].DestCount)
+		assert.NotEqual(report.Mismatches[1].SourceHash, report.Mismatches[1].DestHash)
+		assert.Equal(1, report.Mismatches[1].Missing)
+		assert.Equal(1, report.Mismatches[1].Unexpected)
+		assert.Equal("d", report.Mismatches[2].DeviceID)
+		assert.Equal(0, report.Mismatches[2].Missing)
+		assert.Equal(1, report.Mismatches[2].Duplicated)
+		assert.Equal(0, report.Mismatches[2].Unexpected)
+	}
+}
+
+func TestVerifyError(t *testing.T) {
+	assert := assert.New(t)
+	source := new(mockGetter)
+	source.On("GetRecords", "a", mock.Anything, "").Return([]db.Record{}, errors.New("test error"))
+	verifier, err := NewVerifier(VerifyConfig{}, source, Devices{"a"}, new(mockGetter))
+	require.NoError(t, err)
+	_, err = verifier.Verify(context.Background())
+	assert.Contains(err.Error(), "Verifying device failed")
+}
+
+func TestHashRecords(t *testing.T) {
+	assert := assert.New(t)
+	// field boundaries are part of the hash.
+	assert.NotEqual(
+		hashRecords([]db.Record{{Alg: "ab", KID: "c"}}),
+		hashRecords([]db.Record{{Alg: "a", KID: "bc"}}),
+	)
+	assert.Equal(hashRecords(nil), hashRecords([]db.Record{}))
+}
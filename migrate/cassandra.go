@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package migrate
+
+import (
+	"time"
+
+	"github.com/yugabyte/gocql"
+)
+
+// cassandraDriver runs migrations over a gocql session.  Cassandra can't run
+// schema changes in a transaction, so a migration that fails part way must be
+// finished by hand, and migrations shouldn't be run from more than one place
+// at a time.
+type cassandraDriver struct {
+	session *gocql.Session
+}
+
+// NewCassandra creates a Migrator for a Cassandra or Yugabyte cluster.  The
+// session must not be bound to the devices keyspace, as it may not exist yet.
+func NewCassandra(session *gocql.Session) (*Migrator, error) {
+	return newMigrator(&cassandraDriver{session: session}, "cassandra", true)
+}
+
+func (d *cassandraDriver) init() error {
+	err := d.session.Query("CREATE KEYSPACE IF NOT EXISTS devices").Exec()
+	if err != nil {
+		return err
+	}
+	return d.session.Query(`CREATE TABLE IF NOT EXISTS devices.schema_version (version INT PRIMARY KEY,
+		name VARCHAR,
+		applied_at TIMESTAMP)`).Exec()
+}
+
+func (d *cassandraDriver) version() (int, error) {
+	var (
+		version int
+		latest  int
+	)
+	iter := d.session.Query("SELECT version FROM devices.schema_version").Iter()
+	for iter.Scan(&version) {
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest, iter.Close()
+}
+
+func (d *cassandraDriver) hasSchema() (bool, error) {
+	var count int
+	err := d.session.Query("SELECT COUNT(*) FROM system_schema.tables WHERE keyspace_name = ? AND table_name = ?",
+		"devices", "events").Scan(&count)
+	return count > 0, err
+}
+
+func (d *cassandraDriver) apply(m Migration) error {
+	for _, statement := range m.Statements {
+		if err := d.session.Query(statement).Exec(); err != nil {
+			return err
+		}
+	}
+	return d.session.Query("INSERT INTO devices.schema_version (version, name, applied_at) VALUES (?, ?, ?)",
+		m.Version, m.Name, time.Now()).Exec()
+}
@@ -0,0 +1,353 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package migrate
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/goph/emperror"
+	"github.com/yugabyte/gocql"
+)
+
+// The ways the drivers can check the live schema when connecting.
+const (
+	// SchemaCheckOff skips the check.
+	SchemaCheckOff = "off"
+	// SchemaCheckWarn logs any drift found and connects anyway.
+	SchemaCheckWarn = "warn"
+	// SchemaCheckError fails to connect if any drift is found.
+	SchemaCheckError = "error"
+)
+
+var (
+	errInvalidSchemaCheck = errors.New("schema check must be off, warn, or error")
+)
+
+// ColumnMismatch is a column whose type isn't the one the drivers need.
+type ColumnMismatch struct {
+	Table    string
+	Column   string
+	Expected string
+	Actual   string
+}
+
+// SchemaDrift is how the live schema differs from what db.Record and
+// blacklist.BlackListedItem need.  It is returned as an error when there is
+// drift.
+type SchemaDrift struct {
+	// MissingTables lists the tables that don't exist.
+	MissingTables []string
+
+	// MissingColumns lists the missing columns as table.column.
+	MissingColumns []string
+
+	// WrongTypes lists the columns with the wrong type.
+	WrongTypes []ColumnMismatch
+
+	// MissingIndexes lists the indexes reads need that don't exist.
+	MissingIndexes []string
+}
+
+// Empty returns whether no drift was found.
+func (d *SchemaDrift) Empty() bool {
+	return len(d.MissingTables) == 0 && len(d.MissingColumns) == 0 && len(d.WrongTypes) == 0 && len(d.MissingIndexes) == 0
+}
+
+func (d *SchemaDrift) Error() string {
+	var parts []string
+	if len(d.MissingTables) > 0 {
+		parts = append(parts, "missing tables: "+strings.Join(d.MissingTables, ", "))
+	}
+	if len(d.MissingColumns) > 0 {
+		parts = append(parts, "missing columns: "+strings.Join(d.MissingColumns, ", "))
+	}
+	if len(d.WrongTypes) > 0 {
+		types := make([]string, 0, len(d.WrongTypes))
+		for _, m := range d.WrongTypes {
+			types = append(types, fmt.Sprintf("%s.%s is %s, not %s", m.Table, m.Column, m.Actual, m.Expected))
+		}
+		parts = append(parts, "wrong column types: "+strings.Join(types, ", "))
+	}
+	if len(d.MissingIndexes) > 0 {
+		parts = append(parts, "missing indexes: "+strings.Join(d.MissingIndexes, ", "))
+	}
+	return "schema drift found: " + strings.Join(parts, "; ")
+}
+
+// AsSchemaDrift finds the SchemaDrift in an error returned when connecting,
+// looking through the errors wrapping it.
+func AsSchemaDrift(err error) (*SchemaDrift, bool) {
+	for err != nil {
+		if drift, ok := err.(*SchemaDrift); ok {
+			return drift, true
+		}
+		switch e := err.(type) {
+		case interface{ Cause() error }:
+			err = e.Cause()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// ParseSchemaCheck validates a schema check mode, where empty means off.
+func ParseSchemaCheck(mode string) (string, error) {
+	switch mode {
+	case "":
+		return SchemaCheckOff, nil
+	case SchemaCheckOff, SchemaCheckWarn, SchemaCheckError:
+		return mode, nil
+	}
+	return "", emperror.With(errInvalidSchemaCheck, "schema check", mode)
+}
+
+type column struct {
+	name string
+	typ  string
+}
+
+type table struct {
+	name    string
+	columns []column
+}
+
+// index is an index that reads need, matched by name for cassandra and by
+// its leading columns for postgres.
+type index struct {
+	table   string
+	name    string
+	columns []string
+}
+
+type expectedSchema struct {
+	tables  []table
+	indexes []index
+}
+
+// liveSchema maps each table to its columns and their types, along with the
+// indexes found.
+type liveSchema struct {
+	tables  map[string]map[string]string
+	indexes []index
+}
+
+var cassandraSchema = expectedSchema{
+	tables: []table{
+		{
+			name: "events",
+			columns: []column{
+				{"device_id", "text"},
+				{"record_type", "int"},
+				{"birthdate", "bigint"},
+				{"deathdate", "bigint"},
+				{"data", "blob"},
+				{"nonce", "blob"},
+				{"alg", "text"},
+				{"kid", "text"},
+				{"row_id", "timeuuid"},
+			},
+		},
+		{
+			name: "blacklist",
+			columns: []column{
+				{"device_id", "text"},
+				{"reason", "text"},
+			},
+		},
+	},
+	indexes: []index{
+		{table: "events", name: "search_by_record_type"},
+		{table: "events", name: "search_by_row_id"},
+	},
+}
+
+var postgresSchema = expectedSchema{
+	tables: []table{
+		{
+			name: "events",
+			columns: []column{
+				{"record_id", "bigint"},
+				{"shard", "integer"},
+				{"type", "integer"},
+				{"device_id", "character varying"},
+				{"birth_date", "bigint"},
+				{"death_date", "bigint"},
+				{"data", "bytea"},
+				{"nonce", "bytea"},
+				{"alg", "character varying"},
+				{"kid", "character varying"},
+				{"row_id", "character varying"},
+			},
+		},
+		{
+			name: "blacklist",
+			columns: []column{
+				{"id", "character varying"},
+				{"reason", "character varying"},
+			},
+		},
+	},
+	indexes: []index{
+		{table: "events", columns: []string{"device_id"}},
+		{table: "events", columns: []string{"shard", "death_date"}},
+	},
+}
+
+// compare finds how the live schema differs from the expected one.
+func (e expectedSchema) compare(live liveSchema) *SchemaDrift {
+	drift := &SchemaDrift{}
+	for _, t := range e.tables {
+		columns, ok := live.tables[t.name]
+		if !ok {
+			drift.MissingTables = append(drift.MissingTables, t.name)
+			continue
+		}
+		for _, c := range t.columns {
+			actual, ok := columns[c.name]
+			switch {
+			case !ok:
+				drift.MissingColumns = append(drift.MissingColumns, t.name+"."+c.name)
+			case actual != c.typ:
+				drift.WrongTypes = append(drift.WrongTypes, ColumnMismatch{
+					Table:    t.name,
+					Column:   c.name,
+					Expected: c.typ,
+					Actual:   actual,
+				})
+			}
+		}
+	}
+	for _, want := range e.indexes {
+		if _, ok := live.tables[want.table]; !ok {
+			// already reported as a missing table.
+			continue
+		}
+		if !hasIndex(live.indexes, want) {
+			drift.MissingIndexes = append(drift.MissingIndexes, want.String())
+		}
+	}
+	return drift
+}
+
+func (i index) String() string {
+	if i.name != "" {
+		return i.name
+	}
+	return fmt.Sprintf("%s(%s)", i.table, strings.Join(i.columns, ", "))
+}
+
+func hasIndex(indexes []index, want index) bool {
+	for _, have := range indexes {
+		if have.table != want.table {
+			continue
+		}
+		if want.name != "" {
+			if have.name == want.name {
+				return true
+			}
+			continue
+		}
+		if hasPrefix(have.columns, want.columns) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(columns []string, prefix []string) bool {
+	if len(columns) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if columns[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckCassandra compares the devices keyspace with what the cassandra
+// driver needs.
+func CheckCassandra(session *gocql.Session) (*SchemaDrift, error) {
+	live := liveSchema{tables: make(map[string]map[string]string)}
+	var tableName, columnName, columnType string
+	iter := session.Query("SELECT table_name, column_name, type FROM system_schema.columns WHERE keyspace_name = ?", "devices").Iter()
+	for iter.Scan(&tableName, &columnName, &columnType) {
+		addColumn(live, tableName, columnName, columnType)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	var indexName string
+	iter = session.Query("SELECT table_name, index_name FROM system_schema.indexes WHERE keyspace_name = ?", "devices").Iter()
+	for iter.Scan(&tableName, &indexName) {
+		live.indexes = append(live.indexes, index{table: tableName, name: indexName})
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return cassandraSchema.compare(live), nil
+}
+
+// CheckPostgres compares the devices schema with what the postgres driver
+// needs.
+func CheckPostgres(db *sql.DB) (*SchemaDrift, error) {
+	live := liveSchema{tables: make(map[string]map[string]string)}
+	rows, err := db.Query("SELECT table_name, column_name, data_type FROM information_schema.columns WHERE table_schema = 'devices'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tableName, columnName, dataType string
+		if err = rows.Scan(&tableName, &columnName, &dataType); err != nil {
+			return nil, err
+		}
+		addColumn(live, tableName, columnName, dataType)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// the columns of each index, in order.
+	indexRows, err := db.Query(`SELECT t.relname, array_to_string(array_agg(a.attname ORDER BY k.n), ',')
+		FROM pg_catalog.pg_index i
+		JOIN pg_catalog.pg_class t ON t.oid = i.indrelid
+		JOIN pg_catalog.pg_namespace ns ON ns.oid = t.relnamespace
+		CROSS JOIN LATERAL unnest(i.indkey) WITH ORDINALITY AS k(attnum, n)
+		JOIN pg_catalog.pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE ns.nspname = 'devices'
+		GROUP BY i.indexrelid, t.relname`)
+	if err != nil {
+		return nil, err
+	}
+	defer indexRows.Close()
+	for indexRows.Next() {
+		var tableName, columns string
+		if err = indexRows.Scan(&tableName, &columns); err != nil {
+			return nil, err
+		}
+		live.indexes = append(live.indexes, index{table: tableName, columns: strings.Split(columns, ",")})
+	}
+	if err = indexRows.Err(); err != nil {
+		return nil, err
+	}
+	return postgresSchema.compare(live), nil
+}
+
+func addColumn(live liveSchema, tableName string, columnName string, columnType string) {
+	columns, ok := live.tables[tableName]
+	if !ok {
+		columns = make(map[string]string)
+		live.tables[tableName] = columns
+	}
+	columns[columnName] = columnType
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package migrate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/goph/emperror"
+	"github.com/stretchr/testify/assert"
+)
+
+func liveFrom(schema expectedSchema) liveSchema {
+	live := liveSchema{tables: make(map[string]map[string]string)}
+	for _, t := range schema.tables {
+		for _, c := range t.columns {
+			addColumn(live, t.name, c.name, c.typ)
+		}
+	}
+	live.indexes = append(live.indexes, schema.indexes...)
+	return live
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		description   string
+		schema        expectedSchema
+		change        func(live *liveSchema)
+		expectedDrift *SchemaDrift
+	}{
+		{
+			description:   "Cassandra Matches",
+			schema:        cassandraSchema,
+			change:        func(*liveSchema) {},
+			expectedDrift: &SchemaDrift{},
+		},
+		{
+			description: "Cassandra v0.4",
+			schema:      cassandraSchema,
+			change: func(live *liveSchema) {
+				delete(live.tables["events"], "row_id")
+				live.indexes = live.indexes[:1]
+			},
+			expectedDrift: &SchemaDrift{
+				MissingColumns: []string{"events.row_id"},
+				MissingIndexes: []string{"search_by_row_id"},
+			},
+		},
+		{
+			description: "Cassandra Missing Blacklist",
+			schema:      cassandraSchema,
+			change: func(live *liveSchema) {
+				delete(live.tables, "blacklist")
+			},
+			expectedDrift: &SchemaDrift{
+				MissingTables: []string{"blacklist"},
+			},
+		},
+		{
+			description:   "Postgres Matches",
+			schema:        postgresSchema,
+			change:        func(*liveSchema) {},
+			expectedDrift: &SchemaDrift{},
+		},
+		{
+			description: "Postgres Wider Index",
+			schema:      postgresSchema,
+			change: func(live *liveSchema) {
+				live.indexes = []index{
+					{table: "events", columns: []string{"device_id", "birth_date"}},
+					{table: "events", columns: []string{"shard", "death_date", "record_id"}},
+				}
+			},
+			expectedDrift: &SchemaDrift{},
+		},
+		{
+			description: "Postgres Drift",
+			schema:      postgresSchema,
+			change: func(live *liveSchema) {
+				live.tables["events"]["birth_date"] = "integer"
+				live.indexes = []index{
+					{table: "events", columns: []string{"death_date", "record_id"}},
+					{table: "blacklist", columns: []string{"device_id"}},
+				}
+			},
+			expectedDrift: &SchemaDrift{
+				WrongTypes: []ColumnMismatch{
+					{Table: "events", Column: "birth_date", Expected: "bigint", Actual: "integer"},
+				},
+				MissingIndexes: []string{"events(device_id)", "events(shard, death_date)"},
+			},
+		},
+		{
+			description: "Postgres No Tables",
+			schema:      postgresSchema,
+			change: func(live *liveSchema) {
+				live.tables = map[string]map[string]string{}
+			},
+			expectedDrift: &SchemaDrift{
+				MissingTables: []string{"events", "blacklist"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			live := liveFrom(tc.schema)
+			tc.change(&live)
+			drift := tc.schema.compare(live)
+			assert.Equal(tc.expectedDrift, drift)
+			assert.Equal(len(tc.expectedDrift.MissingTables)+len(tc.expectedDrift.MissingColumns)+
+				len(tc.expectedDrift.WrongTypes)+len(tc.expectedDrift.MissingIndexes) == 0, drift.Empty())
+		})
+	}
+}
+
+func TestSchemaDriftError(t *testing.T) {
+	assert := assert.New(t)
+	drift := &SchemaDrift{
+		MissingColumns: []string{"events.row_id"},
+		WrongTypes: []ColumnMismatch{
+			{Table: "events", Column: "birth_date", Expected: "bigint", Actual: "integer"},
+		},
+	}
+	err := emperror.Wrap(drift, "Checking database schema failed")
+	assert.Contains(err.Error(), "missing columns: events.row_id; wrong column types: events.birth_date is integer, not bigint")
+
+	found, ok := AsSchemaDrift(err)
+	assert.True(ok)
+	assert.Equal(drift, found)
+	_, ok = AsSchemaDrift(errors.New("test error"))
+	assert.False(ok)
+}
+
+func TestParseSchemaCheck(t *testing.T) {
+	assert := assert.New(t)
+	mode, err := ParseSchemaCheck("")
+	assert.NoError(err)
+	assert.Equal(SchemaCheckOff, mode)
+	mode, err = ParseSchemaCheck(SchemaCheckWarn)
+	assert.NoError(err)
+	assert.Equal(SchemaCheckWarn, mode)
+	_, err = ParseSchemaCheck("loud")
+	assert.Error(err)
+}
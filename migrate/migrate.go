@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package migrate creates and upgrades the codex database schema for the
+// Cassandra/Yugabyte and Postgres drivers.  The migrations are embedded and
+// numbered, and the version a database is at is kept in its
+// devices.schema_version table.
+package migrate
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goph/emperror"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+var (
+	errSchemaOutdated    = errors.New("database schema is older than the latest migration")
+	errSchemaTooNew      = errors.New("database schema is newer than the latest migration")
+	errUnversionedSchema = errors.New("database schema exists without a schema version; it must be baselined")
+	errUnknownVersion    = errors.New("unknown schema version")
+	errAlreadyVersioned  = errors.New("database schema already has a version")
+	errBadMigrationName  = errors.New("migration file name must be <version>_<name>")
+	errMigrationGap      = errors.New("migration versions must start at 1 and have no gaps")
+)
+
+// Migration is one numbered change to the schema.
+type Migration struct {
+	Version    int
+	Name       string
+	Statements []string
+}
+
+// driver runs migrations against one kind of database.
+type driver interface {
+	// init creates the schema version table if it doesn't exist.
+	init() error
+	// version returns the latest version applied, or 0 if there is none.
+	version() (int, error)
+	// hasSchema returns whether the events table exists.
+	hasSchema() (bool, error)
+	// apply runs the migration's statements and records its version, unless
+	// the version was already recorded.
+	apply(m Migration) error
+}
+
+// Migrator brings a database's schema up to date.
+type Migrator struct {
+	driver     driver
+	migrations []Migration
+}
+
+func newMigrator(d driver, dir string, split bool) (*Migrator, error) {
+	loaded, err := loadMigrations(migrations, dir, split)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{driver: d, migrations: loaded}, nil
+}
+
+// Migrations returns the migrations known to the migrator, oldest first.
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), m.migrations...)
+}
+
+// Latest returns the version of the newest migration.
+func (m *Migrator) Latest() int {
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the version the database's schema is at, 0 if it has none.
+func (m *Migrator) Version() (int, error) {
+	if err := m.driver.init(); err != nil {
+		return 0, emperror.Wrap(err, "Creating schema version table failed")
+	}
+	version, err := m.driver.version()
+	if err != nil {
+		return 0, emperror.Wrap(err, "Getting schema version failed")
+	}
+	return version, nil
+}
+
+// current returns the schema's version, failing if it isn't one the
+// migrations know how to upgrade from.
+func (m *Migrator) current() (int, error) {
+	version, err := m.Version()
+	if err != nil {
+		return 0, err
+	}
+	if version > m.Latest() {
+		return 0, emperror.With(errSchemaTooNew, "version", version, "latest", m.Latest())
+	}
+	if version == 0 {
+		exists, err := m.driver.hasSchema()
+		if err != nil {
+			return 0, emperror.Wrap(err, "Checking for existing schema failed")
+		}
+		if exists {
+			return 0, errUnversionedSchema
+		}
+	}
+	return version, nil
+}
+
+// MigrateUp applies every migration newer than the database's schema.
+func (m *Migrator) MigrateUp() error {
+	version, err := m.current()
+	if err != nil {
+		return err
+	}
+	for _, migration := range m.migrations {
+		if migration.Version <= version {
+			continue
+		}
+		if err := m.driver.apply(migration); err != nil {
+			return emperror.WrapWith(err, "Applying migration failed", "version", migration.Version, "name", migration.Name)
+		}
+	}
+	return nil
+}
+
+// EnsureSchema creates the schema if the database doesn't have one, and
+// otherwise checks that the schema is at the latest version.  An outdated
+// schema is an error rather than being upgraded, as upgrading existing data
+// should be done on purpose with MigrateUp.
+func (m *Migrator) EnsureSchema() error {
+	version, err := m.current()
+	if err != nil {
+		return err
+	}
+	switch {
+	case version == 0:
+		return m.MigrateUp()
+	case version < m.Latest():
+		return emperror.With(errSchemaOutdated, "version", version, "latest", m.Latest())
+	}
+	return nil
+}
+
+// Baseline records that a database whose schema was created by hand is at
+// the version given, without running any migrations.
+func (m *Migrator) Baseline(version int) error {
+	if version < 1 || version > m.Latest() {
+		return emperror.With(errUnknownVersion, "version", version)
+	}
+	current, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if current != 0 {
+		return emperror.With(errAlreadyVersioned, "version", current)
+	}
+	for _, migration := range m.migrations[:version] {
+		migration.Statements = nil
+		if err := m.driver.apply(migration); err != nil {
+			return emperror.WrapWith(err, "Recording schema version failed", "version", migration.Version)
+		}
+	}
+	return nil
+}
+
+// loadMigrations reads the migration files in dir, named
+// <version>_<name>.<ext>.  When split is set, each file is broken into
+// statements at semicolons ending a line, for databases that can only run
+// one statement at a time.
+func loadMigrations(fsys fs.FS, dir string, split bool) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, path.Join("migrations", dir))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		base := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		parts := strings.SplitN(base, "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if len(parts) != 2 || err != nil {
+			return nil, emperror.With(errBadMigrationName, "file", entry.Name())
+		}
+		contents, err := fs.ReadFile(fsys, path.Join("migrations", dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		statements := []string{string(contents)}
+		if split {
+			statements = splitStatements(string(contents))
+		}
+		result = append(result, Migration{
+			Version:    version,
+			Name:       parts[1],
+			Statements: statements,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+	for i, migration := range result {
+		if migration.Version != i+1 {
+			return nil, emperror.With(errMigrationGap, "version", migration.Version)
+		}
+	}
+	if len(result) == 0 {
+		return nil, errMigrationGap
+	}
+	return result, nil
+}
+
+// splitStatements breaks a script into its statements, dropping comment
+// lines.
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		current    []string
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current = append(current, line)
+		if strings.HasSuffix(trimmed, ";") {
+			statement := strings.TrimSuffix(strings.TrimSpace(strings.Join(current, "\n")), ";")
+			statements = append(statements, statement)
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		statements = append(statements, strings.TrimSpace(strings.Join(current, "\n")))
+	}
+	return statements
+}
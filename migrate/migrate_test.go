@@ -0,0 +1,254 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDriver struct {
+	versions   []int
+	statements []string
+	exists     bool
+	applyErr   error
+}
+
+func (d *fakeDriver) init() error {
+	return nil
+}
+
+func (d *fakeDriver) version() (int, error) {
+	latest := 0
+	for _, v := range d.versions {
+		if v > latest {
+			latest = v
+		}
+	}
+	return latest, nil
+}
+
+func (d *fakeDriver) hasSchema() (bool, error) {
+	return d.exists || len(d.versions) > 0, nil
+}
+
+func (d *fakeDriver) apply(m Migration) error {
+	if d.applyErr != nil {
+		return d.applyErr
+	}
+	d.statements = append(d.statements, m.Statements...)
+	d.versions = append(d.versions, m.Version)
+	return nil
+}
+
+func testMigrator(d *fakeDriver) *Migrator {
+	return &Migrator{
+		driver: d,
+		migrations: []Migration{
+			{Version: 1, Name: "first", Statements: []string{"a", "b"}},
+			{Version: 2, Name: "second", Statements: []string{"c"}},
+		},
+	}
+}
+
+func TestMigrateUp(t *testing.T) {
+	tests := []struct {
+		description        string
+		driver             *fakeDriver
+		expectedVersions   []int
+		expectedStatements []string
+		expectedErr        error
+	}{
+		{
+			description:        "Empty Database",
+			driver:             &fakeDriver{},
+			expectedVersions:   []int{1, 2},
+			expectedStatements: []string{"a", "b", "c"},
+		},
+		{
+			description:        "Outdated",
+			driver:             &fakeDriver{versions: []int{1}},
+			expectedVersions:   []int{1, 2},
+			expectedStatements: []string{"c"},
+		},
+		{
+			description:      "Latest",
+			driver:           &fakeDriver{versions: []int{1, 2}},
+			expectedVersions: []int{1, 2},
+		},
+		{
+			description:      "Too New",
+			driver:           &fakeDriver{versions: []int{3}},
+			expectedVersions: []int{3},
+			expectedErr:      errSchemaTooNew,
+		},
+		{
+			description: "Unversioned",
+			driver:      &fakeDriver{exists: true},
+			expectedErr: errUnversionedSchema,
+		},
+		{
+			description: "Apply Error",
+			driver:      &fakeDriver{applyErr: errors.New("test apply error")},
+			expectedErr: errors.New("test apply error"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			err := testMigrator(tc.driver).MigrateUp()
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+			}
+			assert.Equal(tc.expectedVersions, tc.driver.versions)
+			assert.Equal(tc.expectedStatements, tc.driver.statements)
+		})
+	}
+}
+
+func TestEnsureSchema(t *testing.T) {
+	tests := []struct {
+		description      string
+		driver           *fakeDriver
+		expectedVersions []int
+		expectedErr      error
+	}{
+		{
+			description:      "Empty Database",
+			driver:           &fakeDriver{},
+			expectedVersions: []int{1, 2},
+		},
+		{
+			description:      "Outdated",
+			driver:           &fakeDriver{versions: []int{1}},
+			expectedVersions: []int{1},
+			expectedErr:      errSchemaOutdated,
+		},
+		{
+			description:      "Latest",
+			driver:           &fakeDriver{versions: []int{1, 2}},
+			expectedVersions: []int{1, 2},
+		},
+		{
+			description: "Unversioned",
+			driver:      &fakeDriver{exists: true},
+			expectedErr: errUnversionedSchema,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			err := testMigrator(tc.driver).EnsureSchema()
+			if tc.expectedErr == nil {
+				assert.NoError(err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+			}
+			assert.Equal(tc.expectedVersions, tc.driver.versions)
+		})
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	assert := assert.New(t)
+	driver := &fakeDriver{exists: true}
+	migrator := testMigrator(driver)
+
+	assert.Error(migrator.Baseline(3))
+	assert.NoError(migrator.Baseline(2))
+	assert.Equal([]int{1, 2}, driver.versions)
+	assert.Empty(driver.statements)
+
+	err := migrator.Baseline(1)
+	require.Error(t, err)
+	assert.Contains(err.Error(), errAlreadyVersioned.Error())
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, dir := range []string{"cassandra", "postgres"} {
+		t.Run(dir, func(t *testing.T) {
+			loaded, err := loadMigrations(migrations, dir, dir == "cassandra")
+			require.NoError(t, err)
+			for _, migration := range loaded {
+				assert.NotEmpty(t, migration.Statements)
+				for _, statement := range migration.Statements {
+					assert.NotEmpty(t, statement)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		description      string
+		files            fstest.MapFS
+		expectedVersions []int
+		expectedErr      error
+	}{
+		{
+			description: "Sorted",
+			files: fstest.MapFS{
+				"migrations/test/0002_b.cql": {Data: []byte("b;")},
+				"migrations/test/0001_a.cql": {Data: []byte("a;")},
+			},
+			expectedVersions: []int{1, 2},
+		},
+		{
+			description: "Gap",
+			files: fstest.MapFS{
+				"migrations/test/0001_a.cql": {Data: []byte("a;")},
+				"migrations/test/0003_c.cql": {Data: []byte("c;")},
+			},
+			expectedErr: errMigrationGap,
+		},
+		{
+			description: "Bad Name",
+			files: fstest.MapFS{
+				"migrations/test/first.cql": {Data: []byte("a;")},
+			},
+			expectedErr: errBadMigrationName,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			loaded, err := loadMigrations(tc.files, "test", true)
+			if tc.expectedErr != nil {
+				require.Error(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			versions := []int{}
+			for _, migration := range loaded {
+				versions = append(versions, migration.Version)
+			}
+			assert.Equal(tc.expectedVersions, versions)
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	assert := assert.New(t)
+	script := `-- a comment
+CREATE TABLE a (id int,
+    name varchar);
+
+CREATE INDEX b ON a (name);
+ALTER TABLE a ADD c int`
+	assert.Equal([]string{
+		"CREATE TABLE a (id int,\n    name varchar)",
+		"CREATE INDEX b ON a (name)",
+		"ALTER TABLE a ADD c int",
+	}, splitStatements(script))
+}
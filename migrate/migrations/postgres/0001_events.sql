@@ -0,0 +1,29 @@
+CREATE SCHEMA IF NOT EXISTS devices;
+
+-- the events table is range partitioned on death_date so that partitions can
+-- be dropped once expired.  Everything lands in the default partition until
+-- partition management is turned on.
+CREATE TABLE IF NOT EXISTS devices.events (
+    record_id BIGSERIAL,
+    shard INT NOT NULL DEFAULT 0,
+    type INT,
+    device_id VARCHAR NOT NULL,
+    birth_date BIGINT,
+    death_date BIGINT NOT NULL,
+    data BYTEA,
+    nonce BYTEA,
+    alg VARCHAR,
+    kid VARCHAR,
+    row_id VARCHAR,
+    PRIMARY KEY (death_date, record_id)
+) PARTITION BY RANGE (death_date);
+CREATE TABLE IF NOT EXISTS devices.events_default PARTITION OF devices.events DEFAULT;
+
+-- reads are by device, newest first; pruning is by shard and death date.
+CREATE INDEX IF NOT EXISTS events_device_id_birth_date_idx ON devices.events (device_id, birth_date DESC);
+CREATE INDEX IF NOT EXISTS events_shard_death_date_idx ON devices.events (shard, death_date);
+
+CREATE TABLE IF NOT EXISTS devices.blacklist (
+    id VARCHAR PRIMARY KEY,
+    reason VARCHAR
+);
@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package migrate
+
+import (
+	"database/sql"
+)
+
+// migrationLockID is the postgres advisory lock held while changing the
+// schema, so that instances migrating at the same time take turns.
+const migrationLockID = 7362501
+
+// postgresDriver runs each migration in a transaction, so a migration either
+// applies fully or not at all.
+type postgresDriver struct {
+	db *sql.DB
+}
+
+// NewPostgres creates a Migrator for a Postgres database.
+func NewPostgres(db *sql.DB) (*Migrator, error) {
+	return newMigrator(&postgresDriver{db: db}, "postgres", false)
+}
+
+func (d *postgresDriver) init() error {
+	return d.inTransaction(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`CREATE SCHEMA IF NOT EXISTS devices;
+			CREATE TABLE IF NOT EXISTS devices.schema_version (
+				version INTEGER PRIMARY KEY,
+				name TEXT NOT NULL,
+				applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			)`)
+		return err
+	})
+}
+
+func (d *postgresDriver) version() (int, error) {
+	var version int
+	err := d.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM devices.schema_version").Scan(&version)
+	return version, err
+}
+
+func (d *postgresDriver) hasSchema() (bool, error) {
+	var exists bool
+	err := d.db.QueryRow("SELECT to_regclass('devices.events') IS NOT NULL").Scan(&exists)
+	return exists, err
+}
+
+func (d *postgresDriver) apply(m Migration) error {
+	return d.inTransaction(func(tx *sql.Tx) error {
+		// another instance may have applied it while we waited for the lock.
+		var applied bool
+		err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM devices.schema_version WHERE version = $1)", m.Version).Scan(&applied)
+		if err != nil || applied {
+			return err
+		}
+		for _, statement := range m.Statements {
+			if _, err = tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec("INSERT INTO devices.schema_version (version, name) VALUES ($1, $2)", m.Version, m.Name)
+		return err
+	})
+}
+
+// inTransaction runs f in a transaction holding the migration lock.
+func (d *postgresDriver) inTransaction(f func(tx *sql.Tx) error) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec("SELECT pg_advisory_xact_lock($1)", migrationLockID); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err = f(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package postgresql
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/goph/emperror"
+)
+
+// The sslmode values understood by the postgres driver.
+const (
+	SSLModeDisable    = "disable"
+	SSLModeRequire    = "require"
+	SSLModeVerifyCA   = "verify-ca"
+	SSLModeVerifyFull = "verify-full"
+)
+
+var (
+	errUnsupportedSSLMode = errors.New("unsupported sslmode")
+	errPasswordEnvNotSet  = errors.New("password environment variable not set")
+)
+
+// resolvePassword finds the password to connect with.  Password is used if
+// set, then the contents of PasswordFile, and then the value of the
+// environment variable named by PasswordEnv.  An empty string is returned if
+// none of them are configured.
+func resolvePassword(config Config) (string, error) {
+	switch {
+	case config.Password != "":
+		return config.Password, nil
+	case config.PasswordFile != "":
+		contents, err := os.ReadFile(config.PasswordFile)
+		if err != nil {
+			return "", emperror.WrapWith(err, "Reading password file failed", "password file", config.PasswordFile)
+		}
+		// files commonly end with a newline that isn't part of the password.
+		return strings.TrimRight(string(contents), "\r\n"), nil
+	case config.PasswordEnv != "":
+		password, ok := os.LookupEnv(config.PasswordEnv)
+		if !ok {
+			return "", emperror.WrapWith(errPasswordEnvNotSet, "Reading password failed", "password env", config.PasswordEnv)
+		}
+		return password, nil
+	}
+	return "", nil
+}
+
+// sslMode determines the sslmode to connect with.  When no mode is
+// configured, verify-full is used if all of the certificate files are given,
+// otherwise the connection is insecure.
+func sslMode(config Config) (string, error) {
+	switch config.SSLMode {
+	case "":
+		if config.SSLCert == "" || config.SSLKey == "" || config.SSLRootCert == "" {
+			return SSLModeDisable, nil
+		}
+		return SSLModeVerifyFull, nil
+	case SSLModeDisable, SSLModeRequire, SSLModeVerifyCA, SSLModeVerifyFull:
+		return config.SSLMode, nil
+	}
+	return "", emperror.WrapWith(errUnsupportedSSLMode, "Invalid postgres config", "sslmode", config.SSLMode)
+}
+
+// buildConnectionURL creates the url handed to the postgres driver.  The
+// result holds the password, so it must never be logged or added to an
+// error; use redactedURL for that.
+func buildConnectionURL(config Config, password string) (*url.URL, error) {
+	mode, err := sslMode(config)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	// pq expects seconds
+	query.Set("connect_timeout", strconv.Itoa(int(config.ConnectTimeout.Seconds())))
+	// pq expects milliseconds
+	query.Set("statement_timeout", strconv.Itoa(int(float64(config.OpTimeout.Nanoseconds())/1000000)))
+
+	switch {
+	case config.TLS.IsSet():
+		// the tls dialer negotiates ssl, so pq must not try to.
+		query.Set("sslmode", SSLModeDisable)
+	default:
+		query.Set("sslmode", mode)
+		if mode != SSLModeDisable {
+			setIfNotEmpty(query, "sslrootcert", config.SSLRootCert)
+			setIfNotEmpty(query, "sslkey", config.SSLKey)
+			setIfNotEmpty(query, "sslcert", config.SSLCert)
+		}
+	}
+
+	u := &url.URL{
+		Scheme:   "postgresql",
+		Host:     config.Server,
+		Path:     "/" + config.Database,
+		RawQuery: query.Encode(),
+	}
+	if password != "" {
+		u.User = url.UserPassword(config.Username, password)
+	} else if config.Username != "" {
+		u.User = url.User(config.Username)
+	}
+	return u, nil
+}
+
+// redactedURL returns the connection url with the password masked, so that
+// it is safe to log.
+func redactedURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+func setIfNotEmpty(values url.Values, key string, value string) {
+	if value != "" {
+		values.Set(key, value)
+	}
+}
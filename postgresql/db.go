@@ -6,16 +6,20 @@
 package postgresql
 
 import (
+	"crypto/tls"
 	"database/sql"
 	"errors"
-	"strconv"
+	"sync"
 	"time"
 
 	db "github.com/xmidt-org/codex-db"
 	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/xmidt-org/codex-db/migrate"
+	"github.com/xmidt-org/codex-db/tlsconfig"
 
 	"github.com/go-kit/kit/metrics/provider"
 	"github.com/goph/emperror"
+	"github.com/lib/pq"
 
 	"github.com/InVisionApp/go-health/v2"
 	"github.com/InVisionApp/go-health/v2/checkers"
@@ -35,17 +39,48 @@ const (
 	defaultPingInterval   = time.Second
 	defaultMaxIdleConns   = 2
 	defaultMaxOpenConns   = 0
+
+	// defaultMaxInsertParams is the most bind parameters postgres allows in
+	// a single statement.
+	defaultMaxInsertParams = 65535
 )
 
 // Config contains the initial configuration information needed to create a
 // postgresql db connection.
 type Config struct {
-	Server         string
-	Username       string
-	Database       string
-	SSLRootCert    string
-	SSLKey         string
-	SSLCert        string
+	Server   string
+	Username string
+
+	// Password to authenticate with.  If empty, PasswordFile and then
+	// PasswordEnv are checked.
+	Password string
+
+	// PasswordFile is the path of a file holding the password.  The file is
+	// read on connect, so a rotated password is picked up by reconnecting.
+	PasswordFile string
+
+	// PasswordEnv is the name of an environment variable holding the password.
+	PasswordEnv string
+
+	Database string
+
+	// SSLMode is the libpq sslmode to connect with: disable, require,
+	// verify-ca, or verify-full.  If empty, verify-full is used when
+	// SSLRootCert, SSLKey, and SSLCert are all set, otherwise disable.
+	SSLMode     string
+	SSLRootCert string
+	SSLKey      string
+	SSLCert     string
+
+	// TLS configures encryption from TLS material held in memory, such as
+	// PEM blobs or a tls.Config given as its Base, instead of the certificate
+	// file settings, which can't be used with it.  Verification is done as
+	// the resulting tls.Config specifies; if no ServerName is set, the
+	// server's host is used.  SSLMode can be left empty, and an SSLMode of
+	// disable, or one of the verify modes with InsecureSkipVerify, is an
+	// error.  It is validated when connecting.
+	TLS tlsconfig.Config
+
 	NumRetries     int
 	PruneLimit     int
 	WaitTimeMult   time.Duration
@@ -59,6 +94,56 @@ type Config struct {
 	MaxOpenConns int
 
 	PingInterval time.Duration
+
+	// MaxInsertParams is the max number of bind parameters used in a single
+	// insert statement.  Inserts needing more are split into multiple
+	// statements.  If unset or above the postgres limit of 65535, the limit is
+	// used.
+	MaxInsertParams int
+
+	// InsertInTransaction runs the statements of a split insert in a single
+	// transaction, so that either all of the records are inserted or none are.
+	InsertInTransaction bool
+
+	// AutoMigrate creates the schema, or upgrades it to the latest version,
+	// when connecting.
+	AutoMigrate bool
+
+	// Baseline, if set, records that a schema created by hand is at this
+	// version when connecting, before AutoMigrate runs.  Connecting fails if
+	// the schema already has a version, so it is only for a one-off
+	// connection such as codexdb schema baseline.
+	Baseline int
+
+	// SchemaCheck compares the live schema with what the driver needs when
+	// connecting, reporting missing tables, columns, and indexes and columns
+	// of the wrong type.  It is off, warn to log any drift with the health
+	// logger, or error to fail with a migrate.SchemaDrift, which
+	// migrate.AsSchemaDrift finds in the error.  Defaults to off.
+	SchemaCheck string
+
+	// Partitioning configures creating and dropping partitions of a range
+	// partitioned events table.
+	Partitioning PartitionConfig
+
+	// Notify configures notifications of inserted records, which can be
+	// received with Connection.Subscribe.
+	Notify NotifyConfig
+
+	// Replicas are the servers of streaming replicas of the database at
+	// Server.  GetRecords, GetRecordsOfType, GetDeviceList, and GetBlacklist
+	// are spread across the healthy replicas, and fall back to Server if none
+	// are healthy.  The replicas are connected to with the same credentials
+	// and TLS settings.
+	Replicas []string
+
+	// MaxReplicaLag is how far behind Server a replica can be before reads
+	// stop going to it.
+	MaxReplicaLag time.Duration
+
+	// ReplicaCheckInterval is how often the replicas' health and lag are
+	// checked.
+	ReplicaCheckInterval time.Duration
 }
 
 // Connection manages the connection to the postgresql database, and maintains
@@ -66,26 +151,33 @@ type Config struct {
 type Connection struct {
 	finder       finder
 	findList     findList
+	listEditor   listEditor
 	deviceFinder deviceFinder
 	multiInsert  multiInserter
 	deleter      deleter
 	closer       closer
 	pinger       pinger
 	stats        stats
+	partitioner  partitioner
 	gennericDB   *sql.DB
-
-	pruneLimit  int
-	health      *health.Health
-	measures    Measures
-	stopThreads []chan struct{}
+	replicas     *replicaRouter
+
+	pruneLimit      int
+	partitionConfig PartitionConfig
+	notifyConfig    NotifyConfig
+	newListener     func() listener
+	notifier        *notifier
+	notifierLock    sync.Mutex
+	health          *health.Health
+	measures        Measures
+	stopThreads     []chan struct{}
 }
 
 // CreateDbConnection creates db connection and returns the struct to the consumer.
 func CreateDbConnection(config Config, provider provider.Provider, health *health.Health) (*Connection, error) {
 	var (
-		conn          *dbDecorator
-		err           error
-		connectionURL string
+		conn *dbDecorator
+		err  error
 	)
 
 	dbConn := Connection{
@@ -94,38 +186,63 @@ func CreateDbConnection(config Config, provider provider.Provider, health *healt
 	}
 
 	validateConfig(&config)
+	dbConn.partitionConfig = config.Partitioning
+	dbConn.notifyConfig = config.Notify
 
-	// pq expects seconds
-	connectTimeout := strconv.Itoa(int(config.ConnectTimeout.Seconds()))
-
-	// pq expects milliseconds
-	opTimeout := strconv.Itoa(int(float64(config.OpTimeout.Nanoseconds()) / 1000000))
-
-	// include timeout when connecting
-	// if missing a cert, connect insecurely
-	if config.SSLCert == "" || config.SSLKey == "" || config.SSLRootCert == "" {
-		connectionURL = "postgresql://" + config.Username + "@" + config.Server + "/" +
-			config.Database + "?sslmode=disable&connect_timeout=" + connectTimeout +
-			"&statement_timeout=" + opTimeout
-	} else {
-		connectionURL = "postgresql://" + config.Username + "@" + config.Server + "/" +
-			config.Database + "?sslmode=verify-full&sslrootcert=" + config.SSLRootCert +
-			"&sslkey=" + config.SSLKey + "&sslcert=" + config.SSLCert + "&connect_timeout=" +
-			connectTimeout + "&statement_timeout=" + opTimeout
+	tlsConfig, err := resolveTLSConfig(config)
+	if err != nil {
+		return &Connection{}, emperror.Wrap(err, "Connecting to database failed")
+	}
+	config.SchemaCheck, err = migrate.ParseSchemaCheck(config.SchemaCheck)
+	if err != nil {
+		return &Connection{}, emperror.Wrap(err, "Invalid postgres config")
+	}
+	password, err := resolvePassword(config)
+	if err != nil {
+		return &Connection{}, emperror.Wrap(err, "Connecting to database failed")
+	}
+	connectionURL, err := buildConnectionURL(config, password)
+	if err != nil {
+		return &Connection{}, emperror.Wrap(err, "Connecting to database failed")
 	}
 
-	conn, err = connect(connectionURL)
+	conn, err = connect(connectionURL.String(), tlsConfig)
 
 	// retry if it fails
 	waitTime := 1 * time.Second
 	for attempt := 0; attempt < config.NumRetries && err != nil; attempt++ {
 		time.Sleep(waitTime)
-		conn, err = connect(connectionURL)
+		conn, err = connect(connectionURL.String(), tlsConfig)
 		waitTime = waitTime * config.WaitTimeMult
 	}
 
 	if err != nil {
-		return &Connection{}, emperror.WrapWith(err, "Connecting to database failed", "connection url", connectionURL)
+		return &Connection{}, emperror.WrapWith(err, "Connecting to database failed", "connection url", redactedURL(connectionURL))
+	}
+
+	conn.maxInsertParams = config.MaxInsertParams
+	conn.insertInTransaction = config.InsertInTransaction
+	if config.Notify.Enabled {
+		conn.notifyChannel = config.Notify.Channel
+	}
+
+	if config.Baseline != 0 {
+		if err = baselineSchema(conn, config.Baseline); err != nil {
+			conn.Close()
+			return &Connection{}, emperror.Wrap(err, "Baselining database schema failed")
+		}
+	}
+	if config.AutoMigrate {
+		if err = migrateSchema(conn); err != nil {
+			conn.Close()
+			return &Connection{}, emperror.Wrap(err, "Migrating database schema failed")
+		}
+	}
+	if config.SchemaCheck != migrate.SchemaCheckOff {
+		if err = dbConn.checkSchema(conn, config.SchemaCheck); err != nil {
+			conn.Close()
+			return &Connection{}, err
+		}
 	}
 
 	emptyRecord := db.Record{}
@@ -137,23 +254,79 @@ func CreateDbConnection(config Config, provider provider.Provider, health *healt
 	dbConn.setDB(conn)
 	dbConn.setupHealthCheck(config.PingInterval)
 	dbConn.setupMetrics()
+	dbConn.setupListener(connectionURL.String(), tlsConfig)
 	dbConn.configure(config.MaxIdleConns, config.MaxOpenConns)
 
+	if len(config.Replicas) > 0 {
+		err = dbConn.setupReplicas(config, tlsConfig, password, conn)
+		if err != nil {
+			dbConn.Close()
+			return &Connection{}, emperror.Wrap(err, "Connecting to database failed")
+		}
+	}
+
+	dbConn.setupExecuterMeasures()
+
+	if config.Partitioning.Enabled {
+		err = dbConn.setupPartitioning()
+		if err != nil {
+			dbConn.Close()
+			return &Connection{}, emperror.Wrap(err, "Connecting to database failed")
+		}
+	}
+
 	return &dbConn, nil
 }
 
 func (c *Connection) setDB(conn *dbDecorator) {
 	c.finder = conn
 	c.findList = conn
+	c.listEditor = conn
 	c.deviceFinder = conn
 	c.multiInsert = conn
 	c.deleter = conn
 	c.closer = conn
 	c.pinger = conn
 	c.stats = conn
+	c.partitioner = conn
 	c.gennericDB = conn.DB.DB()
 }
 
+func migrateSchema(conn *dbDecorator) error {
+	migrator, err := migrate.NewPostgres(conn.DB.DB())
+	if err != nil {
+		return err
+	}
+	return migrator.MigrateUp()
+}
+
+func baselineSchema(conn *dbDecorator, version int) error {
+	migrator, err := migrate.NewPostgres(conn.DB.DB())
+	if err != nil {
+		return err
+	}
+	return migrator.Baseline(version)
+}
+
+// checkSchema fails with the drift found if the schema check is set to
+// error, and otherwise logs it as a warning.
+func (c *Connection) checkSchema(conn *dbDecorator, mode string) error {
+	drift, err := migrate.CheckPostgres(conn.DB.DB())
+	if err != nil {
+		return emperror.Wrap(err, "Checking database schema failed")
+	}
+	if drift.Empty() {
+		return nil
+	}
+	if mode == migrate.SchemaCheckError {
+		return emperror.Wrap(drift, "Checking database schema failed")
+	}
+	if c.health != nil && c.health.Logger != nil {
+		c.health.Logger.Warn(drift.Error())
+	}
+	return nil
+}
+
 func validateConfig(config *Config) {
 	zeroDuration := time.Duration(0) * time.Second
 
@@ -183,6 +356,17 @@ func validateConfig(config *Config) {
 	if config.MaxOpenConns < 0 {
 		config.MaxOpenConns = defaultMaxOpenConns
 	}
+	validatePartitionConfig(&config.Partitioning)
+	validateNotifyConfig(&config.Notify)
+	if config.MaxReplicaLag <= 0 {
+		config.MaxReplicaLag = defaultMaxReplicaLag
+	}
+	if config.ReplicaCheckInterval <= 0 {
+		config.ReplicaCheckInterval = defaultReplicaCheckInterval
+	}
+	if config.MaxInsertParams <= 0 || config.MaxInsertParams > defaultMaxInsertParams {
+		config.MaxInsertParams = defaultMaxInsertParams
+	}
 }
 
 func (c *Connection) configure(maxIdleConns int, maxOpenConns int) {
@@ -209,6 +393,70 @@ func (c *Connection) setupHealthCheck(interval time.Duration) {
 	})
 }
 
+// setupReplicas connects to the replicas and routes reads to them.  Replicas
+// that can't be reached yet are connected to once they pass a health check.
+func (c *Connection) setupReplicas(config Config, tlsConfig *tls.Config, password string, primary *dbDecorator) error {
+	router := &replicaRouter{
+		primary:  primary,
+		maxLag:   config.MaxReplicaLag,
+		measures: c.measures,
+	}
+	for _, server := range config.Replicas {
+		replicaConfig := config
+		replicaConfig.Server = server
+		connectionURL, err := buildConnectionURL(replicaConfig, password)
+		if err != nil {
+			router.close()
+			return err
+		}
+		conn, err := connectLazily(connectionURL.String(), tlsConfig)
+		if err != nil {
+			router.close()
+			return emperror.WrapWith(err, "Connecting to replica failed", "connection url", redactedURL(connectionURL))
+		}
+		conn.DB.DB().SetMaxIdleConns(config.MaxIdleConns)
+		conn.DB.DB().SetMaxOpenConns(config.MaxOpenConns)
+		router.replicas = append(router.replicas, &replica{server: server, db: conn})
+	}
+
+	router.checkReplicas()
+	c.replicas = router
+	c.finder = router
+	c.findList = router
+	c.deviceFinder = router
+	replicaStop := doEvery(config.ReplicaCheckInterval, router.checkReplicas)
+	c.stopThreads = append(c.stopThreads, replicaStop)
+	return nil
+}
+
+// setupPartitioning makes sure the partitions needed now exist before any
+// records are inserted, then keeps managing them in the background.
+func (c *Connection) setupPartitioning() error {
+	err := c.ManagePartitions()
+	if err != nil {
+		return err
+	}
+	partitionStop := doEvery(c.partitionConfig.CheckInterval, func() {
+		// failures are counted in the metrics; the next check tries again.
+		_ = c.ManagePartitions()
+	})
+	c.stopThreads = append(c.stopThreads, partitionStop)
+	return nil
+}
+
+// setupListener prepares to open the connection used for listening to
+// notifications, which is done once something subscribes.
+func (c *Connection) setupListener(connectionURL string, tlsConfig *tls.Config) {
+	c.newListener = func() listener {
+		minReconnect := c.notifyConfig.MinReconnectInterval
+		maxReconnect := c.notifyConfig.MaxReconnectInterval
+		if tlsConfig != nil {
+			return pq.NewDialListener(tlsDialer{config: tlsConfig}, connectionURL, minReconnect, maxReconnect, c.listenerEventCallback)
+		}
+		return pq.NewListener(connectionURL, minReconnect, maxReconnect, c.listenerEventCallback)
+	}
+}
+
 func (c *Connection) setupMetrics() {
 	// baseline
 	startStats := c.stats.getStats()
@@ -255,7 +503,7 @@ func (c *Connection) GetRecordsOfType(deviceID string, limit int, eventType db.E
 	var (
 		deviceInfo []db.Record
 	)
-	err := c.finder.findRecords(&deviceInfo, limit, "device_id = ? AND record_type = ?", deviceID, eventType)
+	err := c.finder.findRecords(&deviceInfo, limit, "device_id = ? AND type = ?", deviceID, eventType)
 	if err != nil {
 		c.measures.SQLQueryFailureCount.With(db.TypeLabel, db.ReadType).Add(1.0)
 		return []db.Record{}, emperror.WrapWith(err, "Getting records from database failed", "device id", deviceID)
@@ -294,6 +542,29 @@ func (c *Connection) GetBlacklist() (list []blacklist.BlackListedItem, err error
 	return
 }
 
+// AddBlacklist adds a device id or pattern to the blacklist, replacing its
+// reason if it is already there.
+func (c *Connection) AddBlacklist(item blacklist.BlackListedItem) error {
+	err := c.listEditor.addBlacklist(item)
+	if err != nil {
+		c.measures.SQLQueryFailureCount.With(db.TypeLabel, db.BlacklistWriteType).Add(1.0)
+		return emperror.WrapWith(err, "Adding to blacklist failed", "id", item.ID)
+	}
+	c.measures.SQLQuerySuccessCount.With(db.TypeLabel, db.BlacklistWriteType).Add(1.0)
+	return nil
+}
+
+// RemoveBlacklist removes a device id or pattern from the blacklist.
+func (c *Connection) RemoveBlacklist(id string) error {
+	err := c.listEditor.removeBlacklist(id)
+	if err != nil {
+		c.measures.SQLQueryFailureCount.With(db.TypeLabel, db.BlacklistWriteType).Add(1.0)
+		return emperror.WrapWith(err, "Removing from blacklist failed", "id", id)
+	}
+	c.measures.SQLQuerySuccessCount.With(db.TypeLabel, db.BlacklistWriteType).Add(1.0)
+	return nil
+}
+
 // GetDeviceList returns a list of device ids where the device id is greater
 // than the offset device id.
 func (c *Connection) GetDeviceList(offset string, limit int) ([]string, error) {
@@ -347,6 +618,15 @@ func (c *Connection) Close() error {
 		stopThread <- struct{}{}
 	}
 
+	// subscriptions can't outlive the connection.
+	_ = c.closeNotifier()
+
+	if c.replicas != nil {
+		if err := c.replicas.close(); err != nil {
+			return emperror.WrapWith(err, "Closing replica connections failed")
+		}
+	}
+
 	err := c.closer.close()
 	if err != nil {
 		return emperror.WrapWith(err, "Closing connection failed")
@@ -6,15 +6,19 @@ package postgresql
 import (
 	// Import GORM-related packages.
 
+	"crypto/tls"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/xmidt-org/codex-db"
 	"github.com/xmidt-org/codex-db/blacklist"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/lib/pq"
 )
 
 type (
@@ -25,6 +29,10 @@ type (
 	findList interface {
 		findBlacklist(out *[]blacklist.BlackListedItem) error
 	}
+	listEditor interface {
+		addBlacklist(item blacklist.BlackListedItem) error
+		removeBlacklist(id string) error
+	}
 	deviceFinder interface {
 		getList(offset string, limit int, where ...interface{}) ([]string, error)
 	}
@@ -43,10 +51,27 @@ type (
 	stats interface {
 		getStats() sql.DBStats
 	}
+	partitioner interface {
+		listPartitions() ([]string, error)
+		createPartition(name string, from int64, to int64) error
+		dropPartition(name string) error
+	}
+	execer interface {
+		Exec(query string, args ...interface{}) (sql.Result, error)
+		Query(query string, args ...interface{}) (*sql.Rows, error)
+	}
+)
+
+var (
+	errNoLag = errors.New("replication lag not returned")
 )
 
 type dbDecorator struct {
 	*gorm.DB
+
+	maxInsertParams     int
+	insertInTransaction bool
+	notifyChannel       string
 }
 
 func (b *dbDecorator) findRecords(out *[]db.Record, limit int, where ...interface{}) error {
@@ -68,6 +93,17 @@ func (b *dbDecorator) findBlacklist(out *[]blacklist.BlackListedItem) error {
 	return db.Error
 }
 
+func (b *dbDecorator) addBlacklist(item blacklist.BlackListedItem) error {
+	// Save inserts the item, or updates its reason if the id is there.
+	db := b.Save(&item)
+	return db.Error
+}
+
+func (b *dbDecorator) removeBlacklist(id string) error {
+	db := b.Delete(&blacklist.BlackListedItem{}, "id = ?", id)
+	return db.Error
+}
+
 func (b *dbDecorator) getList(offset string, limit int, where ...interface{}) ([]string, error) {
 	var result []string
 	// Raw SQL
@@ -80,35 +116,127 @@ func (b *dbDecorator) insert(records []db.Record) (int64, error) {
 	if len(records) == 0 {
 		return 0, errNoEvents
 	}
-	mainScope := b.DB.NewScope(records[0])
-	mainFields := mainScope.Fields()
-	quoted := make([]string, 0, len(mainFields))
-	for i := range mainFields {
+	chunks := chunkRecords(records, b.insertChunkSize())
+	if !b.insertInTransaction || len(chunks) == 1 {
+		return b.insertChunks(b.DB.DB(), chunks)
+	}
+
+	tx, err := b.DB.DB().Begin()
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := b.insertChunks(tx, chunks)
+	if err != nil {
+		// nothing was committed, so nothing was inserted.
+		_ = tx.Rollback()
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return rowsAffected, nil
+}
+
+// insertChunks runs one insert statement per chunk, stopping at the first
+// failure.  The rows affected by the chunks that succeeded are always
+// returned.
+func (b *dbDecorator) insertChunks(e execer, chunks [][]db.Record) (int64, error) {
+	var total int64
+	for _, chunk := range chunks {
+		statement, vars := b.buildInsert(chunk)
+		insert := execInsert
+		if b.notifyChannel != "" {
+			insert = b.notifyingInsert
+		}
+		rowsAffected, err := insert(e, statement, vars)
+		total += rowsAffected
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func execInsert(e execer, statement string, vars []interface{}) (int64, error) {
+	result, err := e.Exec(statement, vars...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// notifyingInsert inserts the records and sends a notification for each one
+// in the same statement, so that notifications are only delivered for
+// records that were committed.
+func (b *dbDecorator) notifyingInsert(e execer, statement string, vars []interface{}) (int64, error) {
+	vars = append(vars, b.notifyChannel)
+	rows, err := e.Query(fmt.Sprintf(`WITH inserted AS (%s RETURNING device_id, record_id)
+		SELECT pg_notify($%d, json_build_object('device_id', device_id, 'record_id', record_id)::text) FROM inserted`,
+		statement, len(vars)), vars...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	var rowsAffected int64
+	for rows.Next() {
+		rowsAffected++
+	}
+	return rowsAffected, rows.Err()
+}
+
+// insertChunkSize returns the number of records that can go into a single
+// insert statement without passing the bind parameter limit.
+func (b *dbDecorator) insertChunkSize() int {
+	maxParams := b.maxInsertParams
+	if maxParams <= 0 || maxParams > defaultMaxInsertParams {
+		maxParams = defaultMaxInsertParams
+	}
+	if b.notifyChannel != "" {
+		// leave room for the channel name.
+		maxParams--
+	}
+	size := maxParams / len(b.insertColumns(db.Record{}))
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+func (b *dbDecorator) insertColumns(record db.Record) []*gorm.Field {
+	fields := b.DB.NewScope(record).Fields()
+	columns := make([]*gorm.Field, 0, len(fields))
+	for i := range fields {
 		// If primary key has blank value (0 for int, "" for string, nil for interface ...), skip it.
 		// If field is ignore field, skip it.
-		if (mainFields[i].IsPrimaryKey && mainFields[i].IsBlank) || (mainFields[i].IsIgnored) {
+		if (fields[i].IsPrimaryKey && fields[i].IsBlank) || (fields[i].IsIgnored) {
 			continue
 		}
+		columns = append(columns, fields[i])
+	}
+	return columns
+}
+
+// buildInsert creates a single multi-row insert statement and its variables
+// for the records given.
+func (b *dbDecorator) buildInsert(records []db.Record) (string, []interface{}) {
+	mainScope := b.DB.NewScope(records[0])
+	mainFields := b.insertColumns(records[0])
+	quoted := make([]string, 0, len(mainFields))
+	for i := range mainFields {
 		quoted = append(quoted, mainScope.Quote(mainFields[i].DBName))
 	}
 	placeholdersArr := make([]string, 0, len(records))
 
 	for _, obj := range records {
-		scope := b.DB.NewScope(obj)
-		fields := scope.Fields()
+		fields := b.insertColumns(obj)
 		placeholders := make([]string, 0, len(fields))
 		for i := range fields {
-			if (fields[i].IsPrimaryKey && fields[i].IsBlank) || (fields[i].IsIgnored) {
-				continue
-			}
 			// the trick it to use mainScope instead of scope so the number keeps on increasing
 			// aka $1, $2, $2, etc.
 			placeholders = append(placeholders, mainScope.AddToVars(fields[i].Field.Interface()))
 		}
 		placeholdersStr := "(" + strings.Join(placeholders, ", ") + ")"
 		placeholdersArr = append(placeholdersArr, placeholdersStr)
-		// add real variables for the replacement of placeholders' '?' letter later.
-		mainScope.SQLVars = append(mainScope.SQLVars, scope.SQLVars...)
 	}
 
 	mainScope.Raw(fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
@@ -116,12 +244,19 @@ func (b *dbDecorator) insert(records []db.Record) (int64, error) {
 		strings.Join(quoted, ", "),
 		strings.Join(placeholdersArr, ", "),
 	))
+	return mainScope.SQL, mainScope.SQLVars
+}
 
-	result, err := mainScope.SQLDB().Exec(mainScope.SQL, mainScope.SQLVars...)
-	if err != nil {
-		return 0, err
+// chunkRecords splits records into slices holding at most size records each.
+func chunkRecords(records []db.Record, size int) [][]db.Record {
+	if size < 1 || len(records) <= size {
+		return [][]db.Record{records}
 	}
-	return result.RowsAffected()
+	chunks := make([][]db.Record, 0, (len(records)+size-1)/size)
+	for size < len(records) {
+		records, chunks = records[size:], append(chunks, records[0:size:size])
+	}
+	return append(chunks, records)
 }
 
 func (b *dbDecorator) delete(value *db.Record, limit int, where ...interface{}) (int64, error) {
@@ -134,6 +269,97 @@ func (b *dbDecorator) delete(value *db.Record, limit int, where ...interface{})
 	return db.RowsAffected, db.Error
 }
 
+func (b *dbDecorator) listPartitions() ([]string, error) {
+	var result []string
+	db := b.Raw(`SELECT c.relname FROM pg_catalog.pg_inherits i
+		JOIN pg_catalog.pg_class c ON c.oid = i.inhrelid
+		JOIN pg_catalog.pg_class p ON p.oid = i.inhparent
+		JOIN pg_catalog.pg_namespace n ON n.oid = p.relnamespace
+		WHERE n.nspname = 'devices' AND p.relname = 'events'`).Pluck("relname", &result)
+	return result, db.Error
+}
+
+// createPartition creates the partition, moving the rows in its range out of
+// the default partition, if there is one.  Postgres won't create a partition
+// while the default partition holds rows that belong in it.
+func (b *dbDecorator) createPartition(name string, from int64, to int64) error {
+	var defaults []string
+	db := b.Raw(`SELECT c.relname FROM pg_catalog.pg_inherits i
+		JOIN pg_catalog.pg_class c ON c.oid = i.inhrelid
+		JOIN pg_catalog.pg_class p ON p.oid = i.inhparent
+		JOIN pg_catalog.pg_namespace n ON n.oid = p.relnamespace
+		WHERE n.nspname = 'devices' AND p.relname = 'events'
+		AND pg_catalog.pg_get_expr(c.relpartbound, c.oid) = 'DEFAULT'`).Pluck("relname", &defaults)
+	if db.Error != nil {
+		return db.Error
+	}
+
+	// partition names and bounds can't be bind parameters.  They are
+	// generated by us, so they are safe to format into the statement.
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS devices.%s PARTITION OF devices.events FOR VALUES FROM (%d) TO (%d)", name, from, to)
+	if len(defaults) == 0 {
+		return b.Exec(create).Error
+	}
+
+	// detach the default partition, create the new one, move the rows that
+	// belong in it, and reattach the default, all or nothing.
+	defaultPartition := "devices." + pq.QuoteIdentifier(defaults[0])
+	statements := []string{
+		fmt.Sprintf("ALTER TABLE devices.events DETACH PARTITION %s", defaultPartition),
+		create,
+		fmt.Sprintf(`WITH moved AS (DELETE FROM %s WHERE death_date >= %d AND death_date < %d RETURNING *)
+			INSERT INTO devices.events SELECT * FROM moved`, defaultPartition, from, to),
+		fmt.Sprintf("ALTER TABLE devices.events ATTACH PARTITION %s DEFAULT", defaultPartition),
+	}
+	tx := b.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+func (b *dbDecorator) dropPartition(name string) error {
+	// detach and drop together, so a failure can't leave a detached table
+	// behind that we no longer know about.
+	tx := b.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if err := tx.Exec(fmt.Sprintf("ALTER TABLE devices.events DETACH PARTITION devices.%s", name)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Exec(fmt.Sprintf("DROP TABLE devices.%s", name)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// replicationLag returns how far a replica is behind its primary.  A replica
+// that has replayed everything it received isn't lagging, even if the last
+// transaction replayed is old.
+func (b *dbDecorator) replicationLag() (time.Duration, error) {
+	var lag []float64
+	db := b.Raw(`SELECT CASE
+		WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END AS lag`).Pluck("lag", &lag)
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	if len(lag) == 0 {
+		return 0, errNoLag
+	}
+	return time.Duration(lag[0] * float64(time.Second)), nil
+}
+
 func (b *dbDecorator) ping() error {
 	return b.DB.DB().Ping()
 }
@@ -146,14 +372,42 @@ func (b *dbDecorator) getStats() sql.DBStats {
 	return b.DB.DB().Stats()
 }
 
-func connect(connSpecStr string) (*dbDecorator, error) {
-	c, err := gorm.Open("postgres", connSpecStr)
+func connect(connSpecStr string, tlsConfig *tls.Config) (*dbDecorator, error) {
+	sqlDB, err := openDB(connSpecStr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	c, err := gorm.Open("postgres", sqlDB)
 
 	if err != nil {
+		sqlDB.Close()
 		return nil, err
 	}
 
-	db := &dbDecorator{c}
+	db := &dbDecorator{DB: c}
 
 	return db, nil
 }
+
+// connectLazily is like connect, but a database that can't be reached yet
+// isn't an error; the connection is retried as the database is used.
+func connectLazily(connSpecStr string, tlsConfig *tls.Config) (*dbDecorator, error) {
+	sqlDB, err := openDB(connSpecStr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	// gorm leaves a database it didn't open alone when the ping fails.
+	c, _ := gorm.Open("postgres", sqlDB)
+	return &dbDecorator{DB: c}, nil
+}
+
+func openDB(connSpecStr string, tlsConfig *tls.Config) (*sql.DB, error) {
+	connector, err := pq.NewConnector(connSpecStr)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		connector.Dialer(tlsDialer{config: tlsConfig})
+	}
+	return sql.OpenDB(connector), nil
+}
@@ -0,0 +1,240 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package postgresql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+)
+
+func TestChunkRecords(t *testing.T) {
+	records := []db.Record{
+		{DeviceID: "a"},
+		{DeviceID: "b"},
+		{DeviceID: "c"},
+		{DeviceID: "d"},
+		{DeviceID: "e"},
+	}
+	tests := []struct {
+		description    string
+		records        []db.Record
+		size           int
+		expectedChunks [][]db.Record
+	}{
+		{
+			description:    "Single Chunk",
+			records:        records,
+			size:           10,
+			expectedChunks: [][]db.Record{records},
+		},
+		{
+			description:    "Exact Fit",
+			records:        records,
+			size:           5,
+			expectedChunks: [][]db.Record{records},
+		},
+		{
+			description:    "Multiple Chunks",
+			records:        records,
+			size:           2,
+			expectedChunks: [][]db.Record{records[0:2], records[2:4], records[4:5]},
+		},
+		{
+			description:    "Chunk Per Record",
+			records:        records[:3],
+			size:           1,
+			expectedChunks: [][]db.Record{records[0:1], records[1:2], records[2:3]},
+		},
+		{
+			description:    "Invalid Size",
+			records:        records,
+			size:           0,
+			expectedChunks: [][]db.Record{records},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			chunks := chunkRecords(tc.records, tc.size)
+			assert.Equal(tc.expectedChunks, chunks)
+			total := 0
+			for _, c := range chunks {
+				total += len(c)
+			}
+			assert.Equal(len(tc.records), total)
+		})
+	}
+}
+
+// newMockDecorator returns a dbDecorator over a mocked database, along with
+// the number of bind parameters each record takes.
+func newMockDecorator(t *testing.T) (*dbDecorator, sqlmock.Sqlmock, int) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	conn, err := gorm.Open("postgres", sqlDB)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	b := &dbDecorator{DB: conn}
+	return b, mock, len(b.insertColumns(db.Record{}))
+}
+
+func TestInsertChunkSize(t *testing.T) {
+	b, _, columns := newMockDecorator(t)
+	tests := []struct {
+		description     string
+		maxInsertParams int
+		notifyChannel   string
+		expectedSize    int
+	}{
+		{
+			description:  "Default",
+			expectedSize: defaultMaxInsertParams / columns,
+		},
+		{
+			description:     "Above Postgres Limit",
+			maxInsertParams: defaultMaxInsertParams + 1,
+			expectedSize:    defaultMaxInsertParams / columns,
+		},
+		{
+			description:     "Configured",
+			maxInsertParams: 2 * columns,
+			expectedSize:    2,
+		},
+		{
+			description:     "Room For Notify Channel",
+			maxInsertParams: 2 * columns,
+			notifyChannel:   "records",
+			expectedSize:    1,
+		},
+		{
+			description:     "At Least One",
+			maxInsertParams: 1,
+			expectedSize:    1,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			b.maxInsertParams = tc.maxInsertParams
+			b.notifyChannel = tc.notifyChannel
+			assert.Equal(t, tc.expectedSize, b.insertChunkSize())
+		})
+	}
+}
+
+func TestInsert(t *testing.T) {
+	testErr := errors.New("test error")
+	records := []db.Record{
+		{DeviceID: "a"},
+		{DeviceID: "b"},
+		{DeviceID: "c"},
+		{DeviceID: "d"},
+		{DeviceID: "e"},
+	}
+	tests := []struct {
+		description         string
+		insertInTransaction bool
+		// expect sets up the statements for chunks of two records.
+		expect               func(mock sqlmock.Sqlmock)
+		expectedRowsAffected int64
+		expectedErr          error
+	}{
+		{
+			description: "Rows Summed Across Chunks",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			expectedRowsAffected: 5,
+		},
+		{
+			description: "Partial Failure",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("INSERT INTO").WillReturnError(testErr)
+			},
+			expectedRowsAffected: 2,
+			expectedErr:          testErr,
+		},
+		{
+			description:         "Transaction Committed",
+			insertInTransaction: true,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedRowsAffected: 5,
+		},
+		{
+			description:         "Transaction Rolled Back",
+			insertInTransaction: true,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("INSERT INTO").WillReturnError(testErr)
+				mock.ExpectRollback()
+			},
+			expectedErr: testErr,
+		},
+		{
+			description:         "Begin Error",
+			insertInTransaction: true,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin().WillReturnError(testErr)
+			},
+			expectedErr: testErr,
+		},
+		{
+			description:         "Commit Error",
+			insertInTransaction: true,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit().WillReturnError(testErr)
+			},
+			expectedErr: testErr,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			b, mock, columns := newMockDecorator(t)
+			b.maxInsertParams = 2 * columns
+			b.insertInTransaction = tc.insertInTransaction
+			tc.expect(mock)
+
+			rowsAffected, err := b.insert(records)
+			assert.Equal(tc.expectedRowsAffected, rowsAffected)
+			assert.Equal(tc.expectedErr, err)
+			assert.NoError(mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestInsertNotify(t *testing.T) {
+	assert := assert.New(t)
+	b, mock, columns := newMockDecorator(t)
+	// two records would fit without the channel name, but not with it.
+	b.maxInsertParams = 2 * columns
+	b.notifyChannel = "records"
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("WITH inserted AS").WillReturnRows(sqlmock.NewRows([]string{"pg_notify"}).AddRow(""))
+	}
+
+	rowsAffected, err := b.insert([]db.Record{{DeviceID: "a"}, {DeviceID: "b"}})
+	assert.NoError(err)
+	assert.Equal(int64(2), rowsAffected)
+	assert.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package postgresql
+
+import (
+	"time"
+
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+)
+
+// dbMeasuresDecorator records how long each database operation takes, the
+// same way the cassandra driver does.
+type dbMeasuresDecorator struct {
+	measures Measures
+
+	finder
+	findList
+	listEditor
+	deviceFinder
+	multiInserter
+	deleter
+	pinger
+}
+
+func (b *dbMeasuresDecorator) findRecords(out *[]db.Record, limit int, where ...interface{}) error {
+	now := time.Now()
+	err := b.finder.findRecords(out, limit, where...)
+	b.measures.SQLDuration.With(db.TypeLabel, db.ReadType).Observe(time.Since(now).Seconds())
+
+	return err
+}
+
+func (b *dbMeasuresDecorator) findRecordsToDelete(limit int, shard int, deathDate int64) ([]db.RecordToDelete, error) {
+	now := time.Now()
+	records, err := b.finder.findRecordsToDelete(limit, shard, deathDate)
+	b.measures.SQLDuration.With(db.TypeLabel, db.ReadType).Observe(time.Since(now).Seconds())
+
+	return records, err
+}
+
+func (b *dbMeasuresDecorator) getList(offset string, limit int, where ...interface{}) ([]string, error) {
+	now := time.Now()
+	result, err := b.deviceFinder.getList(offset, limit, where...)
+	b.measures.SQLDuration.With(db.TypeLabel, db.ReadType).Observe(time.Since(now).Seconds())
+
+	return result, err
+}
+
+func (b *dbMeasuresDecorator) findBlacklist(out *[]blacklist.BlackListedItem) error {
+	now := time.Now()
+	err := b.findList.findBlacklist(out)
+	b.measures.SQLDuration.With(db.TypeLabel, db.BlacklistReadType).Observe(time.Since(now).Seconds())
+
+	return err
+}
+
+func (b *dbMeasuresDecorator) insert(records []db.Record) (int64, error) {
+	now := time.Now()
+	count, err := b.multiInserter.insert(records)
+	b.measures.SQLDuration.With(db.TypeLabel, db.InsertType).Observe(time.Since(now).Seconds())
+
+	return count, err
+}
+
+func (b *dbMeasuresDecorator) delete(value *db.Record, limit int, where ...interface{}) (int64, error) {
+	now := time.Now()
+	count, err := b.deleter.delete(value, limit, where...)
+	b.measures.SQLDuration.With(db.TypeLabel, db.DeleteType).Observe(time.Since(now).Seconds())
+
+	return count, err
+}
+
+func (b *dbMeasuresDecorator) addBlacklist(item blacklist.BlackListedItem) error {
+	now := time.Now()
+	err := b.listEditor.addBlacklist(item)
+	b.measures.SQLDuration.With(db.TypeLabel, db.BlacklistWriteType).Observe(time.Since(now).Seconds())
+
+	return err
+}
+
+func (b *dbMeasuresDecorator) removeBlacklist(id string) error {
+	now := time.Now()
+	err := b.listEditor.removeBlacklist(id)
+	b.measures.SQLDuration.With(db.TypeLabel, db.BlacklistWriteType).Observe(time.Since(now).Seconds())
+
+	return err
+}
+
+func (b *dbMeasuresDecorator) ping() error {
+	now := time.Now()
+	err := b.pinger.ping()
+	b.measures.SQLDuration.With(db.TypeLabel, db.PingType).Observe(time.Since(now).Seconds())
+
+	return err
+}
+
+// setupExecuterMeasures wraps the executers so that every operation's
+// latency is recorded.  It should be called once the executers are final.
+func (c *Connection) setupExecuterMeasures() {
+	decorator := &dbMeasuresDecorator{
+		measures:      c.measures,
+		finder:        c.finder,
+		findList:      c.findList,
+		listEditor:    c.listEditor,
+		deviceFinder:  c.deviceFinder,
+		multiInserter: c.multiInsert,
+		deleter:       c.deleter,
+		pinger:        c.pinger,
+	}
+	c.finder = decorator
+	c.findList = decorator
+	c.listEditor = decorator
+	c.deviceFinder = decorator
+	c.multiInsert = decorator
+	c.deleter = decorator
+	c.pinger = decorator
+}
@@ -0,0 +1,189 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package postgresql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+// executerMocks are the executers wrapped by the measures decorator.
+type executerMocks struct {
+	finder       *mockFinder
+	findList     *mockFindList
+	listEditor   *mockListEditor
+	deviceFinder *mockDeviceFinder
+	inserter     *mockMultiInsert
+	deleter      *mockDeleter
+	pinger       *mockPing
+}
+
+func (m executerMocks) assertExpectations(t *testing.T) {
+	m.finder.AssertExpectations(t)
+	m.findList.AssertExpectations(t)
+	m.listEditor.AssertExpectations(t)
+	m.deviceFinder.AssertExpectations(t)
+	m.inserter.AssertExpectations(t)
+	m.deleter.AssertExpectations(t)
+	m.pinger.AssertExpectations(t)
+}
+
+func TestExecuterMeasures(t *testing.T) {
+	testErr := errors.New("test error")
+	tests := []struct {
+		description  string
+		setup        func(m executerMocks)
+		call         func(assert *assert.Assertions, c *Connection) error
+		expectedType string
+		expectedErr  error
+	}{
+		{
+			description: "Find Records",
+			setup: func(m executerMocks) {
+				m.finder.On("findRecords", mock.Anything, 5, mock.Anything).Return(testErr, []byte("[]")).Once()
+			},
+			call: func(_ *assert.Assertions, c *Connection) error {
+				var records []db.Record
+				return c.finder.findRecords(&records, 5, "device_id = ?", "1234")
+			},
+			expectedType: db.ReadType,
+			expectedErr:  testErr,
+		},
+		{
+			description: "Find Records To Delete",
+			setup: func(m executerMocks) {
+				m.finder.On("findRecordsToDelete", 5, 1, int64(10)).Return([]db.RecordToDelete{{RecordID: 7}}, nil).Once()
+			},
+			call: func(assert *assert.Assertions, c *Connection) error {
+				records, err := c.finder.findRecordsToDelete(5, 1, 10)
+				assert.Equal([]db.RecordToDelete{{RecordID: 7}}, records)
+				return err
+			},
+			expectedType: db.ReadType,
+		},
+		{
+			description: "Get List",
+			setup: func(m executerMocks) {
+				m.deviceFinder.On("getList", "a", 5, mock.Anything).Return([]string{"b"}, nil).Once()
+			},
+			call: func(assert *assert.Assertions, c *Connection) error {
+				list, err := c.deviceFinder.getList("a", 5)
+				assert.Equal([]string{"b"}, list)
+				return err
+			},
+			expectedType: db.ReadType,
+		},
+		{
+			description: "Find Blacklist",
+			setup: func(m executerMocks) {
+				m.findList.On("findBlacklist", mock.Anything).Return(testErr).Once()
+			},
+			call: func(_ *assert.Assertions, c *Connection) error {
+				var list []blacklist.BlackListedItem
+				return c.findList.findBlacklist(&list)
+			},
+			expectedType: db.BlacklistReadType,
+			expectedErr:  testErr,
+		},
+		{
+			description: "Add Blacklist",
+			setup: func(m executerMocks) {
+				m.listEditor.On("addBlacklist", blacklist.BlackListedItem{ID: "a"}).Return(nil).Once()
+			},
+			call: func(_ *assert.Assertions, c *Connection) error {
+				return c.listEditor.addBlacklist(blacklist.BlackListedItem{ID: "a"})
+			},
+			expectedType: db.BlacklistWriteType,
+		},
+		{
+			description: "Remove Blacklist",
+			setup: func(m executerMocks) {
+				m.listEditor.On("removeBlacklist", "a").Return(nil).Once()
+			},
+			call: func(_ *assert.Assertions, c *Connection) error {
+				return c.listEditor.removeBlacklist("a")
+			},
+			expectedType: db.BlacklistWriteType,
+		},
+		{
+			description: "Insert",
+			setup: func(m executerMocks) {
+				m.inserter.On("insert", mock.Anything).Return(2, testErr).Once()
+			},
+			call: func(assert *assert.Assertions, c *Connection) error {
+				count, err := c.multiInsert.insert([]db.Record{{}, {}})
+				assert.Equal(int64(2), count)
+				return err
+			},
+			expectedType: db.InsertType,
+			expectedErr:  testErr,
+		},
+		{
+			description: "Delete",
+			setup: func(m executerMocks) {
+				m.deleter.On("delete", mock.Anything, 1, mock.Anything).Return(1, nil).Once()
+			},
+			call: func(assert *assert.Assertions, c *Connection) error {
+				count, err := c.deleter.delete(&db.Record{}, 1)
+				assert.Equal(int64(1), count)
+				return err
+			},
+			expectedType: db.DeleteType,
+		},
+		{
+			description: "Ping",
+			setup: func(m executerMocks) {
+				m.pinger.On("ping").Return(testErr).Once()
+			},
+			call: func(_ *assert.Assertions, c *Connection) error {
+				return c.pinger.ping()
+			},
+			expectedType: db.PingType,
+			expectedErr:  testErr,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			mocks := executerMocks{
+				finder:       new(mockFinder),
+				findList:     new(mockFindList),
+				listEditor:   new(mockListEditor),
+				deviceFinder: new(mockDeviceFinder),
+				inserter:     new(mockMultiInsert),
+				deleter:      new(mockDeleter),
+				pinger:       new(mockPing),
+			}
+			tc.setup(mocks)
+			histogram := newRecordingHistogram()
+			measures := NewMeasures(xmetricstest.NewProvider(nil, Metrics))
+			measures.SQLDuration = histogram
+			dbConnection := Connection{
+				measures:     measures,
+				finder:       mocks.finder,
+				findList:     mocks.findList,
+				listEditor:   mocks.listEditor,
+				deviceFinder: mocks.deviceFinder,
+				multiInsert:  mocks.inserter,
+				deleter:      mocks.deleter,
+				pinger:       mocks.pinger,
+			}
+			dbConnection.setupExecuterMeasures()
+
+			err := tc.call(assert, &dbConnection)
+			assert.Equal(tc.expectedErr, err)
+			mocks.assertExpectations(t)
+			// one observation, labeled the way the cassandra driver labels
+			// the same operation.
+			assert.Len(histogram.observed, 1)
+			assert.Len(histogram.observed[tc.expectedType], 1)
+		})
+	}
+}
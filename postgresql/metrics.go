@@ -18,11 +18,24 @@ const (
 	SQLWaitDurationCounter      = "sql_wait_duration_seconds"
 	SQLMaxIdleClosedCounter     = "sql_max_idle_closed"
 	SQLMaxLifetimeClosedCounter = "sql_max_lifetime_closed"
+	SQLDurationSeconds          = "sql_duration_seconds"
 	SQLQuerySuccessCounter      = "sql_query_success_count"
 	SQLQueryFailureCounter      = "sql_query_failure_count"
 	SQLInsertedRecordsCounter   = "sql_inserted_rows_count"
 	SQLReadRecordsCounter       = "sql_read_rows_count"
 	SQLDeletedRecordsCounter    = "sql_deleted_rows_count"
+	SQLPartitionsCreatedCounter = "sql_partitions_created_count"
+	SQLPartitionsDroppedCounter = "sql_partitions_dropped_count"
+
+	SQLNotifySubscribersGauge       = "sql_notify_subscribers"
+	SQLNotificationsReceivedCounter = "sql_notifications_received_count"
+	SQLNotificationsDroppedCounter  = "sql_notifications_dropped_count"
+	SQLListenerReconnectsCounter    = "sql_listener_reconnect_count"
+	SQLListenerFailuresCounter      = "sql_listener_failure_count"
+
+	SQLReplicaHealthyGauge    = "sql_replica_healthy"
+	SQLReplicaLagGauge        = "sql_replica_lag_seconds"
+	SQLReplicaFallbackCounter = "sql_replica_fallback_count"
 )
 
 // nolint: funlen // we just have a lot of metrics
@@ -64,6 +77,13 @@ func Metrics() []xmetrics.Metric {
 			Type: "counter",
 			Help: "The total number of connections closed due to SetConnMaxLifetime",
 		},
+		{
+			Name:       SQLDurationSeconds,
+			Type:       "histogram",
+			Help:       "A histogram of latencies for requests.",
+			Buckets:    []float64{0.0625, 0.125, .25, .5, 1, 5, 10, 20, 40, 80, 160},
+			LabelNames: []string{db.TypeLabel},
+		},
 		{
 			Name:       SQLQuerySuccessCounter,
 			Type:       "counter",
@@ -91,6 +111,58 @@ func Metrics() []xmetrics.Metric {
 			Type: "counter",
 			Help: "The total number of rows deleted",
 		},
+		{
+			Name: SQLPartitionsCreatedCounter,
+			Type: "counter",
+			Help: "The total number of events table partitions created",
+		},
+		{
+			Name: SQLPartitionsDroppedCounter,
+			Type: "counter",
+			Help: "The total number of expired events table partitions dropped",
+		},
+		{
+			Name: SQLNotifySubscribersGauge,
+			Type: "gauge",
+			Help: "The number of subscriptions to inserted record notifications",
+		},
+		{
+			Name: SQLNotificationsReceivedCounter,
+			Type: "counter",
+			Help: "The total number of inserted record notifications received",
+		},
+		{
+			Name: SQLNotificationsDroppedCounter,
+			Type: "counter",
+			Help: "The total number of notifications dropped because they were malformed or a subscriber was full",
+		},
+		{
+			Name: SQLListenerReconnectsCounter,
+			Type: "counter",
+			Help: "The total number of times the notification listener reconnected",
+		},
+		{
+			Name: SQLListenerFailuresCounter,
+			Type: "counter",
+			Help: "The total number of notification listener disconnects and failed connection attempts",
+		},
+		{
+			Name:       SQLReplicaHealthyGauge,
+			Type:       "gauge",
+			Help:       "Whether a read replica is receiving reads, 1 if it is and 0 if not",
+			LabelNames: []string{ServerLabel},
+		},
+		{
+			Name:       SQLReplicaLagGauge,
+			Type:       "gauge",
+			Help:       "How far a read replica is behind the primary in seconds",
+			LabelNames: []string{ServerLabel},
+		},
+		{
+			Name: SQLReplicaFallbackCounter,
+			Type: "counter",
+			Help: "The total number of reads sent to the primary because no replica was healthy or a replica failed",
+		},
 	}
 }
 
@@ -104,11 +176,24 @@ type Measures struct {
 	SQLWaitDuration      metrics.Counter
 	SQLMaxIdleClosed     metrics.Counter
 	SQLMaxLifetimeClosed metrics.Counter
+	SQLDuration          metrics.Histogram
 	SQLQuerySuccessCount metrics.Counter
 	SQLQueryFailureCount metrics.Counter
 	SQLInsertedRecords   metrics.Counter
 	SQLReadRecords       metrics.Counter
 	SQLDeletedRecords    metrics.Counter
+	SQLPartitionsCreated metrics.Counter
+	SQLPartitionsDropped metrics.Counter
+
+	SQLNotifySubscribers     metrics.Gauge
+	SQLNotificationsReceived metrics.Counter
+	SQLNotificationsDropped  metrics.Counter
+	SQLListenerReconnects    metrics.Counter
+	SQLListenerFailures      metrics.Counter
+
+	SQLReplicaHealthy   metrics.Gauge
+	SQLReplicaLag       metrics.Gauge
+	SQLReplicaFallbacks metrics.Counter
 }
 
 func NewMeasures(p provider.Provider) Measures {
@@ -122,10 +207,23 @@ func NewMeasures(p provider.Provider) Measures {
 		SQLWaitDuration:      p.NewCounter(SQLWaitDurationCounter),
 		SQLMaxIdleClosed:     p.NewCounter(SQLMaxIdleClosedCounter),
 		SQLMaxLifetimeClosed: p.NewCounter(SQLMaxLifetimeClosedCounter),
+		SQLDuration:          p.NewHistogram(SQLDurationSeconds, 11),
 		SQLQuerySuccessCount: p.NewCounter(SQLQuerySuccessCounter),
 		SQLQueryFailureCount: p.NewCounter(SQLQueryFailureCounter),
 		SQLInsertedRecords:   p.NewCounter(SQLInsertedRecordsCounter),
 		SQLReadRecords:       p.NewCounter(SQLReadRecordsCounter),
 		SQLDeletedRecords:    p.NewCounter(SQLDeletedRecordsCounter),
+		SQLPartitionsCreated: p.NewCounter(SQLPartitionsCreatedCounter),
+		SQLPartitionsDropped: p.NewCounter(SQLPartitionsDroppedCounter),
+
+		SQLNotifySubscribers:     p.NewGauge(SQLNotifySubscribersGauge),
+		SQLNotificationsReceived: p.NewCounter(SQLNotificationsReceivedCounter),
+		SQLNotificationsDropped:  p.NewCounter(SQLNotificationsDroppedCounter),
+		SQLListenerReconnects:    p.NewCounter(SQLListenerReconnectsCounter),
+		SQLListenerFailures:      p.NewCounter(SQLListenerFailuresCounter),
+
+		SQLReplicaHealthy:   p.NewGauge(SQLReplicaHealthyGauge),
+		SQLReplicaLag:       p.NewGauge(SQLReplicaLagGauge),
+		SQLReplicaFallbacks: p.NewCounter(SQLReplicaFallbackCounter),
 	}
 }
@@ -5,9 +5,14 @@ package postgresql
 
 import (
 	"encoding/json"
+	"sync"
+	"time"
 
+	"github.com/go-kit/kit/metrics"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/mock"
 	"github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
 )
 
 type mockFinder struct {
@@ -72,3 +77,123 @@ func (d *mockPing) ping() error {
 	args := d.Called()
 	return args.Error(0)
 }
+
+type mockPartitioner struct {
+	mock.Mock
+}
+
+func (p *mockPartitioner) listPartitions() ([]string, error) {
+	args := p.Called()
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (p *mockPartitioner) createPartition(name string, from int64, to int64) error {
+	args := p.Called(name, from, to)
+	return args.Error(0)
+}
+
+func (p *mockPartitioner) dropPartition(name string) error {
+	args := p.Called(name)
+	return args.Error(0)
+}
+
+type fakeListener struct {
+	listenErr     error
+	listened      []string
+	notifications chan *pq.Notification
+	closed        bool
+}
+
+func newFakeListener() *fakeListener {
+	return &fakeListener{notifications: make(chan *pq.Notification)}
+}
+
+func (l *fakeListener) Listen(channel string) error {
+	l.listened = append(l.listened, channel)
+	return l.listenErr
+}
+
+func (l *fakeListener) NotificationChannel() <-chan *pq.Notification {
+	return l.notifications
+}
+
+func (l *fakeListener) Ping() error {
+	return nil
+}
+
+func (l *fakeListener) Close() error {
+	if !l.closed {
+		l.closed = true
+		close(l.notifications)
+	}
+	return nil
+}
+
+type mockReplicaDB struct {
+	mockFinder
+	mockDeviceFinder
+	mockCloser
+	mockPing
+	lag    time.Duration
+	lagErr error
+}
+
+func (r *mockReplicaDB) findBlacklist(out *[]blacklist.BlackListedItem) error {
+	args := r.mockFinder.Called(out)
+	return args.Error(0)
+}
+
+func (r *mockReplicaDB) replicationLag() (time.Duration, error) {
+	return r.lag, r.lagErr
+}
+
+type mockFindList struct {
+	mock.Mock
+}
+
+func (f *mockFindList) findBlacklist(out *[]blacklist.BlackListedItem) error {
+	args := f.Called(out)
+	return args.Error(0)
+}
+
+type mockPrimary struct {
+	mockFinder
+	mockFindList
+	mockDeviceFinder
+}
+
+type mockListEditor struct {
+	mock.Mock
+}
+
+func (l *mockListEditor) addBlacklist(item blacklist.BlackListedItem) error {
+	args := l.Called(item)
+	return args.Error(0)
+}
+
+func (l *mockListEditor) removeBlacklist(id string) error {
+	args := l.Called(id)
+	return args.Error(0)
+}
+
+// recordingHistogram keeps the values observed, by the value of the label
+// they were observed with.
+type recordingHistogram struct {
+	lock     *sync.Mutex
+	label    string
+	observed map[string][]float64
+}
+
+func newRecordingHistogram() *recordingHistogram {
+	return &recordingHistogram{lock: new(sync.Mutex), observed: map[string][]float64{}}
+}
+
+func (h *recordingHistogram) With(labelsAndValues ...string) metrics.Histogram {
+	return &recordingHistogram{lock: h.lock, label: labelsAndValues[len(labelsAndValues)-1], observed: h.observed}
+}
+
+func (h *recordingHistogram) Observe(value float64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.observed[h.label] = append(h.observed[h.label], value)
+}
@@ -0,0 +1,280 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package postgresql
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultNotifyChannel        = "codex_events"
+	defaultMinReconnectInterval = time.Second
+	defaultMaxReconnectInterval = time.Minute
+	defaultSubscriberBuffer     = 100
+
+	// listenerPingInterval is how often an idle listener checks that its
+	// connection is still alive, as recommended by pq.
+	listenerPingInterval = 90 * time.Second
+)
+
+var (
+	errNotifierClosed = errors.New("notifier closed")
+)
+
+// NotifyConfig configures the change feed of inserted records, which uses
+// postgres LISTEN and NOTIFY.
+type NotifyConfig struct {
+	// Enabled makes every insert send a notification on Channel for each
+	// record inserted.  The events table must have a record_id column.  This
+	// can be left off if a trigger on the events table sends the
+	// notifications instead.
+	Enabled bool
+
+	// Channel is the postgres notification channel used.  Defaults to
+	// codex_events.
+	Channel string
+
+	// MinReconnectInterval and MaxReconnectInterval bound the backoff used
+	// when the connection for listening is lost.
+	MinReconnectInterval time.Duration
+	MaxReconnectInterval time.Duration
+
+	// SubscriberBuffer is the number of notifications that can wait for a
+	// subscriber before more are dropped.
+	SubscriberBuffer int
+}
+
+// Notification tells a subscriber that a record was inserted for a device.
+type Notification struct {
+	DeviceID string `json:"device_id"`
+	RecordID int64  `json:"record_id"`
+
+	// Missed is set, with no device or record, when the connection for
+	// listening was lost and notifications may have been missed.  Subscribers
+	// should read the records for their devices again.
+	Missed bool `json:"-"`
+}
+
+type listener interface {
+	Listen(channel string) error
+	NotificationChannel() <-chan *pq.Notification
+	Ping() error
+	Close() error
+}
+
+// Subscription receives notifications of records inserted for some devices.
+type Subscription struct {
+	notifications chan Notification
+	devices       map[string]bool
+	notifier      *notifier
+}
+
+// Notifications returns the channel notifications are delivered on.  It is
+// closed when the Subscription or the Connection is closed.
+func (s *Subscription) Notifications() <-chan Notification {
+	return s.notifications
+}
+
+// Close stops the subscription and closes its notification channel.
+func (s *Subscription) Close() {
+	s.notifier.unsubscribe(s)
+}
+
+func (s *Subscription) wants(n Notification) bool {
+	return n.Missed || len(s.devices) == 0 || s.devices[n.DeviceID]
+}
+
+// notifier shares one listening connection among all the subscriptions of a
+// Connection.
+type notifier struct {
+	listener    listener
+	bufferSize  int
+	measures    Measures
+	lock        sync.Mutex
+	subscribers map[*Subscription]bool
+	closed      bool
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func newNotifier(l listener, channel string, bufferSize int, measures Measures) (*notifier, error) {
+	if err := l.Listen(channel); err != nil {
+		l.Close()
+		return nil, emperror.WrapWith(err, "Listening for notifications failed", "channel", channel)
+	}
+	n := &notifier{
+		listener:    l,
+		bufferSize:  bufferSize,
+		measures:    measures,
+		subscribers: make(map[*Subscription]bool),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go n.run()
+	return n, nil
+}
+
+func (n *notifier) run() {
+	defer close(n.done)
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			// a failed ping makes the listener reconnect.
+			_ = n.listener.Ping()
+		case pn, ok := <-n.listener.NotificationChannel():
+			if !ok {
+				return
+			}
+			// pq sends nil after reconnecting, as notifications sent while
+			// disconnected are lost.
+			if pn == nil {
+				n.publish(Notification{Missed: true})
+				continue
+			}
+			var notification Notification
+			if err := json.Unmarshal([]byte(pn.Extra), &notification); err != nil {
+				n.measures.SQLNotificationsDropped.Add(1.0)
+				continue
+			}
+			n.measures.SQLNotificationsReceived.Add(1.0)
+			n.publish(notification)
+		}
+	}
+}
+
+func (n *notifier) publish(notification Notification) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for s := range n.subscribers {
+		if !s.wants(notification) {
+			continue
+		}
+		select {
+		case s.notifications <- notification:
+		default:
+			// never let a slow subscriber hold up the others.
+			n.measures.SQLNotificationsDropped.Add(1.0)
+		}
+	}
+}
+
+func (n *notifier) subscribe(deviceIDs []string) (*Subscription, error) {
+	s := &Subscription{
+		notifications: make(chan Notification, n.bufferSize),
+		devices:       make(map[string]bool, len(deviceIDs)),
+		notifier:      n,
+	}
+	for _, id := range deviceIDs {
+		s.devices[id] = true
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.closed {
+		return nil, errNotifierClosed
+	}
+	n.subscribers[s] = true
+	n.measures.SQLNotifySubscribers.Add(1.0)
+	return s, nil
+}
+
+func (n *notifier) unsubscribe(s *Subscription) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if !n.subscribers[s] {
+		return
+	}
+	delete(n.subscribers, s)
+	close(s.notifications)
+	n.measures.SQLNotifySubscribers.Add(-1.0)
+}
+
+func (n *notifier) close() error {
+	n.lock.Lock()
+	if n.closed {
+		n.lock.Unlock()
+		return nil
+	}
+	n.closed = true
+	n.lock.Unlock()
+
+	close(n.stop)
+	err := n.listener.Close()
+	<-n.done
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for s := range n.subscribers {
+		delete(n.subscribers, s)
+		close(s.notifications)
+		n.measures.SQLNotifySubscribers.Add(-1.0)
+	}
+	return err
+}
+
+// Subscribe returns a Subscription that is notified of every record inserted
+// for the devices given, or for all devices if none are given.  The
+// connection used for listening is opened on the first call and reconnects on
+// its own if lost.
+func (c *Connection) Subscribe(deviceIDs []string) (*Subscription, error) {
+	c.notifierLock.Lock()
+	defer c.notifierLock.Unlock()
+	if c.notifier == nil {
+		n, err := newNotifier(c.newListener(), c.notifyConfig.Channel, c.notifyConfig.SubscriberBuffer, c.measures)
+		if err != nil {
+			return nil, err
+		}
+		c.notifier = n
+	}
+	return c.notifier.subscribe(deviceIDs)
+}
+
+func (c *Connection) closeNotifier() error {
+	c.notifierLock.Lock()
+	defer c.notifierLock.Unlock()
+	if c.notifier == nil {
+		return nil
+	}
+	err := c.notifier.close()
+	c.notifier = nil
+	return err
+}
+
+// listenerEventCallback counts the connection events of the listener.
+func (c *Connection) listenerEventCallback(event pq.ListenerEventType, _ error) {
+	switch event {
+	case pq.ListenerEventReconnected:
+		c.measures.SQLListenerReconnects.Add(1.0)
+	case pq.ListenerEventDisconnected, pq.ListenerEventConnectionAttemptFailed:
+		c.measures.SQLListenerFailures.Add(1.0)
+	}
+}
+
+func validateNotifyConfig(config *NotifyConfig) {
+	if config.Channel == "" {
+		config.Channel = defaultNotifyChannel
+	}
+	if config.MinReconnectInterval <= 0 {
+		config.MinReconnectInterval = defaultMinReconnectInterval
+	}
+	if config.MaxReconnectInterval < config.MinReconnectInterval {
+		config.MaxReconnectInterval = defaultMaxReconnectInterval
+		if config.MaxReconnectInterval < config.MinReconnectInterval {
+			config.MaxReconnectInterval = config.MinReconnectInterval
+		}
+	}
+	if config.SubscriberBuffer <= 0 {
+		config.SubscriberBuffer = defaultSubscriberBuffer
+	}
+}
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package postgresql
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func receive(t *testing.T, s *Subscription) (Notification, bool) {
+	select {
+	case n, ok := <-s.Notifications():
+		return n, ok
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for notification")
+	}
+	return Notification{}, false
+}
+
+func TestSubscribe(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	l := newFakeListener()
+	p := xmetricstest.NewProvider(nil, Metrics)
+	dbConnection := Connection{
+		measures:     NewMeasures(p),
+		notifyConfig: NotifyConfig{Channel: "test_channel", SubscriberBuffer: 1},
+		newListener:  func() listener { return l },
+	}
+
+	all, err := dbConnection.Subscribe(nil)
+	require.Nil(err)
+	one, err := dbConnection.Subscribe([]string{"mac:112233445566"})
+	require.Nil(err)
+	assert.Equal([]string{"test_channel"}, l.listened)
+	p.Assert(t, SQLNotifySubscribersGauge)(xmetricstest.Value(2.0))
+
+	l.notifications <- &pq.Notification{Extra: `{"device_id":"mac:112233445566","record_id":7}`}
+	n, ok := receive(t, all)
+	assert.True(ok)
+	assert.Equal(Notification{DeviceID: "mac:112233445566", RecordID: 7}, n)
+	n, ok = receive(t, one)
+	assert.True(ok)
+	assert.Equal(Notification{DeviceID: "mac:112233445566", RecordID: 7}, n)
+
+	// only the subscriber for all devices gets this one.
+	l.notifications <- &pq.Notification{Extra: `{"device_id":"mac:aabbccddeeff","record_id":8}`}
+	n, ok = receive(t, all)
+	assert.True(ok)
+	assert.Equal(Notification{DeviceID: "mac:aabbccddeeff", RecordID: 8}, n)
+
+	// the buffer of one fills and the next is dropped.
+	l.notifications <- &pq.Notification{Extra: `{"device_id":"mac:aabbccddeeff","record_id":9}`}
+	l.notifications <- &pq.Notification{Extra: `{"device_id":"mac:aabbccddeeff","record_id":10}`}
+	l.notifications <- &pq.Notification{Extra: `not json`}
+	// a reconnect goes to everyone.
+	l.notifications <- nil
+	n, ok = receive(t, all)
+	assert.True(ok)
+	assert.Equal(int64(9), n.RecordID)
+	n, ok = receive(t, one)
+	assert.True(ok)
+	assert.Equal(Notification{Missed: true}, n)
+	p.Assert(t, SQLNotificationsReceivedCounter)(xmetricstest.Value(4.0))
+	p.Assert(t, SQLNotificationsDroppedCounter)(xmetricstest.Value(3.0))
+
+	one.Close()
+	_, ok = receive(t, one)
+	assert.False(ok)
+	p.Assert(t, SQLNotifySubscribersGauge)(xmetricstest.Value(1.0))
+
+	assert.Nil(dbConnection.closeNotifier())
+	assert.True(l.closed)
+	_, ok = receive(t, all)
+	assert.False(ok)
+	p.Assert(t, SQLNotifySubscribersGauge)(xmetricstest.Value(0.0))
+}
+
+func TestSubscribeListenError(t *testing.T) {
+	assert := assert.New(t)
+	l := newFakeListener()
+	l.listenErr = errors.New("test listen error")
+	dbConnection := Connection{
+		measures:     NewMeasures(xmetricstest.NewProvider(nil, Metrics)),
+		notifyConfig: NotifyConfig{Channel: "test_channel", SubscriberBuffer: 1},
+		newListener:  func() listener { return l },
+	}
+
+	s, err := dbConnection.Subscribe(nil)
+	assert.Nil(s)
+	assert.Contains(err.Error(), l.listenErr.Error())
+	assert.True(l.closed)
+	assert.Nil(dbConnection.notifier)
+}
+
+func TestListenerEventCallback(t *testing.T) {
+	p := xmetricstest.NewProvider(nil, Metrics)
+	dbConnection := Connection{
+		measures: NewMeasures(p),
+	}
+	dbConnection.listenerEventCallback(pq.ListenerEventConnected, nil)
+	dbConnection.listenerEventCallback(pq.ListenerEventDisconnected, errors.New("test error"))
+	dbConnection.listenerEventCallback(pq.ListenerEventConnectionAttemptFailed, errors.New("test error"))
+	dbConnection.listenerEventCallback(pq.ListenerEventReconnected, nil)
+	p.Assert(t, SQLListenerReconnectsCounter)(xmetricstest.Value(1.0))
+	p.Assert(t, SQLListenerFailuresCounter)(xmetricstest.Value(2.0))
+}
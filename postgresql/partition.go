@@ -0,0 +1,192 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package postgresql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goph/emperror"
+	db "github.com/xmidt-org/codex-db"
+)
+
+const (
+	// PartitionReadType, PartitionCreateType, and PartitionDropType label the
+	// query metrics for managing partitions.
+	PartitionReadType   = "partitionRead"
+	PartitionCreateType = "partitionCreate"
+	PartitionDropType   = "partitionDrop"
+
+	partitionPrefix = "events_p"
+
+	defaultPartitionInterval      = 24 * time.Hour
+	minPartitionInterval          = time.Minute
+	defaultPartitionCreateAhead   = 7 * 24 * time.Hour
+	defaultPartitionCheckInterval = time.Hour
+)
+
+// PartitionConfig configures the management of an events table that is range
+// partitioned on death_date.  The table itself must already be created with
+// PARTITION BY RANGE (death_date).
+type PartitionConfig struct {
+	// Enabled turns on partition management.
+	Enabled bool
+
+	// Interval is the range of death dates each partition covers.  Partitions
+	// are aligned to multiples of the interval.
+	Interval time.Duration
+
+	// CreateAhead is how far into the future partitions are created.  This
+	// should be longer than the time to live of the records being inserted,
+	// unless a default partition exists to catch them.
+	CreateAhead time.Duration
+
+	// CheckInterval is how often partitions are created and dropped.
+	CheckInterval time.Duration
+}
+
+type partition struct {
+	name string
+	from int64
+	to   int64
+}
+
+func validatePartitionConfig(config *PartitionConfig) {
+	if config.Interval < minPartitionInterval {
+		config.Interval = defaultPartitionInterval
+	}
+	if config.CreateAhead <= 0 {
+		config.CreateAhead = defaultPartitionCreateAhead
+	}
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = defaultPartitionCheckInterval
+	}
+}
+
+// newPartition creates the partition holding death dates from start up to,
+// but not including, end.
+func newPartition(start time.Time, end time.Time) partition {
+	return partition{
+		name: fmt.Sprintf("%s%d_%d", partitionPrefix, start.Unix(), end.Unix()),
+		from: start.UnixNano(),
+		to:   end.UnixNano(),
+	}
+}
+
+// parsePartition gets the bounds of a partition from its name.  Tables not
+// named by newPartition are not ours to manage, so false is returned for
+// them.
+func parsePartition(name string) (partition, bool) {
+	if !strings.HasPrefix(name, partitionPrefix) {
+		return partition{}, false
+	}
+	bounds := strings.Split(strings.TrimPrefix(name, partitionPrefix), "_")
+	if len(bounds) != 2 {
+		return partition{}, false
+	}
+	from, err := strconv.ParseInt(bounds[0], 10, 64)
+	if err != nil {
+		return partition{}, false
+	}
+	to, err := strconv.ParseInt(bounds[1], 10, 64)
+	if err != nil || to <= from {
+		return partition{}, false
+	}
+	return newPartition(time.Unix(from, 0), time.Unix(to, 0)), true
+}
+
+func (p partition) overlaps(other partition) bool {
+	return p.from < other.to && other.from < p.to
+}
+
+// ManagePartitions creates the partitions needed for upcoming death dates and
+// drops the partitions where every record has expired.  Records left in
+// partitions that haven't fully expired are pruned by the usual
+// GetRecordsToDelete and DeleteRecord calls.  A partition that can't be
+// created or dropped doesn't stop the others from being tried; the errors
+// are returned together.
+func (c *Connection) ManagePartitions() error {
+	return c.managePartitions(time.Now())
+}
+
+func (c *Connection) managePartitions(now time.Time) error {
+	names, err := c.partitioner.listPartitions()
+	if err != nil {
+		c.measures.SQLQueryFailureCount.With(db.TypeLabel, PartitionReadType).Add(1.0)
+		return emperror.Wrap(err, "Getting partitions from database failed")
+	}
+	c.measures.SQLQuerySuccessCount.With(db.TypeLabel, PartitionReadType).Add(1.0)
+
+	existing := make([]partition, 0, len(names))
+	for _, name := range names {
+		if p, ok := parsePartition(name); ok {
+			existing = append(existing, p)
+		}
+	}
+
+	var errs []error
+	for _, p := range neededPartitions(now, c.partitionConfig, existing) {
+		err = c.partitioner.createPartition(p.name, p.from, p.to)
+		if err != nil {
+			c.measures.SQLQueryFailureCount.With(db.TypeLabel, PartitionCreateType).Add(1.0)
+			errs = append(errs, emperror.WrapWith(err, "Creating partition failed", "partition", p.name))
+			continue
+		}
+		c.measures.SQLQuerySuccessCount.With(db.TypeLabel, PartitionCreateType).Add(1.0)
+		c.measures.SQLPartitionsCreated.Add(1.0)
+	}
+
+	for _, p := range existing {
+		// every record in the partition has a death date before p.to.
+		if p.to > now.UnixNano() {
+			continue
+		}
+		err = c.partitioner.dropPartition(p.name)
+		if err != nil {
+			c.measures.SQLQueryFailureCount.With(db.TypeLabel, PartitionDropType).Add(1.0)
+			errs = append(errs, emperror.WrapWith(err, "Dropping partition failed", "partition", p.name))
+			continue
+		}
+		c.measures.SQLQuerySuccessCount.With(db.TypeLabel, PartitionDropType).Add(1.0)
+		c.measures.SQLPartitionsDropped.Add(1.0)
+	}
+	return combineErrors(errs)
+}
+
+// combineErrors returns the one error given, or an error listing all of
+// their messages.
+func combineErrors(errs []error) error {
+	builder := emperror.NewMultiErrorBuilder()
+	builder.SingleWrapMode = emperror.ReturnSingle
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		builder.Add(err)
+		msgs[i] = err.Error()
+	}
+	builder.Message = strings.Join(msgs, "; ")
+	return builder.ErrOrNil()
+}
+
+// neededPartitions returns the partitions from the one holding now through
+// the one holding now + CreateAhead that don't overlap an existing partition.
+func neededPartitions(now time.Time, config PartitionConfig, existing []partition) []partition {
+	var needed []partition
+	last := now.Add(config.CreateAhead)
+	for start := now.Truncate(config.Interval); !start.After(last); start = start.Add(config.Interval) {
+		p := newPartition(start, start.Add(config.Interval))
+		found := false
+		for _, e := range existing {
+			if e.overlaps(p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			needed = append(needed, p)
+		}
+	}
+	return needed
+}
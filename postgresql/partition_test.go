@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package postgresql
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/goph/emperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func TestParsePartition(t *testing.T) {
+	start := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	tests := []struct {
+		description       string
+		name              string
+		expectedPartition partition
+		expectedOK        bool
+	}{
+		{
+			description:       "Success",
+			name:              newPartition(start, end).name,
+			expectedPartition: partition{name: "events_p1792281600_1792368000", from: start.UnixNano(), to: end.UnixNano()},
+			expectedOK:        true,
+		},
+		{
+			description: "Not Ours",
+			name:        "events_default",
+		},
+		{
+			description: "Bad Bounds",
+			name:        "events_p1760745600",
+		},
+		{
+			description: "Not A Number",
+			name:        "events_p1760745600_tomorrow",
+		},
+		{
+			description: "Backwards Bounds",
+			name:        "events_p1760832000_1760745600",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			p, ok := parsePartition(tc.name)
+			assert.Equal(tc.expectedOK, ok)
+			assert.Equal(tc.expectedPartition, p)
+		})
+	}
+}
+
+func TestManagePartitions(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Date(2026, 10, 18, 13, 0, 0, 0, time.UTC)
+	today := now.Truncate(day)
+	expired := newPartition(today.Add(-2*day), today.Add(-day))
+	yesterday := newPartition(today.Add(-day), today)
+	current := newPartition(today, today.Add(day))
+	tomorrow := newPartition(today.Add(day), today.Add(2*day))
+	config := PartitionConfig{
+		Interval:    day,
+		CreateAhead: day,
+	}
+	testErr := errors.New("test partition error")
+
+	tests := []struct {
+		description     string
+		existing        []string
+		listErr         error
+		createErr       error
+		dropErr         error
+		expectedCreates []partition
+		expectedDrops   []partition
+		expectedCreated float64
+		expectedDropped float64
+		expectedErr     error
+		expectedErrs    int
+	}{
+		{
+			description:     "Success",
+			existing:        []string{expired.name, yesterday.name, current.name, "events_default"},
+			expectedCreates: []partition{tomorrow},
+			expectedDrops:   []partition{expired, yesterday},
+			expectedCreated: 1.0,
+			expectedDropped: 2.0,
+		},
+		{
+			description: "Nothing To Do",
+			existing:    []string{current.name, tomorrow.name},
+		},
+		{
+			description: "List Error",
+			listErr:     testErr,
+			expectedErr: testErr,
+		},
+		{
+			description:     "Create Error",
+			existing:        []string{current.name},
+			createErr:       testErr,
+			expectedCreates: []partition{tomorrow},
+			expectedErr:     testErr,
+		},
+		{
+			description:     "Errors Don't Stop The Rest",
+			existing:        []string{expired.name},
+			createErr:       testErr,
+			expectedCreates: []partition{current, tomorrow},
+			expectedDrops:   []partition{expired},
+			expectedDropped: 1.0,
+			expectedErr:     testErr,
+			expectedErrs:    2,
+		},
+		{
+			description:   "Drop Error",
+			existing:      []string{expired.name, current.name, tomorrow.name},
+			dropErr:       testErr,
+			expectedDrops: []partition{expired},
+			expectedErr:   testErr,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			mockObj := new(mockPartitioner)
+			p := xmetricstest.NewProvider(nil, Metrics)
+			dbConnection := Connection{
+				measures:        NewMeasures(p),
+				partitioner:     mockObj,
+				partitionConfig: config,
+			}
+			mockObj.On("listPartitions").Return(tc.existing, tc.listErr).Once()
+			for _, c := range tc.expectedCreates {
+				mockObj.On("createPartition", c.name, c.from, c.to).Return(tc.createErr).Once()
+			}
+			for _, d := range tc.expectedDrops {
+				mockObj.On("dropPartition", d.name).Return(tc.dropErr).Once()
+			}
+
+			err := dbConnection.managePartitions(now)
+			mockObj.AssertExpectations(t)
+			mockObj.AssertNumberOfCalls(t, "createPartition", len(tc.expectedCreates))
+			mockObj.AssertNumberOfCalls(t, "dropPartition", len(tc.expectedDrops))
+			p.Assert(t, SQLPartitionsCreatedCounter)(xmetricstest.Value(tc.expectedCreated))
+			p.Assert(t, SQLPartitionsDroppedCounter)(xmetricstest.Value(tc.expectedDropped))
+			if tc.expectedErr == nil || err == nil {
+				assert.Equal(tc.expectedErr, err)
+			} else {
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+			}
+			if tc.expectedErrs > 1 {
+				multi, ok := err.(emperror.Errors)
+				if assert.True(ok) {
+					assert.Len(multi.Errors(), tc.expectedErrs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package postgresql
+
+import (
+	"sync/atomic"
+	"time"
+
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/codex-db/blacklist"
+)
+
+const (
+	// ServerLabel is for labeling replica metrics with the replica's server.
+	ServerLabel = "server"
+
+	defaultMaxReplicaLag        = 10 * time.Second
+	defaultReplicaCheckInterval = 5 * time.Second
+)
+
+type replicaDB interface {
+	finder
+	findList
+	deviceFinder
+	pinger
+	closer
+	replicationLag() (time.Duration, error)
+}
+
+type replica struct {
+	server  string
+	db      replicaDB
+	healthy int32
+}
+
+func (r *replica) isHealthy() bool {
+	return atomic.LoadInt32(&r.healthy) == 1
+}
+
+func (r *replica) setHealthy(healthy bool) {
+	var value int32
+	if healthy {
+		value = 1
+	}
+	atomic.StoreInt32(&r.healthy, value)
+}
+
+// replicaRouter sends reads to healthy replicas in turn, falling back to the
+// primary when no replica is healthy or a replica fails.  Reads done to
+// decide what to delete stay on the primary, as a lagging replica could
+// return records that are already deleted.
+type replicaRouter struct {
+	primary interface {
+		finder
+		findList
+		deviceFinder
+	}
+	replicas []*replica
+	next     uint32
+	maxLag   time.Duration
+	measures Measures
+}
+
+// pick returns the next healthy replica, or nil if there are none.
+func (r *replicaRouter) pick() *replica {
+	n := uint32(len(r.replicas))
+	for i := uint32(0); i < n; i++ {
+		rep := r.replicas[(atomic.AddUint32(&r.next, 1)-1)%n]
+		if rep.isHealthy() {
+			return rep
+		}
+	}
+	return nil
+}
+
+// read runs the read on a healthy replica, and on the primary if that isn't
+// possible.  A replica that fails is marked unhealthy until it passes a
+// check again.
+func (r *replicaRouter) read(onReplica func(replicaDB) error, onPrimary func() error) error {
+	if rep := r.pick(); rep != nil {
+		err := onReplica(rep.db)
+		if err == nil {
+			return nil
+		}
+		r.setHealth(rep, false)
+	}
+	r.measures.SQLReplicaFallbacks.Add(1.0)
+	return onPrimary()
+}
+
+func (r *replicaRouter) findRecords(out *[]db.Record, limit int, where ...interface{}) error {
+	return r.read(func(rep replicaDB) error {
+		// don't return part of a failed read.
+		*out = nil
+		return rep.findRecords(out, limit, where...)
+	}, func() error {
+		*out = nil
+		return r.primary.findRecords(out, limit, where...)
+	})
+}
+
+func (r *replicaRouter) findRecordsToDelete(limit int, shard int, deathDate int64) ([]db.RecordToDelete, error) {
+	return r.primary.findRecordsToDelete(limit, shard, deathDate)
+}
+
+func (r *replicaRouter) findBlacklist(out *[]blacklist.BlackListedItem) error {
+	return r.read(func(rep replicaDB) error {
+		*out = nil
+		return rep.findBlacklist(out)
+	}, func() error {
+		*out = nil
+		return r.primary.findBlacklist(out)
+	})
+}
+
+func (r *replicaRouter) getList(offset string, limit int, where ...interface{}) ([]string, error) {
+	var result []string
+	err := r.read(func(rep replicaDB) error {
+		var err error
+		result, err = rep.getList(offset, limit, where...)
+		return err
+	}, func() error {
+		var err error
+		result, err = r.primary.getList(offset, limit, where...)
+		return err
+	})
+	return result, err
+}
+
+// checkReplicas marks each replica healthy if it can be reached and isn't
+// lagging behind the primary by more than the max lag allowed.
+func (r *replicaRouter) checkReplicas() {
+	for _, rep := range r.replicas {
+		healthy := rep.db.ping() == nil
+		if healthy {
+			lag, err := rep.db.replicationLag()
+			if err == nil {
+				r.measures.SQLReplicaLag.With(ServerLabel, rep.server).Set(lag.Seconds())
+			}
+			healthy = err == nil && lag <= r.maxLag
+		}
+		r.setHealth(rep, healthy)
+	}
+}
+
+func (r *replicaRouter) setHealth(rep *replica, healthy bool) {
+	rep.setHealthy(healthy)
+	value := 0.0
+	if healthy {
+		value = 1.0
+	}
+	r.measures.SQLReplicaHealthy.With(ServerLabel, rep.server).Set(value)
+}
+
+func (r *replicaRouter) close() error {
+	var firstErr error
+	for _, rep := range r.replicas {
+		if err := rep.db.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
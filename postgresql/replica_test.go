@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package postgresql
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+func TestCheckReplicas(t *testing.T) {
+	tests := []struct {
+		description     string
+		pingErr         error
+		lag             time.Duration
+		lagErr          error
+		expectedHealthy bool
+	}{
+		{
+			description:     "Healthy",
+			lag:             time.Second,
+			expectedHealthy: true,
+		},
+		{
+			description: "Ping Error",
+			pingErr:     errors.New("test ping error"),
+		},
+		{
+			description: "Lag Error",
+			lagErr:      errors.New("test lag error"),
+		},
+		{
+			description: "Lagging",
+			lag:         time.Minute,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			rdb := &mockReplicaDB{lag: tc.lag, lagErr: tc.lagErr}
+			rdb.mockPing.On("ping").Return(tc.pingErr).Once()
+			p := xmetricstest.NewProvider(nil, Metrics)
+			rep := &replica{server: "replica:5432", db: rdb}
+			rep.setHealthy(!tc.expectedHealthy)
+			router := replicaRouter{
+				replicas: []*replica{rep},
+				maxLag:   10 * time.Second,
+				measures: NewMeasures(p),
+			}
+
+			router.checkReplicas()
+			rdb.mockPing.AssertExpectations(t)
+			assert.Equal(tc.expectedHealthy, rep.isHealthy())
+			expectedGauge := 0.0
+			if tc.expectedHealthy {
+				expectedGauge = 1.0
+			}
+			p.Assert(t, SQLReplicaHealthyGauge, ServerLabel, "replica:5432")(xmetricstest.Value(expectedGauge))
+		})
+	}
+}
+
+func TestReplicaRouting(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	replicaRecords, err := json.Marshal([]db.Record{{DeviceID: "replica"}})
+	require.Nil(err)
+	primaryRecords, err := json.Marshal([]db.Record{{DeviceID: "primary"}})
+	require.Nil(err)
+
+	primary := new(mockPrimary)
+	good := &mockReplicaDB{}
+	bad := &mockReplicaDB{}
+	down := &mockReplicaDB{}
+	p := xmetricstest.NewProvider(nil, Metrics)
+	router := replicaRouter{
+		primary: primary,
+		replicas: []*replica{
+			{server: "good", db: good, healthy: 1},
+			{server: "bad", db: bad, healthy: 1},
+			{server: "down", db: down},
+		},
+		measures: NewMeasures(p),
+	}
+
+	good.mockFinder.On("findRecords", mock.Anything, mock.Anything, mock.Anything).Return(nil, replicaRecords)
+	bad.mockFinder.On("findRecords", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("test replica error"), replicaRecords).Once()
+	primary.mockFinder.On("findRecords", mock.Anything, mock.Anything, mock.Anything).Return(nil, primaryRecords)
+	primary.mockFinder.On("findRecordsToDelete", mock.Anything, mock.Anything, mock.Anything).Return([]db.RecordToDelete{}, nil).Once()
+
+	// the replicas are used in turn, skipping the one that is down.
+	var records []db.Record
+	assert.Nil(router.findRecords(&records, 5))
+	assert.Equal("replica", records[0].DeviceID)
+	// the failing replica falls back to the primary and is marked unhealthy.
+	assert.Nil(router.findRecords(&records, 5))
+	assert.Equal("primary", records[0].DeviceID)
+	assert.False(router.replicas[1].isHealthy())
+	p.Assert(t, SQLReplicaFallbackCounter)(xmetricstest.Value(1.0))
+	// only the good replica is left.
+	assert.Nil(router.findRecords(&records, 5))
+	assert.Equal("replica", records[0].DeviceID)
+	assert.Nil(router.findRecords(&records, 5))
+	assert.Equal("replica", records[0].DeviceID)
+
+	// deletes are decided on the primary.
+	_, err = router.findRecordsToDelete(5, 0, 0)
+	assert.Nil(err)
+
+	// with no healthy replicas everything goes to the primary.
+	router.replicas[0].setHealthy(false)
+	primary.mockDeviceFinder.On("getList", mock.Anything, mock.Anything, mock.Anything).Return([]string{"primary"}, nil).Once()
+	list, err := router.getList("", 5)
+	assert.Nil(err)
+	assert.Equal([]string{"primary"}, list)
+	p.Assert(t, SQLReplicaFallbackCounter)(xmetricstest.Value(2.0))
+
+	good.mockFinder.AssertNumberOfCalls(t, "findRecords", 3)
+	bad.mockFinder.AssertExpectations(t)
+	primary.mockFinder.AssertExpectations(t)
+	primary.mockDeviceFinder.AssertExpectations(t)
+}
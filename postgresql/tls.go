@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package postgresql
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/goph/emperror"
+)
+
+var (
+	errSSLNotSupported = errors.New("server does not support SSL")
+	errConflictingTLS  = errors.New("in-memory TLS can't be used with the SSLRootCert, SSLKey, or SSLCert files")
+	errConflictingMode = errors.New("sslmode contradicts the in-memory TLS settings")
+
+	// sslRequest is the message asking the server to switch to TLS: a length
+	// of 8 followed by the SSLRequest code 80877103.
+	sslRequest = []byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f}
+)
+
+// tlsDialer is a pq.Dialer that negotiates TLS itself using the tls.Config
+// given, so that TLS material held in memory can be used.  pq must be told
+// to connect with sslmode=disable so that it speaks plain protocol over the
+// encrypted connection instead of negotiating again.
+type tlsDialer struct {
+	dialer net.Dialer
+	config *tls.Config
+}
+
+func (d tlsDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (d tlsDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.DialContext(ctx, network, address)
+}
+
+func (d tlsDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	client, err := d.upgrade(ctx, conn, address)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func (d tlsDialer) upgrade(ctx context.Context, conn net.Conn, address string) (net.Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := conn.Write(sslRequest); err != nil {
+		return nil, err
+	}
+	response := make([]byte, 1)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+	if response[0] != 'S' {
+		return nil, errSSLNotSupported
+	}
+
+	config := d.config.Clone()
+	if config.ServerName == "" {
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			config.ServerName = host
+		}
+	}
+	client := tls.Client(conn, config)
+	if err := client.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+
+	// clear the deadline now that the handshake is done.
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// resolveTLSConfig validates and builds the in-memory TLS configuration,
+// returning nil if the sslmode and certificate file settings should be used.
+// In-memory TLS always encrypts and verifies as its tls.Config says, so an
+// sslmode asking for anything else is an error rather than being ignored.
+func resolveTLSConfig(config Config) (*tls.Config, error) {
+	if !config.TLS.IsSet() {
+		return nil, nil
+	}
+	if config.SSLRootCert != "" || config.SSLKey != "" || config.SSLCert != "" {
+		return nil, emperror.Wrap(errConflictingTLS, "Invalid postgres config")
+	}
+	if config.SSLMode == SSLModeDisable {
+		return nil, emperror.WrapWith(errConflictingMode, "Invalid postgres config", "sslmode", config.SSLMode)
+	}
+	tlsConfig, err := config.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+	verify := config.SSLMode == SSLModeVerifyCA || config.SSLMode == SSLModeVerifyFull
+	if verify && tlsConfig.InsecureSkipVerify {
+		return nil, emperror.WrapWith(errConflictingMode, "Invalid postgres config", "sslmode", config.SSLMode)
+	}
+	return tlsConfig, nil
+}
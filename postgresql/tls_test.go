@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package postgresql
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xmidt-org/codex-db/tlsconfig"
+)
+
+func TestTLSDialerSSLRefused(t *testing.T) {
+	assert := assert.New(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer listener.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request := make([]byte, len(sslRequest))
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		received <- request
+		conn.Write([]byte{'N'})
+	}()
+
+	d := tlsDialer{config: &tls.Config{MinVersion: tls.VersionTLS12}}
+	conn, err := d.DialTimeout("tcp", listener.Addr().String(), time.Second)
+	assert.Nil(conn)
+	assert.Equal(errSSLNotSupported, err)
+	assert.Equal(sslRequest, <-received)
+}
+
+func TestResolveTLSConfig(t *testing.T) {
+	base := &tls.Config{ServerName: "base"}
+	tests := []struct {
+		description        string
+		config             Config
+		expectedServerName string
+		expectNil          bool
+		expectedErr        error
+	}{
+		{
+			description: "No TLS",
+			config:      Config{SSLRootCert: "root.pem"},
+			expectNil:   true,
+		},
+		{
+			description:        "Base Only",
+			config:             Config{TLS: tlsconfig.Config{Base: base}},
+			expectedServerName: "base",
+		},
+		{
+			description:        "Server Name",
+			config:             Config{TLS: tlsconfig.Config{Base: base, ServerName: "db.example.com"}},
+			expectedServerName: "db.example.com",
+		},
+		{
+			description:        "Verify Full",
+			config:             Config{SSLMode: SSLModeVerifyFull, TLS: tlsconfig.Config{Base: base}},
+			expectedServerName: "base",
+		},
+		{
+			description: "Conflicting Files",
+			config:      Config{SSLCert: "cert.pem", TLS: tlsconfig.Config{MinVersion: "1.2"}},
+			expectedErr: errConflictingTLS,
+		},
+		{
+			description: "Conflicting Files With Base",
+			config:      Config{SSLRootCert: "root.pem", TLS: tlsconfig.Config{Base: base}},
+			expectedErr: errConflictingTLS,
+		},
+		{
+			description: "Disable Error",
+			config:      Config{SSLMode: SSLModeDisable, TLS: tlsconfig.Config{Base: base}},
+			expectedErr: errConflictingMode,
+		},
+		{
+			description: "Verify Without Verification Error",
+			config:      Config{SSLMode: SSLModeVerifyCA, TLS: tlsconfig.Config{Base: base, InsecureSkipVerify: true}},
+			expectedErr: errConflictingMode,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			tlsConfig, err := resolveTLSConfig(tc.config)
+			if tc.expectedErr != nil {
+				require.Error(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+				return
+			}
+			require.NoError(t, err)
+			if tc.expectNil {
+				assert.Nil(tlsConfig)
+				return
+			}
+			assert.Equal(tc.expectedServerName, tlsConfig.ServerName)
+		})
+	}
+	// the base given isn't changed.
+	assert.Equal(t, "base", base.ServerName)
+}
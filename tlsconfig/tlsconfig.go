@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// Package tlsconfig builds TLS configuration for the database drivers from
+// material held in memory, such as PEM blobs handed over by a secret manager,
+// so that keys don't have to be written to disk.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/goph/emperror"
+)
+
+const (
+	defaultMinVersion     = tls.VersionTLS12
+	defaultReloadInterval = 5 * time.Minute
+)
+
+var (
+	errInvalidRootCAs     = errors.New("no certificates found in root CA PEM")
+	errIncompleteKeyPair  = errors.New("client certificate and key must both be set")
+	errConflictingCert    = errors.New("client certificate PEM and LoadClientCert can't both be set")
+	errUnsupportedVersion = errors.New("unsupported minimum TLS version")
+
+	versions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+)
+
+// Config is TLS configuration given in memory rather than as file paths.
+type Config struct {
+	// Base, if set, is copied and the settings below are applied to the copy.
+	Base *tls.Config
+
+	// RootCAs is the PEM encoded certificates used to verify the server.  The
+	// system pool is used if neither this nor Base gives any.
+	RootCAs []byte
+
+	// Cert and Key are the PEM encoded client certificate and its key.
+	Cert []byte
+	Key  []byte
+
+	// LoadClientCert, if set, returns the PEM encoded client certificate and
+	// key.  It is called at most once every ReloadInterval, so a rotated
+	// certificate is used for new connections.  The last certificate loaded
+	// is kept if it fails.
+	LoadClientCert func() (cert []byte, key []byte, err error)
+
+	// ReloadInterval is how long a certificate from LoadClientCert is used
+	// before loading it again.  Defaults to 5 minutes.
+	ReloadInterval time.Duration
+
+	// MinVersion is the minimum TLS version allowed: 1.0, 1.1, 1.2 or 1.3.
+	// Defaults to 1.2, unless Base has a minimum version.
+	MinVersion string
+
+	// ServerName is the name sent for SNI and checked against the server's
+	// certificate, when it isn't the host connected to.
+	ServerName string
+
+	// InsecureSkipVerify turns off verification of the server's certificate.
+	InsecureSkipVerify bool
+}
+
+// IsSet returns whether any TLS configuration was given.
+func (c Config) IsSet() bool {
+	return c.Base != nil || len(c.RootCAs) > 0 || len(c.Cert) > 0 || len(c.Key) > 0 ||
+		c.LoadClientCert != nil || c.MinVersion != "" || c.ServerName != "" || c.InsecureSkipVerify
+}
+
+// Build validates the configuration and creates the tls.Config described by
+// it.  A client certificate from LoadClientCert is loaded once here, so that
+// a bad certificate is found before connecting.
+func (c Config) Build() (*tls.Config, error) {
+	config := &tls.Config{}
+	if c.Base != nil {
+		config = c.Base.Clone()
+	}
+
+	switch {
+	case c.MinVersion != "":
+		version, ok := versions[c.MinVersion]
+		if !ok {
+			return nil, emperror.WrapWith(errUnsupportedVersion, "Invalid TLS config", "min version", c.MinVersion)
+		}
+		config.MinVersion = version
+	case config.MinVersion == 0:
+		config.MinVersion = defaultMinVersion
+	}
+
+	if len(c.RootCAs) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(c.RootCAs) {
+			return nil, emperror.Wrap(errInvalidRootCAs, "Invalid TLS config")
+		}
+		config.RootCAs = pool
+	}
+
+	if (len(c.Cert) > 0) != (len(c.Key) > 0) {
+		return nil, emperror.Wrap(errIncompleteKeyPair, "Invalid TLS config")
+	}
+	if len(c.Cert) > 0 {
+		if c.LoadClientCert != nil {
+			return nil, emperror.Wrap(errConflictingCert, "Invalid TLS config")
+		}
+		cert, err := tls.X509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return nil, emperror.Wrap(err, "Invalid TLS client certificate")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if c.LoadClientCert != nil {
+		reloader := &certReloader{
+			load:     c.LoadClientCert,
+			interval: c.ReloadInterval,
+			now:      time.Now,
+		}
+		if reloader.interval <= 0 {
+			reloader.interval = defaultReloadInterval
+		}
+		if _, err := reloader.getClientCertificate(nil); err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = reloader.getClientCertificate
+	}
+
+	if c.ServerName != "" {
+		config.ServerName = c.ServerName
+	}
+	if c.InsecureSkipVerify {
+		config.InsecureSkipVerify = true
+	}
+	return config, nil
+}
+
+// certReloader hands the TLS handshake a client certificate, loading it
+// again once it is older than the interval.
+type certReloader struct {
+	load     func() ([]byte, []byte, error)
+	interval time.Duration
+	now      func() time.Time
+
+	lock   sync.Mutex
+	cert   *tls.Certificate
+	loaded time.Time
+}
+
+func (r *certReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.now()
+	if r.cert != nil && now.Sub(r.loaded) < r.interval {
+		return r.cert, nil
+	}
+	cert, err := r.loadCert()
+	if err != nil {
+		if r.cert == nil {
+			return nil, err
+		}
+		// keep using the certificate we have rather than failing connections.
+		return r.cert, nil
+	}
+	r.cert = cert
+	r.loaded = now
+	return r.cert, nil
+}
+
+func (r *certReloader) loadCert() (*tls.Certificate, error) {
+	certPEM, keyPEM, err := r.load()
+	if err != nil {
+		return nil, emperror.Wrap(err, "Loading TLS client certificate failed")
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, emperror.Wrap(err, "Invalid TLS client certificate")
+	}
+	return &cert, nil
+}
@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCert(t *testing.T, name string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestBuild(t *testing.T) {
+	cert, key := newTestCert(t, "client")
+	_, otherKey := newTestCert(t, "other")
+	tests := []struct {
+		description        string
+		config             Config
+		expectedMinVersion uint16
+		expectedServerName string
+		expectedCerts      int
+		expectRootCAs      bool
+		expectReload       bool
+		expectedErr        error
+	}{
+		{
+			description:        "Defaults",
+			config:             Config{},
+			expectedMinVersion: tls.VersionTLS12,
+		},
+		{
+			description: "All PEM",
+			config: Config{
+				RootCAs:    cert,
+				Cert:       cert,
+				Key:        key,
+				MinVersion: "1.3",
+				ServerName: "db.example.com",
+			},
+			expectedMinVersion: tls.VersionTLS13,
+			expectedServerName: "db.example.com",
+			expectedCerts:      1,
+			expectRootCAs:      true,
+		},
+		{
+			description:        "Base Min Version Kept",
+			config:             Config{Base: &tls.Config{MinVersion: tls.VersionTLS11, ServerName: "base"}},
+			expectedMinVersion: tls.VersionTLS11,
+			expectedServerName: "base",
+		},
+		{
+			description: "Load Client Cert",
+			config: Config{
+				LoadClientCert: func() ([]byte, []byte, error) {
+					return cert, key, nil
+				},
+			},
+			expectedMinVersion: tls.VersionTLS12,
+			expectReload:       true,
+		},
+		{
+			description: "Unsupported Version",
+			config:      Config{MinVersion: "1.4"},
+			expectedErr: errUnsupportedVersion,
+		},
+		{
+			description: "Invalid Root CAs",
+			config:      Config{RootCAs: []byte("not a cert")},
+			expectedErr: errInvalidRootCAs,
+		},
+		{
+			description: "Cert Without Key",
+			config:      Config{Cert: cert},
+			expectedErr: errIncompleteKeyPair,
+		},
+		{
+			description: "Mismatched Key",
+			config:      Config{Cert: cert, Key: otherKey},
+			expectedErr: errors.New("private key does not match public key"),
+		},
+		{
+			description: "Cert And Loader",
+			config: Config{
+				Cert: cert,
+				Key:  key,
+				LoadClientCert: func() ([]byte, []byte, error) {
+					return cert, key, nil
+				},
+			},
+			expectedErr: errConflictingCert,
+		},
+		{
+			description: "Loader Error",
+			config: Config{
+				LoadClientCert: func() ([]byte, []byte, error) {
+					return nil, nil, errors.New("test load error")
+				},
+			},
+			expectedErr: errors.New("test load error"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.description, func(t *testing.T) {
+			assert := assert.New(t)
+			config, err := tc.config.Build()
+			if tc.expectedErr != nil {
+				require.Error(t, err)
+				assert.Contains(err.Error(), tc.expectedErr.Error())
+				assert.Nil(config)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(tc.expectedMinVersion, config.MinVersion)
+			assert.Equal(tc.expectedServerName, config.ServerName)
+			assert.Len(config.Certificates, tc.expectedCerts)
+			assert.Equal(tc.expectRootCAs, config.RootCAs != nil)
+			assert.Equal(tc.expectReload, config.GetClientCertificate != nil)
+		})
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	firstCert, firstKey := newTestCert(t, "first")
+	secondCert, secondKey := newTestCert(t, "second")
+
+	var (
+		now     = time.Now()
+		loadErr error
+		certPEM = firstCert
+		keyPEM  = firstKey
+		loads   int
+	)
+	reloader := &certReloader{
+		load: func() ([]byte, []byte, error) {
+			loads++
+			return certPEM, keyPEM, loadErr
+		},
+		interval: time.Minute,
+		now: func() time.Time {
+			return now
+		},
+	}
+	commonName := func(cert *tls.Certificate) string {
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(err)
+		return parsed.Subject.CommonName
+	}
+
+	cert, err := reloader.getClientCertificate(nil)
+	require.NoError(err)
+	assert.Equal("first", commonName(cert))
+
+	// the certificate is cached until the interval passes.
+	certPEM, keyPEM = secondCert, secondKey
+	cert, err = reloader.getClientCertificate(nil)
+	require.NoError(err)
+	assert.Equal("first", commonName(cert))
+	assert.Equal(1, loads)
+
+	// a failed reload keeps the old certificate.
+	now = now.Add(time.Minute)
+	loadErr = errors.New("test load error")
+	cert, err = reloader.getClientCertificate(nil)
+	require.NoError(err)
+	assert.Equal("first", commonName(cert))
+
+	loadErr = nil
+	cert, err = reloader.getClientCertificate(nil)
+	require.NoError(err)
+	assert.Equal("second", commonName(cert))
+	assert.Equal(3, loads)
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"time"
+
+	db "github.com/xmidt-org/codex-db"
+)
+
+const (
+	headerSize = 8
+
+	// maxFrameSize is the largest frame a segment is trusted to hold, so a
+	// torn length can't make the reader allocate gigabytes.
+	maxFrameSize = 64 << 20
+)
+
+var (
+	errTorn = errors.New("frame is incomplete or corrupt")
+)
+
+// frame is what is written to a segment: either an entry, the sequence
+// numbers of entries that have been acknowledged, or those of entries that
+// have been replayed.
+type frame struct {
+	Seq       uint64     `json:"seq,omitempty"`
+	Record    *db.Record `json:"record,omitempty"`
+	Beginning time.Time  `json:"beginning"`
+	Acks      []uint64   `json:"acks,omitempty"`
+	Replays   []uint64   `json:"replays,omitempty"`
+}
+
+// encodeFrame returns f after its length and CRC-32 checksum, each as four
+// big endian bytes.
+func encodeFrame(f frame) ([]byte, error) {
+	payload, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:], crc32.ChecksumIEEE(payload))
+	copy(buf[headerSize:], payload)
+	return buf, nil
+}
+
+type frameReader struct {
+	r *bufio.Reader
+}
+
+func newFrameReader(r io.Reader) *frameReader {
+	return &frameReader{r: bufio.NewReader(r)}
+}
+
+// next returns the next frame and how many bytes of the segment it took up,
+// io.EOF once the segment is done, or errTorn if the rest of the segment
+// can't be trusted.
+func (r *frameReader) next() (frame, int64, error) {
+	var (
+		f      frame
+		header [headerSize]byte
+	)
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return f, 0, errTorn
+		}
+		return f, 0, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return f, 0, errTorn
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r.r, payload); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return f, 0, errTorn
+		}
+		return f, 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return f, 0, errTorn
+	}
+	if err := json.Unmarshal(payload, &f); err != nil {
+		return f, 0, errTorn
+	}
+	return f, int64(headerSize + len(payload)), nil
+}
@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package wal
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics"
+)
+
+const (
+	WALLagGauge       = "wal_lag"
+	WALSegmentsGauge  = "wal_segments"
+	WALReplayCounter  = "wal_replayed_count"
+	WALExpiredCounter = "wal_expired_count"
+	WALPoisonCounter  = "wal_poison_count"
+)
+
+func Metrics() []xmetrics.Metric {
+	return []xmetrics.Metric{
+		{
+			Name: WALLagGauge,
+			Help: "The number of entries in the write-ahead log that haven't been acknowledged",
+			Type: "gauge",
+		},
+		{
+			Name: WALSegmentsGauge,
+			Help: "The number of segment files in the write-ahead log",
+			Type: "gauge",
+		},
+		{
+			Name: WALReplayCounter,
+			Help: "The total number of unacknowledged entries replayed from the write-ahead log",
+			Type: "counter",
+		},
+		{
+			Name: WALExpiredCounter,
+			Help: "The total number of unacknowledged entries removed from the write-ahead log for being older than the retention",
+			Type: "counter",
+		},
+		{
+			Name: WALPoisonCounter,
+			Help: "The total number of entries dropped from the write-ahead log for being replayed too many times without being acknowledged",
+			Type: "counter",
+		},
+	}
+}
+
+type Measures struct {
+	Lag      metrics.Gauge
+	Segments metrics.Gauge
+	Replayed metrics.Counter
+	Expired  metrics.Counter
+	Poisoned metrics.Counter
+}
+
+// NewMeasures constructs a Measures given a go-kit metrics Provider
+func NewMeasures(p provider.Provider) *Measures {
+	return &Measures{
+		Lag:      p.NewGauge(WALLagGauge),
+		Segments: p.NewGauge(WALSegmentsGauge),
+		Replayed: p.NewCounter(WALReplayCounter),
+		Expired:  p.NewCounter(WALExpiredCounter),
+		Poisoned: p.NewCounter(WALPoisonCounter),
+	}
+}
@@ -0,0 +1,600 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+// package wal provides a write-ahead log for records waiting to be inserted,
+// so that records which were never inserted can be replayed after a restart.
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/provider"
+	"github.com/goph/emperror"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/logging"
+)
+
+// The policies for flushing the log to disk.
+const (
+	// SyncAlways flushes after every append and acknowledgement.
+	SyncAlways = "always"
+	// SyncInterval flushes every SyncInterval, so a crash can lose what was
+	// written since the last flush.
+	SyncInterval = "interval"
+	// SyncNever leaves flushing to the operating system.
+	SyncNever = "never"
+)
+
+const (
+	defaultSegmentSize  = 16 << 20
+	defaultSyncInterval = time.Second
+	defaultRetention    = 24 * time.Hour
+	defaultMaxReplays   = 3
+	filePrefix          = "wal-"
+	fileExt             = ".log"
+)
+
+var (
+	defaultLogger = log.NewNopLogger()
+)
+
+var (
+	errNoDir             = errors.New("no write-ahead log directory")
+	errUnknownSyncPolicy = errors.New("sync policy must be always, interval, or never")
+	errClosed            = errors.New("write-ahead log is closed")
+)
+
+// Config holds the configuration values for a write-ahead log.
+type Config struct {
+	// Dir is the directory the segment files are kept in.  It is created if
+	// it doesn't exist.
+	Dir string
+
+	// SyncPolicy is SyncAlways, SyncInterval, or SyncNever.  It defaults to
+	// SyncInterval.
+	SyncPolicy string
+
+	// SyncInterval is how often the log is flushed when the policy is
+	// SyncInterval.
+	SyncInterval time.Duration
+
+	// SegmentSize is how many bytes a segment file holds before a new one is
+	// started.
+	SegmentSize int64
+
+	// Retention is how long a segment with unacknowledged entries is kept
+	// after it was last written to.  It defaults to a day, so that entries
+	// which are never acknowledged don't keep every later segment around.
+	Retention time.Duration
+
+	// MaxReplays is how many times an entry is given to Replay.  An entry
+	// still unacknowledged after that is dropped when the log is opened, so
+	// that a record the database keeps rejecting isn't replayed on every
+	// start.  It defaults to 3.
+	MaxReplays int
+}
+
+// Entry is a record in the log that hasn't been acknowledged.
+type Entry struct {
+	Seq       uint64
+	Record    db.Record
+	Beginning time.Time
+}
+
+// segment is one of the log's files.
+type segment struct {
+	path     string
+	pending  int
+	modified time.Time
+}
+
+// Log is a write-ahead log of records.  Records are appended before they are
+// queued to be inserted and acknowledged once they are inserted.  Segment
+// files are removed, oldest first, once every entry in them has been
+// acknowledged.  Entries left unacknowledged when the log was last closed are
+// given to Replay.
+type Log struct {
+	config   Config
+	logger   log.Logger
+	measures *Measures
+
+	lock        sync.Mutex
+	segments    []*segment
+	nextSegment uint64
+	file        *os.File
+	size        int64
+	nextSeq     uint64
+	pending     map[uint64]*segment
+	replays     map[uint64]int
+	replay      []Entry
+	dirty       bool
+	closed      bool
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewLog opens the write-ahead log in the configured directory, ensuring
+// that the configuration values given are valid.  If configuration values
+// aren't valid, a default value is used.  Entries in the directory that
+// weren't acknowledged are kept for Replay.
+func NewLog(config Config, logger log.Logger, metricsRegistry provider.Provider) (*Log, error) {
+	if config.Dir == "" {
+		return nil, errNoDir
+	}
+	switch config.SyncPolicy {
+	case "":
+		config.SyncPolicy = SyncInterval
+	case SyncAlways, SyncInterval, SyncNever:
+	default:
+		return nil, emperror.With(errUnknownSyncPolicy, "policy", config.SyncPolicy)
+	}
+	if config.SyncInterval <= 0 {
+		config.SyncInterval = defaultSyncInterval
+	}
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = defaultSegmentSize
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaultRetention
+	}
+	if config.MaxReplays <= 0 {
+		config.MaxReplays = defaultMaxReplays
+	}
+	if logger == nil {
+		logger = defaultLogger
+	}
+	if err := os.MkdirAll(config.Dir, 0700); err != nil {
+		return nil, emperror.WrapWith(err, "Creating write-ahead log directory failed", "dir", config.Dir)
+	}
+
+	l := &Log{
+		config:  config,
+		logger:  logger,
+		nextSeq: 1,
+		pending: make(map[uint64]*segment),
+		replays: make(map[uint64]int),
+		stop:    make(chan struct{}),
+	}
+	if metricsRegistry != nil {
+		l.measures = NewMeasures(metricsRegistry)
+	}
+	entries, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	if err = l.startSegment(); err != nil {
+		return nil, err
+	}
+	if err = l.dropPoison(entries); err != nil {
+		l.closeSegment()
+		return nil, err
+	}
+	l.compact()
+	for _, e := range entries {
+		if _, ok := l.pending[e.Seq]; ok {
+			l.replay = append(l.replay, e)
+		}
+	}
+	l.replays = nil
+	l.updateGauges()
+
+	if config.SyncPolicy == SyncInterval {
+		l.wg.Add(1)
+		go l.syncEvery(config.SyncInterval)
+	}
+	return l, nil
+}
+
+// segmentName is the name of the segment file with the number given.
+// Sorting the names sorts the segments from oldest to newest.
+func segmentName(number uint64) string {
+	return fmt.Sprintf("%s%020d%s", filePrefix, number, fileExt)
+}
+
+func parseSegmentName(name string) (uint64, bool) {
+	if !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileExt) {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileExt), 10, 64)
+	return seq, err == nil
+}
+
+// load reads the segments in the directory, returning the entries that
+// weren't acknowledged, in order.
+func (l *Log) load() ([]Entry, error) {
+	files, err := os.ReadDir(l.config.Dir)
+	if err != nil {
+		return nil, emperror.WrapWith(err, "Reading write-ahead log directory failed", "dir", l.config.Dir)
+	}
+	for _, file := range files {
+		number, ok := parseSegmentName(file.Name())
+		if !ok || file.IsDir() {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			return nil, emperror.WrapWith(err, "Reading segment failed", "file", file.Name())
+		}
+		l.segments = append(l.segments, &segment{
+			path:     filepath.Join(l.config.Dir, file.Name()),
+			modified: info.ModTime(),
+		})
+		if number >= l.nextSegment {
+			l.nextSegment = number + 1
+		}
+	}
+	sort.Slice(l.segments, func(i, j int) bool {
+		return l.segments[i].path < l.segments[j].path
+	})
+
+	unacked := make(map[uint64]Entry)
+	for i, s := range l.segments {
+		if err := l.loadSegment(s, i == len(l.segments)-1, unacked); err != nil {
+			return nil, err
+		}
+	}
+	entries := make([]Entry, 0, len(unacked))
+	for _, e := range unacked {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Seq < entries[j].Seq
+	})
+	return entries, nil
+}
+
+func (l *Log) loadSegment(s *segment, last bool, unacked map[uint64]Entry) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return emperror.WrapWith(err, "Opening segment failed", "file", s.path)
+	}
+	defer f.Close()
+
+	var valid int64
+	reader := newFrameReader(f)
+	for {
+		fr, size, err := reader.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err == errTorn {
+			logging.Error(l.logger).Log(logging.MessageKey(), "Skipping the rest of a torn segment",
+				"file", s.path, "offset", valid)
+			if !last {
+				return nil
+			}
+			// the process stopped partway through a write; cut it off so
+			// the segment reads cleanly next time.
+			if err := os.Truncate(s.path, valid); err != nil {
+				return emperror.WrapWith(err, "Truncating torn segment failed", "file", s.path)
+			}
+			return nil
+		}
+		if err != nil {
+			return emperror.WrapWith(err, "Reading segment failed", "file", s.path)
+		}
+		valid += size
+
+		if fr.Record != nil {
+			unacked[fr.Seq] = Entry{Seq: fr.Seq, Record: *fr.Record, Beginning: fr.Beginning}
+			l.pending[fr.Seq] = s
+			s.pending++
+			if fr.Seq >= l.nextSeq {
+				l.nextSeq = fr.Seq + 1
+			}
+		}
+		for _, seq := range fr.Acks {
+			if owner, ok := l.pending[seq]; ok {
+				delete(unacked, seq)
+				delete(l.pending, seq)
+				delete(l.replays, seq)
+				owner.pending--
+			}
+		}
+		for _, seq := range fr.Replays {
+			if _, ok := l.pending[seq]; ok {
+				l.replays[seq]++
+			}
+		}
+	}
+}
+
+// dropPoison acknowledges the entries that have been replayed MaxReplays
+// times without being acknowledged.
+func (l *Log) dropPoison(entries []Entry) error {
+	var poison []uint64
+	for _, e := range entries {
+		if l.replays[e.Seq] >= l.config.MaxReplays {
+			poison = append(poison, e.Seq)
+		}
+	}
+	if len(poison) == 0 {
+		return nil
+	}
+	if err := l.write(frame{Acks: poison}); err != nil {
+		return err
+	}
+	for _, seq := range poison {
+		l.pending[seq].pending--
+		delete(l.pending, seq)
+	}
+	logging.Error(l.logger).Log(logging.MessageKey(), "Dropped entries replayed too many times without being acknowledged",
+		"entries", len(poison), "replays", l.config.MaxReplays)
+	if l.measures != nil {
+		l.measures.Poisoned.Add(float64(len(poison)))
+	}
+	return nil
+}
+
+// Append adds the record to the log, returning its sequence number to
+// acknowledge it with once it has been inserted.
+func (l *Log) Append(record db.Record, beginning time.Time) (uint64, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.closed {
+		return 0, errClosed
+	}
+	seq := l.nextSeq
+	current := l.segments[len(l.segments)-1]
+	if err := l.write(frame{Seq: seq, Record: &record, Beginning: beginning}); err != nil {
+		return 0, err
+	}
+	l.nextSeq++
+	current.pending++
+	l.pending[seq] = current
+	l.updateGauges()
+	return seq, l.rotateIfFull()
+}
+
+// Ack acknowledges the entries with the sequence numbers given, so that
+// they aren't replayed.  Unknown sequence numbers are ignored.
+func (l *Log) Ack(seqs ...uint64) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.closed {
+		return errClosed
+	}
+	acks := make([]uint64, 0, len(seqs))
+	for _, seq := range seqs {
+		if owner, ok := l.pending[seq]; ok {
+			delete(l.pending, seq)
+			owner.pending--
+			acks = append(acks, seq)
+		}
+	}
+	if len(acks) == 0 {
+		return nil
+	}
+	if err := l.write(frame{Acks: acks}); err != nil {
+		return err
+	}
+	l.compact()
+	l.updateGauges()
+	return l.rotateIfFull()
+}
+
+// Replay calls f with each entry that wasn't acknowledged when the log was
+// opened, oldest first.  Each entry is given to Replay once; if f returns an
+// error, Replay stops and the rest are given to the next call.  The replay is
+// recorded in the log before f is called, so that it counts towards
+// MaxReplays even if the process doesn't survive it.
+func (l *Log) Replay(f func(Entry) error) error {
+	l.lock.Lock()
+	entries := l.replay
+	l.replay = nil
+	l.lock.Unlock()
+
+	for i, e := range entries {
+		ok, err := l.markReplayed(e.Seq)
+		if err != nil {
+			l.lock.Lock()
+			l.replay = append(entries[i:], l.replay...)
+			l.lock.Unlock()
+			return err
+		}
+		if !ok {
+			// acknowledged or expired since the log was opened.
+			continue
+		}
+		if err := f(e); err != nil {
+			l.lock.Lock()
+			l.replay = append(entries[i:], l.replay...)
+			l.lock.Unlock()
+			return err
+		}
+		if l.measures != nil {
+			l.measures.Replayed.Add(1.0)
+		}
+	}
+	return nil
+}
+
+// markReplayed records that the entry is being replayed, returning false if
+// it is no longer pending.
+func (l *Log) markReplayed(seq uint64) (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.closed {
+		return false, errClosed
+	}
+	if _, ok := l.pending[seq]; !ok {
+		return false, nil
+	}
+	if err := l.write(frame{Replays: []uint64{seq}}); err != nil {
+		return false, err
+	}
+	return true, l.rotateIfFull()
+}
+
+// Close flushes and closes the log.  Entries that weren't acknowledged are
+// replayed the next time the log is opened.
+func (l *Log) Close() error {
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.stop)
+	err := l.closeSegment()
+	l.lock.Unlock()
+	l.wg.Wait()
+	return err
+}
+
+// write appends the frame to the current segment.  The lock must be held.
+func (l *Log) write(f frame) error {
+	buf, err := encodeFrame(f)
+	if err != nil {
+		return emperror.Wrap(err, "Encoding write-ahead log entry failed")
+	}
+	n, err := l.file.Write(buf)
+	l.size += int64(n)
+	l.dirty = true
+	l.segments[len(l.segments)-1].modified = time.Now()
+	if err != nil {
+		// start over in a new segment, leaving the partial frame at the end
+		// of this one.
+		if rotateErr := l.rotate(); rotateErr != nil {
+			logging.Error(l.logger).Log(logging.MessageKey(), "Failed to start a new segment",
+				logging.ErrorKey(), rotateErr.Error())
+		}
+		return emperror.Wrap(err, "Writing to the write-ahead log failed")
+	}
+	if l.config.SyncPolicy == SyncAlways {
+		return l.sync()
+	}
+	return nil
+}
+
+// rotateIfFull starts a new segment once the current one is large enough.
+// The lock must be held.
+func (l *Log) rotateIfFull() error {
+	if l.size < l.config.SegmentSize {
+		return nil
+	}
+	return l.rotate()
+}
+
+// rotate closes the current segment and starts a new one.  The lock must be
+// held.
+func (l *Log) rotate() error {
+	if err := l.closeSegment(); err != nil {
+		return err
+	}
+	if err := l.startSegment(); err != nil {
+		return err
+	}
+	l.compact()
+	l.updateGauges()
+	return nil
+}
+
+// startSegment creates a new segment to write to.  The lock must be held, if
+// the log is in use.
+func (l *Log) startSegment() error {
+	path := filepath.Join(l.config.Dir, segmentName(l.nextSegment))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return emperror.WrapWith(err, "Creating segment failed", "file", path)
+	}
+	l.nextSegment++
+	l.file, l.size = f, 0
+	l.segments = append(l.segments, &segment{path: path, modified: time.Now()})
+	return nil
+}
+
+func (l *Log) closeSegment() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.sync()
+	if closeErr := l.file.Close(); err == nil && closeErr != nil {
+		err = emperror.Wrap(closeErr, "Closing segment failed")
+	}
+	l.file = nil
+	return err
+}
+
+func (l *Log) sync() error {
+	if !l.dirty || l.file == nil {
+		return nil
+	}
+	l.dirty = false
+	if err := l.file.Sync(); err != nil {
+		return emperror.Wrap(err, "Syncing the write-ahead log failed")
+	}
+	return nil
+}
+
+// compact removes segments from the front of the log that are either fully
+// acknowledged or older than the retention.  Only removing from the front
+// ensures the acknowledgements for an entry are never removed before it is.
+// The lock must be held.
+func (l *Log) compact() {
+	now := time.Now()
+	for len(l.segments) > 1 {
+		s := l.segments[0]
+		expired := l.config.Retention > 0 && now.Sub(s.modified) > l.config.Retention
+		if s.pending > 0 && !expired {
+			return
+		}
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			logging.Error(l.logger).Log(logging.MessageKey(), "Failed to remove segment",
+				"file", s.path, logging.ErrorKey(), err.Error())
+			return
+		}
+		if s.pending > 0 {
+			for seq, owner := range l.pending {
+				if owner == s {
+					delete(l.pending, seq)
+				}
+			}
+			logging.Error(l.logger).Log(logging.MessageKey(), "Removed unacknowledged entries past the retention",
+				"file", s.path, "entries", s.pending)
+			if l.measures != nil {
+				l.measures.Expired.Add(float64(s.pending))
+			}
+		}
+		l.segments = l.segments[1:]
+	}
+}
+
+// updateGauges sets the lag and segment gauges.  The lock must be held.
+func (l *Log) updateGauges() {
+	if l.measures != nil {
+		l.measures.Lag.Set(float64(len(l.pending)))
+		l.measures.Segments.Set(float64(len(l.segments)))
+	}
+}
+
+func (l *Log) syncEvery(interval time.Duration) {
+	defer l.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+		l.lock.Lock()
+		err := l.sync()
+		l.lock.Unlock()
+		if err != nil {
+			logging.Error(l.logger).Log(logging.MessageKey(), "Failed to sync the write-ahead log",
+				logging.ErrorKey(), err.Error())
+		}
+	}
+}
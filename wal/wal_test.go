@@ -0,0 +1,228 @@
+// SPDX-FileCopyrightText: 2025 Comcast Cable Communications Management, LLC
+// SPDX-License-Identifier: Apache-2.0
+
+package wal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	db "github.com/xmidt-org/codex-db"
+	"github.com/xmidt-org/webpa-common/v2/xmetrics/xmetricstest"
+)
+
+var (
+	testBeginning = time.Unix(1000, 0).UTC()
+	testRecords   = []db.Record{
+		{DeviceID: "a", Data: []byte("a")},
+		{DeviceID: "b", Data: []byte("b")},
+		{DeviceID: "c", Data: []byte("c")},
+	}
+)
+
+func segmentFiles(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func replayAll(t *testing.T, l *Log) []Entry {
+	var entries []Entry
+	require.NoError(t, l.Replay(func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	}))
+	return entries
+}
+
+func TestNewLog(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewLog(Config{}, nil, nil)
+	assert.Equal(errNoDir, err)
+	_, err = NewLog(Config{Dir: t.TempDir(), SyncPolicy: "sometimes"}, nil, nil)
+	assert.Contains(err.Error(), errUnknownSyncPolicy.Error())
+
+	dir := filepath.Join(t.TempDir(), "wal")
+	l, err := NewLog(Config{Dir: dir, SyncInterval: -1, SegmentSize: -1, Retention: -1, MaxReplays: -1}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(Config{
+		Dir:          dir,
+		SyncPolicy:   SyncInterval,
+		SyncInterval: defaultSyncInterval,
+		SegmentSize:  defaultSegmentSize,
+		Retention:    defaultRetention,
+		MaxReplays:   defaultMaxReplays,
+	}, l.config)
+	assert.Equal(defaultLogger, l.logger)
+	assert.Equal([]string{segmentName(0)}, segmentFiles(t, dir))
+	assert.NoError(l.Close())
+	assert.NoError(l.Close())
+	_, err = l.Append(testRecords[0], testBeginning)
+	assert.Equal(errClosed, err)
+	assert.Equal(errClosed, l.Ack(1))
+}
+
+func TestReplay(t *testing.T) {
+	for _, policy := range []string{SyncAlways, SyncInterval, SyncNever} {
+		t.Run(policy, func(t *testing.T) {
+			assert := assert.New(t)
+			dir := t.TempDir()
+			p := xmetricstest.NewProvider(nil, Metrics)
+			l, err := NewLog(Config{Dir: dir, SyncPolicy: policy}, nil, p)
+			require.NoError(t, err)
+			assert.Empty(replayAll(t, l))
+
+			var seqs []uint64
+			for _, r := range testRecords {
+				seq, err := l.Append(r, testBeginning)
+				require.NoError(t, err)
+				seqs = append(seqs, seq)
+			}
+			assert.Equal([]uint64{1, 2, 3}, seqs)
+			assert.NoError(l.Ack(seqs[0], seqs[2], 42))
+			p.Assert(t, WALLagGauge)(xmetricstest.Value(1))
+			require.NoError(t, l.Close())
+
+			l, err = NewLog(Config{Dir: dir, SyncPolicy: policy}, nil, p)
+			require.NoError(t, err)
+			p.Assert(t, WALLagGauge)(xmetricstest.Value(1))
+
+			// a failed replay hands the entry to the next one.
+			assert.Equal(errors.New("test error"), l.Replay(func(Entry) error {
+				return errors.New("test error")
+			}))
+			assert.Equal([]Entry{{Seq: 2, Record: testRecords[1], Beginning: testBeginning}}, replayAll(t, l))
+			assert.Empty(replayAll(t, l))
+			p.Assert(t, WALReplayCounter)(xmetricstest.Value(1))
+
+			seq, err := l.Append(testRecords[0], testBeginning)
+			assert.NoError(err)
+			assert.Equal(uint64(4), seq)
+			assert.NoError(l.Ack(2, 4))
+			p.Assert(t, WALLagGauge)(xmetricstest.Value(0))
+			// the first segment is fully acknowledged, so it is gone.
+			assert.Equal([]string{segmentName(1)}, segmentFiles(t, dir))
+			assert.NoError(l.Close())
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	p := xmetricstest.NewProvider(nil, Metrics)
+	// every frame is larger than a byte, so each is followed by a new segment.
+	l, err := NewLog(Config{Dir: dir, SegmentSize: 1}, nil, p)
+	require.NoError(t, err)
+
+	for _, r := range testRecords[:2] {
+		_, err := l.Append(r, testBeginning)
+		require.NoError(t, err)
+	}
+	assert.Equal([]string{segmentName(0), segmentName(1), segmentName(2)}, segmentFiles(t, dir))
+	p.Assert(t, WALSegmentsGauge)(xmetricstest.Value(3))
+
+	// the first segment can't go before the second.
+	assert.NoError(l.Ack(2))
+	assert.Len(segmentFiles(t, dir), 4)
+	assert.NoError(l.Ack(1))
+	assert.Equal([]string{segmentName(4)}, segmentFiles(t, dir))
+	p.Assert(t, WALSegmentsGauge)(xmetricstest.Value(1))
+	assert.NoError(l.Close())
+}
+
+func TestRetention(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	p := xmetricstest.NewProvider(nil, Metrics)
+	l, err := NewLog(Config{Dir: dir, SegmentSize: 1, Retention: time.Millisecond}, nil, p)
+	require.NoError(t, err)
+
+	_, err = l.Append(testRecords[0], testBeginning)
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	_, err = l.Append(testRecords[1], testBeginning)
+	require.NoError(t, err)
+	// only the first entry's segment is past the retention.
+	assert.Equal([]string{segmentName(1), segmentName(2)}, segmentFiles(t, dir))
+	p.Assert(t, WALExpiredCounter)(xmetricstest.Value(1))
+	p.Assert(t, WALLagGauge)(xmetricstest.Value(1))
+	assert.NoError(l.Close())
+}
+
+func TestPoison(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	p := xmetricstest.NewProvider(nil, Metrics)
+	config := Config{Dir: dir, MaxReplays: 2}
+	l, err := NewLog(config, nil, p)
+	require.NoError(t, err)
+	for _, r := range testRecords[:2] {
+		_, err := l.Append(r, testBeginning)
+		require.NoError(t, err)
+	}
+	require.NoError(t, l.Close())
+
+	// the second entry is inserted on its first replay, but the first never is.
+	for i := 0; i < 2; i++ {
+		l, err = NewLog(config, nil, p)
+		require.NoError(t, err)
+		entries := replayAll(t, l)
+		assert.Equal(uint64(1), entries[0].Seq)
+		assert.NoError(l.Ack(2))
+		require.NoError(t, l.Close())
+	}
+
+	l, err = NewLog(config, nil, p)
+	require.NoError(t, err)
+	assert.Empty(replayAll(t, l))
+	p.Assert(t, WALPoisonCounter)(xmetricstest.Value(1))
+	p.Assert(t, WALLagGauge)(xmetricstest.Value(0))
+	require.NoError(t, l.Close())
+
+	// the acknowledgement is kept, so it stays dropped.
+	l, err = NewLog(config, nil, p)
+	require.NoError(t, err)
+	assert.Empty(replayAll(t, l))
+	p.Assert(t, WALPoisonCounter)(xmetricstest.Value(1))
+	require.NoError(t, l.Close())
+}
+
+func TestTornSegment(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+	l, err := NewLog(Config{Dir: dir}, nil, nil)
+	require.NoError(t, err)
+	_, err = l.Append(testRecords[0], testBeginning)
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	path := filepath.Join(dir, segmentName(0))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	frame, err := encodeFrame(frame{Seq: 2, Record: &testRecords[1]})
+	require.NoError(t, err)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = f.Write(frame[:len(frame)-1])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	l, err = NewLog(Config{Dir: dir}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal([]Entry{{Seq: 1, Record: testRecords[0], Beginning: testBeginning}}, replayAll(t, l))
+	truncated, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(info.Size(), truncated.Size())
+	assert.NoError(l.Close())
+}